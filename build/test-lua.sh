@@ -26,4 +26,7 @@ resty \
   --shdict "certificate_data 16M" \
   --shdict "balancer_ewma 1M" \
   --shdict "balancer_ewma_last_touched_at 1M" \
+  --shdict "balancer_concurrency 1M" \
+  --shdict "monitor_pending 5M" \
+  --shdict "tcp_udp_configuration_data 5M" \
   ./rootfs/etc/nginx/lua/test/run.lua ${BUSTED_ARGS} ./rootfs/etc/nginx/lua/test/
@@ -75,3 +75,106 @@ func TestFlagConflict(t *testing.T) {
 		t.Fatalf("Expected an error parsing flags but none returned")
 	}
 }
+
+func TestStatsdTagsWithoutDogStatsD(t *testing.T) {
+	resetForTesting(func() { t.Fatal("Parsing failed") })
+
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"cmd", "--http-port", "0", "--https-port", "0", "--statsd-address", "127.0.0.1:8125",
+		"--statsd-tags", "env:prod", "--statsd-dogstatsd=false"}
+
+	_, _, err := parseFlags()
+	if err == nil {
+		t.Fatalf("Expected an error parsing flags but none returned")
+	}
+}
+
+func TestDynamicStreamPortsConflict(t *testing.T) {
+	resetForTesting(func() { t.Fatal("Parsing failed") })
+
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"cmd", "--http-port", "0", "--https-port", "0", "--dynamic-stream-ports", "10000-10300"}
+
+	_, _, err := parseFlags()
+	if err == nil {
+		t.Fatalf("Expected an error parsing flags but none returned")
+	}
+}
+
+func TestDynamicStreamPorts(t *testing.T) {
+	resetForTesting(func() { t.Fatal("Parsing failed") })
+
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"cmd", "--http-port", "0", "--https-port", "0", "--dynamic-stream-ports", "20000-20099"}
+
+	_, conf, err := parseFlags()
+	if err != nil {
+		t.Fatalf("Unexpected error parsing flags: %v", err)
+	}
+
+	if conf.ListenPorts.DynamicStream.First != 20000 || conf.ListenPorts.DynamicStream.Last != 20099 {
+		t.Errorf("Unexpected dynamic stream ports %v", conf.ListenPorts.DynamicStream)
+	}
+}
+
+func TestHTTP3(t *testing.T) {
+	resetForTesting(func() { t.Fatal("Parsing failed") })
+
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"cmd", "--http-port", "0", "--https-port", "0", "--enable-http3", "--http3-port", "20443"}
+
+	_, conf, err := parseFlags()
+	if err != nil {
+		t.Fatalf("Unexpected error parsing flags: %v", err)
+	}
+
+	if conf.ListenPorts.HTTP3 != 20443 {
+		t.Errorf("Unexpected HTTP/3 port %v", conf.ListenPorts.HTTP3)
+	}
+
+	resetForTesting(func() { t.Fatal("Parsing failed") })
+	os.Args = []string{"cmd", "--http-port", "0", "--https-port", "0", "--http3-port", "20443"}
+
+	_, conf, err = parseFlags()
+	if err != nil {
+		t.Fatalf("Unexpected error parsing flags: %v", err)
+	}
+
+	if conf.ListenPorts.HTTP3 != 0 {
+		t.Errorf("Expected HTTP/3 to be disabled without --enable-http3 but the port is %v", conf.ListenPorts.HTTP3)
+	}
+}
+
+func TestHTTP3PortConflict(t *testing.T) {
+	resetForTesting(func() { t.Fatal("Parsing failed") })
+
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"cmd", "--http-port", "0", "--https-port", "0", "--dynamic-stream-ports", "20000-20099",
+		"--enable-http3", "--http3-port", "20050"}
+
+	_, _, err := parseFlags()
+	if err == nil {
+		t.Fatalf("Expected an error parsing flags but none returned")
+	}
+}
+
+func TestHTTP3Supported(t *testing.T) {
+	if err := checkHTTP3Support(false); err != nil {
+		t.Errorf("Expected no error when HTTP/3 is disabled but got %v", err)
+	}
+
+	withoutHTTP3 := "nginx version: nginx/1.15.9\nconfigure arguments: --prefix=/usr/share/nginx --with-http_v2_module --with-http_ssl_module"
+	if err := http3Supported(withoutHTTP3); err == nil {
+		t.Errorf("Expected an error with an NGINX build without HTTP/3 support but none returned")
+	}
+
+	withHTTP3 := "nginx version: nginx/1.25.3\nconfigure arguments: --prefix=/usr/share/nginx --with-http_v2_module --with-http_v3_module"
+	if err := http3Supported(withHTTP3); err != nil {
+		t.Errorf("Expected no error with an NGINX build with HTTP/3 support but got %v", err)
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/spf13/pflag"
 
@@ -30,10 +31,15 @@ import (
 	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
 	"k8s.io/ingress-nginx/internal/ingress/controller"
 	ngx_config "k8s.io/ingress-nginx/internal/ingress/controller/config"
+	"k8s.io/ingress-nginx/internal/ingress/metric"
 	ing_net "k8s.io/ingress-nginx/internal/net"
 	"k8s.io/ingress-nginx/internal/nginx"
 )
 
+// maxDynamicStreamPorts is the maximum number of ports of the flag
+// --dynamic-stream-ports, each one listened by every NGINX worker
+const maxDynamicStreamPorts = 1000
+
 func parseFlags() (bool, *controller.Configuration, error) {
 	var (
 		flags = pflag.NewFlagSet("", pflag.ExitOnError)
@@ -76,6 +82,11 @@ controller for servicing HTTP traffic.`)
 The key in the map indicates the external port to be used. The value is a
 reference to a Service in the form "namespace/name:port", where "port" can
 either be a port name or number.`)
+		hostOwnershipConfigMapName = flags.String("host-ownership-configmap", "",
+			`Name of the ConfigMap containing the namespaces allowed to define hosts.
+The key in the map is a namespace and the value a comma separated list of hostnames
+or wildcard domains ("*.example.com"). Ingresses defining a host listed in the map
+in a namespace not allowed to use it are ignored. Hosts not listed are not restricted.`)
 
 		resyncPeriod = flags.Duration("sync-period", 0,
 			`Period at which the controller forces the repopulation of its local object stores. Disabled by default.`)
@@ -149,6 +160,23 @@ Feature backed by OpenResty Lua libraries. Requires that OCSP stapling is not en
 			`Enables the collection of NGINX metrics`)
 		metricsPerHost = flags.Bool("metrics-per-host", true,
 			`Export metrics per-host`)
+		enableZoneMetrics = flags.Bool("enable-zone-metrics", false,
+			`Export the number of requests, bytes and responses of each server and upstream zone,
+counted by NGINX. Requires the enable-metrics parameter.`)
+
+		statsdAddress = flags.String("statsd-address", "",
+			`Address of a StatsD server the metrics are also pushed to, in the form "host:port".
+Requires the enable-metrics parameter. Disabled if left empty.`)
+		statsdPrefix = flags.String("statsd-prefix", "",
+			`Prefix prepended to the name of the metrics pushed to StatsD.`)
+		statsdTags = flags.StringSlice("statsd-tags", []string{},
+			`Comma separated list of tags, in the form "key:value", added to the metrics pushed to StatsD.
+Requires the statsd-dogstatsd parameter.`)
+		statsdFlushInterval = flags.Duration("statsd-flush-interval", 10*time.Second,
+			`Time between two consecutive pushes of the metrics to StatsD.`)
+		statsdDogStatsD = flags.Bool("statsd-dogstatsd", true,
+			`Use the DogStatsD extension to send metric labels as tags.
+Otherwise label values are appended to the metric name.`)
 
 		httpPort      = flags.Int("http-port", 80, `Port to use for servicing HTTP traffic.`)
 		httpsPort     = flags.Int("https-port", 443, `Port to use for servicing HTTPS traffic.`)
@@ -157,8 +185,32 @@ Feature backed by OpenResty Lua libraries. Requires that OCSP stapling is not en
 		defServerPort = flags.Int("default-server-port", 8181, `Port to use for exposing the default server (catch-all).`)
 		healthzPort   = flags.Int("healthz-port", 10254, "Port to use for the healthz endpoint.")
 
+		enableHTTP3 = flags.Bool("enable-http3", false,
+			`Listen for HTTP/3 (QUIC) on the UDP port --http3-port with the certificates of the HTTPS
+servers, and advertise it in the Alt-Svc header of their responses.
+Requires a custom image with NGINX built with HTTP/3 support (--with-http_v3_module), checked
+at startup: the NGINX of the official image does not support it. Also requires TLSv1.3 in
+the ssl-protocols setting.`)
+		http3Port = flags.Int("http3-port", 443, `UDP port to use for servicing HTTP/3 traffic.`)
+
+		dynamicStreamPorts = flags.String("dynamic-stream-ports", "",
+			`Range of ports, in the form "first-last", where NGINX listens for the TCP and UDP services.
+The entries of the tcp-services and udp-services ConfigMaps with a port in the range are
+added, changed and removed without reloading NGINX. These entries cannot use a hostname or
+settings other than the load balancing algorithm. At most 1000 ports.`)
+
 		disableCatchAll = flags.Bool("disable-catch-all", false,
 			`Disable support for catch-all Ingresses`)
+
+		conflictPolicy = flags.String("conflict-policy", controller.ConflictPolicyOldestWins,
+			`Policy applied to the Ingresses defining the same path, server snippet or alias of a host.
+"oldest-wins" ignores the conflicting configuration of the newer Ingresses, "reject-newer" ignores
+the newer Ingresses and "namespace-owns-host" ignores the Ingresses defining a host already defined
+in a different namespace.`)
+
+		reloadHistorySize = flags.Int("reload-history-size", 10,
+			`Number of configuration changes that required a reload of NGINX kept in memory.
+Exposed in the /reloads endpoint of the healthz port.`)
 	)
 
 	flags.MarkDeprecated("status-port", `The status port is a unix socket now.`)
@@ -210,6 +262,40 @@ Feature backed by OpenResty Lua libraries. Requires that OCSP stapling is not en
 		return false, nil, fmt.Errorf("Port %v is already in use. Please check the flag --ssl-passthrough-proxy-port", *sslProxyPort)
 	}
 
+	if *enableHTTP3 && !ing_net.IsUDPPortAvailable(*http3Port) {
+		return false, nil, fmt.Errorf("UDP port %v is already in use. Please check the flag --http3-port", *http3Port)
+	}
+
+	var dynamicStream ngx_config.PortRange
+	if *dynamicStreamPorts != "" {
+		var err error
+		dynamicStream, err = ngx_config.ParsePortRange(*dynamicStreamPorts)
+		if err != nil {
+			return false, nil, fmt.Errorf("Invalid value for flag --dynamic-stream-ports: %v", err)
+		}
+
+		if len(dynamicStream.Ports()) > maxDynamicStreamPorts {
+			return false, nil, fmt.Errorf("Flag --dynamic-stream-ports cannot contain more than %v ports", maxDynamicStreamPorts)
+		}
+
+		ports := map[string]int{
+			"http-port":                  *httpPort,
+			"https-port":                 *httpsPort,
+			"default-server-port":        *defServerPort,
+			"healthz-port":               *healthzPort,
+			"ssl-passthrough-proxy-port": *sslProxyPort,
+		}
+		if *enableHTTP3 {
+			ports["http3-port"] = *http3Port
+		}
+
+		for flag, port := range ports {
+			if dynamicStream.Contains(port) {
+				return false, nil, fmt.Errorf("Flag --dynamic-stream-ports contains the port %v of the flag --%v", port, flag)
+			}
+		}
+	}
+
 	if !*enableSSLChainCompletion {
 		klog.Warningf("SSL certificate chain completion is disabled (--enable-ssl-chain-completion=false)")
 	}
@@ -222,6 +308,31 @@ Feature backed by OpenResty Lua libraries. Requires that OCSP stapling is not en
 		return false, nil, fmt.Errorf("Flags --publish-service and --publish-status-address are mutually exclusive")
 	}
 
+	if *enableZoneMetrics && !*enableMetrics {
+		return false, nil, fmt.Errorf("Flag --enable-zone-metrics requires --enable-metrics")
+	}
+
+	if !controller.IsValidConflictPolicy(*conflictPolicy) {
+		return false, nil, fmt.Errorf("Invalid value %q for flag --conflict-policy", *conflictPolicy)
+	}
+
+	if *statsdAddress != "" && !*enableMetrics {
+		return false, nil, fmt.Errorf("Flag --statsd-address requires --enable-metrics")
+	}
+
+	if *statsdAddress != "" && *statsdFlushInterval <= 0 {
+		return false, nil, fmt.Errorf("Flag --statsd-flush-interval must be greater than zero")
+	}
+
+	if len(*statsdTags) > 0 && !*statsdDogStatsD {
+		return false, nil, fmt.Errorf("Flag --statsd-tags requires --statsd-dogstatsd")
+	}
+
+	http3ListenPort := 0
+	if *enableHTTP3 {
+		http3ListenPort = *http3Port
+	}
+
 	nginx.HealthPath = *defHealthzURL
 
 	config := &controller.Configuration{
@@ -232,6 +343,7 @@ Feature backed by OpenResty Lua libraries. Requires that OCSP stapling is not en
 		EnableProfiling:            *profiling,
 		EnableMetrics:              *enableMetrics,
 		MetricsPerHost:             *metricsPerHost,
+		EnableZoneMetrics:          *enableZoneMetrics,
 		EnableSSLPassthrough:       *enableSSLPassthrough,
 		EnableSSLChainCompletion:   *enableSSLChainCompletion,
 		ResyncPeriod:               *resyncPeriod,
@@ -240,6 +352,7 @@ Feature backed by OpenResty Lua libraries. Requires that OCSP stapling is not en
 		ConfigMapName:              *configMap,
 		TCPConfigMapName:           *tcpConfigMapName,
 		UDPConfigMapName:           *udpConfigMapName,
+		HostOwnershipConfigMapName: *hostOwnershipConfigMapName,
 		DefaultSSLCertificate:      *defSSLCertificate,
 		HealthCheckTimeout:         *healthCheckTimeout,
 		PublishService:             *publishSvc,
@@ -254,8 +367,20 @@ Feature backed by OpenResty Lua libraries. Requires that OCSP stapling is not en
 			HTTP:     *httpPort,
 			HTTPS:    *httpsPort,
 			SSLProxy: *sslProxyPort,
+			HTTP3:    http3ListenPort,
+
+			DynamicStream: dynamicStream,
+		},
+		DisableCatchAll:   *disableCatchAll,
+		ConflictPolicy:    *conflictPolicy,
+		ReloadHistorySize: *reloadHistorySize,
+		StatsD: metric.StatsDConfig{
+			Address:       *statsdAddress,
+			Prefix:        *statsdPrefix,
+			Tags:          *statsdTags,
+			FlushInterval: *statsdFlushInterval,
+			DogStatsD:     *statsdDogStatsD,
 		},
-		DisableCatchAll: *disableCatchAll,
 	}
 
 	return false, config, nil
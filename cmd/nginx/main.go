@@ -76,6 +76,11 @@ func main() {
 
 	nginxVersion()
 
+	err = checkHTTP3Support(conf.ListenPorts.HTTP3 != 0)
+	if err != nil {
+		klog.Fatal(err)
+	}
+
 	fs, err := file.NewLocalFS()
 	if err != nil {
 		klog.Fatal(err)
@@ -136,7 +141,16 @@ func main() {
 
 	mc := metric.NewDummyCollector()
 	if conf.EnableMetrics {
-		mc, err = metric.NewCollector(conf.MetricsPerHost, reg)
+		var sinks []metric.Sink
+		if conf.StatsD.Address != "" {
+			sink, err := metric.NewStatsDSink(conf.StatsD)
+			if err != nil {
+				klog.Fatalf("Error creating StatsD sink: %v", err)
+			}
+			sinks = append(sinks, sink)
+		}
+
+		mc, err = metric.NewCollector(conf.MetricsPerHost, conf.EnableZoneMetrics, reg, sinks...)
 		if err != nil {
 			klog.Fatalf("Error creating prometheus collector:  %v", err)
 		}
@@ -156,6 +170,8 @@ func main() {
 
 	registerHealthz(ngx, mux)
 	registerMetrics(reg, mux)
+	registerReloadHistory(ngx, mux)
+	registerTrafficStats(mc, mux)
 	registerHandlers(mux)
 
 	go startHTTPServer(conf.ListenPorts.Health, mux)
@@ -285,6 +301,34 @@ func registerHealthz(ic *controller.NGINXController, mux *http.ServeMux) {
 	)
 }
 
+func registerReloadHistory(ic *controller.NGINXController, mux *http.ServeMux) {
+	mux.HandleFunc("/reloads", func(w http.ResponseWriter, r *http.Request) {
+		b, err := json.Marshal(ic.ReloadHistory())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(b)
+	})
+}
+
+func registerTrafficStats(mc metric.Collector, mux *http.ServeMux) {
+	mux.HandleFunc("/traffic", func(w http.ResponseWriter, r *http.Request) {
+		b, err := json.Marshal(mc.TrafficStats())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(b)
+	})
+}
+
 func registerMetrics(reg *prometheus.Registry, mux *http.ServeMux) {
 	mux.Handle(
 		"/metrics",
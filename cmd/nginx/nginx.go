@@ -17,12 +17,17 @@ limitations under the License.
 package main
 
 import (
+	"fmt"
 	"os"
 	"os/exec"
+	"strings"
 
 	"k8s.io/klog"
 )
 
+// http3Module is the configure option of the NGINX builds with HTTP/3 support
+const http3Module = "--with-http_v3_module"
+
 func nginxVersion() {
 	flag := "-v"
 
@@ -35,3 +40,27 @@ func nginxVersion() {
 	cmd.Stderr = os.Stderr
 	cmd.Run()
 }
+
+// checkHTTP3Support returns an error when HTTP/3 is enabled but the NGINX
+// binary was built without the HTTP/3 module
+func checkHTTP3Support(enabled bool) error {
+	if !enabled {
+		return nil
+	}
+
+	// nginx -V prints the configure options to stderr
+	out, err := exec.Command("nginx", "-V").CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("unexpected error reading the NGINX build options: %v", err)
+	}
+
+	return http3Supported(string(out))
+}
+
+func http3Supported(buildInfo string) error {
+	if !strings.Contains(buildInfo, http3Module) {
+		return fmt.Errorf("flag --enable-http3 requires NGINX built with HTTP/3 support (%v) but the NGINX binary of this image does not support it", http3Module)
+	}
+
+	return nil
+}
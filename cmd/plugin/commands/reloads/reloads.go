@@ -0,0 +1,105 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reloads
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/spf13/cobra"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	"k8s.io/ingress-nginx/cmd/plugin/request"
+	"k8s.io/ingress-nginx/cmd/plugin/util"
+	"k8s.io/ingress-nginx/internal/ingress/reload"
+)
+
+// CreateCommand creates and returns this cobra subcommand
+func CreateCommand(flags *genericclioptions.ConfigFlags) *cobra.Command {
+	var pod, deployment *string
+	cmd := &cobra.Command{
+		Use:   "reloads",
+		Short: "Show the last configuration changes that required a reload of NGINX",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			port, err := cmd.Flags().GetInt("healthz-port")
+			if err != nil {
+				return err
+			}
+
+			util.PrintError(reloads(flags, *pod, *deployment, port))
+			return nil
+		},
+	}
+
+	pod = util.AddPodFlag(cmd)
+	deployment = util.AddDeploymentFlag(cmd)
+	cmd.Flags().Int("healthz-port", 10254, "The healthz port of the ingress-nginx pod")
+
+	return cmd
+}
+
+func reloads(flags *genericclioptions.ConfigFlags, podName string, deployment string, port int) error {
+	pod, err := request.ChoosePod(flags, podName, deployment)
+	if err != nil {
+		return err
+	}
+
+	out, err := request.GetPodProxy(flags, &pod, port, "/reloads")
+	if err != nil {
+		return err
+	}
+
+	var diffs []reload.ConfigurationDiff
+	err = json.Unmarshal(out, &diffs)
+	if err != nil {
+		return err
+	}
+
+	printer := tabwriter.NewWriter(os.Stdout, 6, 4, 3, ' ', 0)
+	defer printer.Flush()
+
+	fmt.Fprintln(printer, "TIME\tREASONS\tCHANGE\tHOST+PATH\tINGRESS\tFIELDS")
+
+	for _, diff := range diffs {
+		timestamp := diff.Timestamp.Format(time.RFC3339)
+		reasons := strings.Join(diff.Reasons, ",")
+		if diff.Error != "" {
+			reasons += " (failed)"
+		}
+
+		if len(diff.Servers) == 0 && len(diff.StreamServices) == 0 {
+			fmt.Fprintf(printer, "%v\t%v\t\t\t\t\n", timestamp, reasons)
+		}
+
+		for _, server := range diff.Servers {
+			fmt.Fprintf(printer, "%v\t%v\t%v\t%v\t\t%v\n", timestamp, reasons, server.Change, server.Hostname, strings.Join(server.Fields, ","))
+			for _, location := range server.Locations {
+				fmt.Fprintf(printer, "%v\t%v\t%v\t%v\t%v\t%v\n", timestamp, reasons, location.Change, server.Hostname+location.Path, location.Ingress, strings.Join(location.Fields, ","))
+			}
+		}
+
+		for _, svc := range diff.StreamServices {
+			fmt.Fprintf(printer, "%v\t%v\t%v\t%v\t\t\n", timestamp, reasons, svc.Change, fmt.Sprintf("%v:%v", strings.ToLower(string(svc.Protocol)), svc.Port))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,180 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package top
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/spf13/cobra"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	"k8s.io/ingress-nginx/cmd/plugin/request"
+	"k8s.io/ingress-nginx/cmd/plugin/util"
+	"k8s.io/ingress-nginx/internal/ingress/metric/traffic"
+)
+
+// CreateCommand creates and returns this cobra subcommand
+func CreateCommand(flags *genericclioptions.ConfigFlags) *cobra.Command {
+	var deployment *string
+	cmd := &cobra.Command{
+		Use:   "top",
+		Short: "Show the traffic of each Ingress, host and service across all ingress-nginx pods",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			port, err := cmd.Flags().GetInt("healthz-port")
+			if err != nil {
+				return err
+			}
+			interval, err := cmd.Flags().GetDuration("interval")
+			if err != nil {
+				return err
+			}
+			once, err := cmd.Flags().GetBool("once")
+			if err != nil {
+				return err
+			}
+
+			util.PrintError(top(flags, *deployment, port, interval, once))
+			return nil
+		},
+	}
+
+	deployment = util.AddDeploymentFlag(cmd)
+	cmd.Flags().Int("healthz-port", 10254, "The healthz port of the ingress-nginx pods")
+	cmd.Flags().Duration("interval", 2*time.Second, "Time between two refreshes of the table")
+	cmd.Flags().Bool("once", false, "Print the table once and exit")
+
+	return cmd
+}
+
+func top(flags *genericclioptions.ConfigFlags, deployment string, port int, interval time.Duration, once bool) error {
+	for {
+		pods, err := request.GetDeploymentPods(flags, deployment)
+		if err != nil {
+			return err
+		}
+
+		var stats [][]traffic.Stats
+		for _, pod := range pods {
+			out, err := request.GetPodProxy(flags, &pod, port, "/traffic")
+			if err != nil {
+				return fmt.Errorf("error getting traffic statistics from pod %v: %v", pod.Name, err)
+			}
+
+			var podStats []traffic.Stats
+			err = json.Unmarshal(out, &podStats)
+			if err != nil {
+				return err
+			}
+
+			stats = append(stats, podStats)
+		}
+
+		if !once {
+			// clear the screen and move the cursor to the top left corner
+			fmt.Print("\033[H\033[2J")
+		}
+
+		printStats(len(pods), aggregate(stats))
+
+		if once {
+			return nil
+		}
+
+		time.Sleep(interval)
+	}
+}
+
+type statsKey struct {
+	namespace string
+	ingress   string
+	host      string
+	service   string
+}
+
+// aggregate merges the statistics of several pods. Rates are added, the
+// median latency is averaged weighted by the number of requests and the
+// 99th percentile is the highest one, as percentiles cannot be merged.
+func aggregate(podStats [][]traffic.Stats) []traffic.Stats {
+	merged := make(map[statsKey]*traffic.Stats)
+	var keys []statsKey
+
+	for _, stats := range podStats {
+		for _, s := range stats {
+			key := statsKey{s.Namespace, s.Ingress, s.Host, s.Service}
+
+			m, ok := merged[key]
+			if !ok {
+				m = &traffic.Stats{
+					Namespace: s.Namespace,
+					Ingress:   s.Ingress,
+					Host:      s.Host,
+					Service:   s.Service,
+				}
+				merged[key] = m
+				keys = append(keys, key)
+			}
+
+			requests := float64(m.Requests + s.Requests)
+			if requests > 0 {
+				m.ErrorRate = (m.ErrorRate*float64(m.Requests) + s.ErrorRate*float64(s.Requests)) / requests
+				m.LatencyP50 = (m.LatencyP50*float64(m.Requests) + s.LatencyP50*float64(s.Requests)) / requests
+			}
+			if s.LatencyP99 > m.LatencyP99 {
+				m.LatencyP99 = s.LatencyP99
+			}
+
+			m.Requests += s.Requests
+			m.RequestsPerSecond += s.RequestsPerSecond
+		}
+	}
+
+	result := make([]traffic.Stats, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, *merged[key])
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].RequestsPerSecond > result[j].RequestsPerSecond
+	})
+
+	return result
+}
+
+func printStats(pods int, stats []traffic.Stats) {
+	fmt.Printf("%v - %v pod(s) - requests in the last minute\n\n", time.Now().Format(time.RFC3339), pods)
+
+	printer := tabwriter.NewWriter(os.Stdout, 6, 4, 3, ' ', 0)
+	defer printer.Flush()
+
+	fmt.Fprintln(printer, "NAMESPACE\tINGRESS\tHOST\tSERVICE\tRPS\tERRORS\tP50\tP99")
+
+	for _, s := range stats {
+		fmt.Fprintf(printer, "%v\t%v\t%v\t%v\t%.2f\t%.2f%%\t%v\t%v\n",
+			s.Namespace, s.Ingress, s.Host, s.Service,
+			s.RequestsPerSecond, s.ErrorRate*100,
+			formatLatency(s.LatencyP50), formatLatency(s.LatencyP99))
+	}
+}
+
+func formatLatency(seconds float64) string {
+	return (time.Duration(seconds * float64(time.Second))).Round(time.Millisecond).String()
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package top
+
+import (
+	"testing"
+
+	"k8s.io/ingress-nginx/internal/ingress/metric/traffic"
+)
+
+func TestAggregate(t *testing.T) {
+	podStats := [][]traffic.Stats{
+		{
+			{Namespace: "default", Ingress: "demo", Host: "demo.com", Service: "demo",
+				Requests: 300, RequestsPerSecond: 5, ErrorRate: 0.1, LatencyP50: 0.1, LatencyP99: 0.5},
+			{Namespace: "default", Ingress: "other", Host: "other.com", Service: "other",
+				Requests: 60, RequestsPerSecond: 1, ErrorRate: 0, LatencyP50: 0.2, LatencyP99: 0.3},
+		},
+		{
+			{Namespace: "default", Ingress: "demo", Host: "demo.com", Service: "demo",
+				Requests: 100, RequestsPerSecond: 2, ErrorRate: 0.5, LatencyP50: 0.3, LatencyP99: 0.9},
+		},
+		{},
+	}
+
+	stats := aggregate(podStats)
+	if len(stats) != 2 {
+		t.Fatalf("expected 2 series but got %v", len(stats))
+	}
+
+	demo := stats[0]
+	if demo.Ingress != "demo" {
+		t.Fatalf("expected the series sorted by requests per second but got %+v", stats)
+	}
+	if demo.Requests != 400 || demo.RequestsPerSecond != 7 {
+		t.Errorf("expected the requests and rates to be added but got %+v", demo)
+	}
+	// weighted by the number of requests of each pod
+	if demo.ErrorRate != 0.2 {
+		t.Errorf("expected an error rate of 0.2 but got %v", demo.ErrorRate)
+	}
+	if demo.LatencyP50 < 0.1499 || demo.LatencyP50 > 0.1501 {
+		t.Errorf("expected a median latency of 0.15 but got %v", demo.LatencyP50)
+	}
+	if demo.LatencyP99 != 0.9 {
+		t.Errorf("expected the highest 99th percentile 0.9 but got %v", demo.LatencyP99)
+	}
+
+	if other := stats[1]; other.Requests != 60 || other.RequestsPerSecond != 1 || other.LatencyP99 != 0.3 {
+		t.Errorf("expected the series of a single pod to be unchanged but got %+v", other)
+	}
+}
+
+func TestAggregateWithoutRequests(t *testing.T) {
+	stats := aggregate([][]traffic.Stats{
+		{{Namespace: "default", Ingress: "idle", LatencyP99: 0}},
+		{{Namespace: "default", Ingress: "idle", LatencyP99: 0}},
+	})
+
+	if len(stats) != 1 || stats[0].ErrorRate != 0 || stats[0].LatencyP50 != 0 {
+		t.Errorf("expected a single series without errors or latency but got %+v", stats)
+	}
+}
@@ -35,7 +35,9 @@ import (
 	"k8s.io/ingress-nginx/cmd/plugin/commands/info"
 	"k8s.io/ingress-nginx/cmd/plugin/commands/ingresses"
 	"k8s.io/ingress-nginx/cmd/plugin/commands/logs"
+	"k8s.io/ingress-nginx/cmd/plugin/commands/reloads"
 	"k8s.io/ingress-nginx/cmd/plugin/commands/ssh"
+	"k8s.io/ingress-nginx/cmd/plugin/commands/top"
 )
 
 func main() {
@@ -57,6 +59,8 @@ func main() {
 	rootCmd.AddCommand(logs.CreateCommand(flags))
 	rootCmd.AddCommand(exec.CreateCommand(flags))
 	rootCmd.AddCommand(ssh.CreateCommand(flags))
+	rootCmd.AddCommand(reloads.CreateCommand(flags))
+	rootCmd.AddCommand(top.CreateCommand(flags))
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
@@ -18,9 +18,12 @@ package request
 
 import (
 	"fmt"
+	"strconv"
+
 	apiv1 "k8s.io/api/core/v1"
 	"k8s.io/api/extensions/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	extensions "k8s.io/client-go/kubernetes/typed/extensions/v1beta1"
@@ -66,6 +69,42 @@ func GetDeploymentPod(flags *genericclioptions.ConfigFlags, deployment string) (
 	return ings[0], nil
 }
 
+// GetPodProxy performs a GET request to the given port and path of a pod
+// through the API server proxy and returns the body of the response
+func GetPodProxy(flags *genericclioptions.ConfigFlags, pod *apiv1.Pod, port int, path string) ([]byte, error) {
+	rawConfig, err := flags.ToRESTConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	api, err := corev1.NewForConfig(rawConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return api.RESTClient().Get().
+		Namespace(pod.Namespace).
+		Resource("pods").
+		SubResource("proxy").
+		Name(utilnet.JoinSchemeNamePort("http", pod.Name, strconv.Itoa(port))).
+		Suffix(path).
+		DoRaw()
+}
+
+// GetDeploymentPods finds all the pods from a given deployment
+func GetDeploymentPods(flags *genericclioptions.ConfigFlags, deployment string) ([]apiv1.Pod, error) {
+	pods, err := getDeploymentPods(flags, deployment)
+	if err != nil {
+		return pods, err
+	}
+
+	if len(pods) == 0 {
+		return pods, fmt.Errorf("No pods for deployment %v found in namespace %v", deployment, util.GetNamespace(flags))
+	}
+
+	return pods, nil
+}
+
 // GetIngressDefinitions returns an array of Ingress resource definitions
 func GetIngressDefinitions(flags *genericclioptions.ConfigFlags, namespace string) ([]v1beta1.Ingress, error) {
 	rawConfig, err := flags.ToRESTConfig()
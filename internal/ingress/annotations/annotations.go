@@ -33,22 +33,31 @@ import (
 	"k8s.io/ingress-nginx/internal/ingress/annotations/authtls"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/backendprotocol"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/clientbodybuffersize"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/concurrencylimit"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/connection"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/cors"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/customhttperrors"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/defaultbackend"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/grpcweb"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/headers"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/http2pushpreload"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/http3altsvc"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/influxdb"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/ipwhitelist"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/loadbalancing"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/log"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/luarestywaf"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/pathtype"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/portinredirect"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/proxy"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/proxycache"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/ratelimit"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/redirect"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/requestvalidation"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/retrypolicy"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/rewrite"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/routing"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/satisfy"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/secureupstream"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/serversnippet"
@@ -83,11 +92,18 @@ type Ingress struct {
 	//TODO: Change this back into an error when https://github.com/imdario/mergo/issues/100 is resolved
 	Denied             *string
 	ExternalAuth       authreq.Config
+	GRPCWeb            bool
+	Headers            headers.Config
 	HTTP2PushPreload   bool
+	PathType           pathtype.Config
 	Proxy              proxy.Config
+	ProxyCache         proxycache.Config
 	RateLimit          ratelimit.Config
 	Redirect           redirect.Config
+	RequestValidation  requestvalidation.Config
+	RetryPolicy        retrypolicy.Config
 	Rewrite            rewrite.Config
+	Routing            routing.Config
 	Satisfy            string
 	SecureUpstream     secureupstream.Config
 	ServerSnippet      string
@@ -97,10 +113,12 @@ type Ingress struct {
 	UsePortInRedirects bool
 	UpstreamHashBy     upstreamhashby.Config
 	LoadBalancing      string
+	ConcurrencyLimit   concurrencylimit.Config
 	UpstreamVhost      string
 	Whitelist          ipwhitelist.SourceRange
 	XForwardedPrefix   bool
 	SSLCiphers         string
+	HTTP3AltSvc        bool
 	Logs               log.Config
 	LuaRestyWAF        luarestywaf.Config
 	InfluxDB           influxdb.Config
@@ -127,11 +145,18 @@ func NewAnnotationExtractor(cfg resolver.Resolver) Extractor {
 			"CustomHTTPErrors":     customhttperrors.NewParser(cfg),
 			"DefaultBackend":       defaultbackend.NewParser(cfg),
 			"ExternalAuth":         authreq.NewParser(cfg),
+			"GRPCWeb":              grpcweb.NewParser(cfg),
+			"Headers":              headers.NewParser(cfg),
 			"HTTP2PushPreload":     http2pushpreload.NewParser(cfg),
+			"PathType":             pathtype.NewParser(cfg),
 			"Proxy":                proxy.NewParser(cfg),
+			"ProxyCache":           proxycache.NewParser(cfg),
 			"RateLimit":            ratelimit.NewParser(cfg),
 			"Redirect":             redirect.NewParser(cfg),
+			"RequestValidation":    requestvalidation.NewParser(cfg),
+			"RetryPolicy":          retrypolicy.NewParser(cfg),
 			"Rewrite":              rewrite.NewParser(cfg),
+			"Routing":              routing.NewParser(cfg),
 			"Satisfy":              satisfy.NewParser(cfg),
 			"SecureUpstream":       secureupstream.NewParser(cfg),
 			"ServerSnippet":        serversnippet.NewParser(cfg),
@@ -141,10 +166,12 @@ func NewAnnotationExtractor(cfg resolver.Resolver) Extractor {
 			"UsePortInRedirects":   portinredirect.NewParser(cfg),
 			"UpstreamHashBy":       upstreamhashby.NewParser(cfg),
 			"LoadBalancing":        loadbalancing.NewParser(cfg),
+			"ConcurrencyLimit":     concurrencylimit.NewParser(cfg),
 			"UpstreamVhost":        upstreamvhost.NewParser(cfg),
 			"Whitelist":            ipwhitelist.NewParser(cfg),
 			"XForwardedPrefix":     xforwardedprefix.NewParser(cfg),
 			"SSLCiphers":           sslcipher.NewParser(cfg),
+			"HTTP3AltSvc":          http3altsvc.NewParser(cfg),
 			"Logs":                 log.NewParser(cfg),
 			"LuaRestyWAF":          luarestywaf.NewParser(cfg),
 			"InfluxDB":             influxdb.NewParser(cfg),
@@ -0,0 +1,96 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package concurrencylimit
+
+import (
+	extensions "k8s.io/api/extensions/v1beta1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	ing_errors "k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+// maxQueueTimeout is the maximum time in milliseconds a request waits for
+// the backend, to keep the queue brief
+const maxQueueTimeout = 10000
+
+// Config contains the limit of concurrent requests sent to a backend
+type Config struct {
+	// MaxRequests is the maximum number of requests in flight to the
+	// backend, across all the clients
+	MaxRequests int `json:"maxRequests,omitempty"`
+	// QueueTimeout is the time in milliseconds a request waits for the
+	// backend when the limit is reached, before being rejected
+	QueueTimeout int `json:"queueTimeout,omitempty"`
+}
+
+// Equal tests for equality between two Config types
+func (c1 *Config) Equal(c2 *Config) bool {
+	if c1 == c2 {
+		return true
+	}
+	if c1 == nil || c2 == nil {
+		return false
+	}
+	if c1.MaxRequests != c2.MaxRequests {
+		return false
+	}
+	if c1.QueueTimeout != c2.QueueTimeout {
+		return false
+	}
+
+	return true
+}
+
+type concurrencyLimit struct {
+	r resolver.Resolver
+}
+
+// NewParser creates a new concurrency limit annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return concurrencyLimit{r}
+}
+
+// Parse parses the annotations contained in the ingress to limit the
+// requests in flight to the backends
+func (a concurrencyLimit) Parse(ing *extensions.Ingress) (interface{}, error) {
+	maxRequests, err := parser.GetIntAnnotation("max-concurrent-requests", ing)
+	if err != nil {
+		return nil, err
+	}
+
+	if maxRequests <= 0 {
+		return nil, ing_errors.NewInvalidAnnotationConfiguration("max-concurrent-requests",
+			"the limit must be greater than zero")
+	}
+
+	config := &Config{
+		MaxRequests: maxRequests,
+	}
+
+	queueTimeout, err := parser.GetIntAnnotation("max-concurrent-requests-queue-timeout", ing)
+	if err == nil {
+		if queueTimeout < 0 || queueTimeout > maxQueueTimeout {
+			return nil, ing_errors.NewInvalidAnnotationConfiguration("max-concurrent-requests-queue-timeout",
+				"the timeout must be between 0 and 10000 milliseconds")
+		}
+
+		config.QueueTimeout = queueTimeout
+	}
+
+	return config, nil
+}
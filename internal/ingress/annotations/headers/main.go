@@ -0,0 +1,248 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package headers
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	extensions "k8s.io/api/extensions/v1beta1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	ing_errors "k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+var (
+	// headerNameRegex matches a valid HTTP header name (RFC 7230 token)
+	headerNameRegex = regexp.MustCompile("^[A-Za-z0-9!#$%&'*+.^_`|~-]+$")
+	// headerValueRegex matches a header value of printable ASCII characters
+	headerValueRegex = regexp.MustCompile(`^[\x20-\x7E]*$`)
+
+	// reservedRequestHeaders are managed by NGINX and cannot be modified
+	reservedRequestHeaders = map[string]bool{
+		"host":              true,
+		"connection":        true,
+		"content-length":    true,
+		"transfer-encoding": true,
+	}
+)
+
+// Header is an HTTP header name and value
+type Header struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Operations contains the changes applied to the headers of a request or a
+// response. Headers are removed first, then set and finally appended.
+type Operations struct {
+	// Set replaces the value of the headers
+	Set []Header `json:"set,omitempty"`
+	// Add appends a value to the headers, keeping the existing ones
+	Add []Header `json:"add,omitempty"`
+	// Remove deletes the headers
+	Remove []string `json:"remove,omitempty"`
+}
+
+// IsEmpty returns true if there are no changes to apply
+func (o1 *Operations) IsEmpty() bool {
+	return len(o1.Set) == 0 && len(o1.Add) == 0 && len(o1.Remove) == 0
+}
+
+// Equal tests for equality between two Operations types
+func (o1 *Operations) Equal(o2 *Operations) bool {
+	if o1 == o2 {
+		return true
+	}
+	if o1 == nil || o2 == nil {
+		return false
+	}
+	if !headersEqual(o1.Set, o2.Set) {
+		return false
+	}
+	if !headersEqual(o1.Add, o2.Add) {
+		return false
+	}
+	if len(o1.Remove) != len(o2.Remove) {
+		return false
+	}
+	for i := range o1.Remove {
+		if o1.Remove[i] != o2.Remove[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// the order of the headers is relevant, i.e. for the values appended
+func headersEqual(h1, h2 []Header) bool {
+	if len(h1) != len(h2) {
+		return false
+	}
+	for i := range h1 {
+		if h1[i] != h2[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Config contains the changes applied to the headers of the requests sent
+// to the upstream and the responses sent to the client
+type Config struct {
+	Request  Operations `json:"request"`
+	Response Operations `json:"response"`
+}
+
+// Equal tests for equality between two Config types
+func (c1 *Config) Equal(c2 *Config) bool {
+	if c1 == c2 {
+		return true
+	}
+	if c1 == nil || c2 == nil {
+		return false
+	}
+	if !(&c1.Request).Equal(&c2.Request) {
+		return false
+	}
+	if !(&c1.Response).Equal(&c2.Response) {
+		return false
+	}
+
+	return true
+}
+
+type headers struct {
+	r resolver.Resolver
+}
+
+// NewParser creates a new header manipulation annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return headers{r}
+}
+
+// Parse parses the annotations contained in the ingress to change the
+// headers of the requests and responses. The set and add annotations
+// contain one "Name: value" header per line and the remove annotations a
+// comma separated list of header names.
+func (h headers) Parse(ing *extensions.Ingress) (interface{}, error) {
+	request, err := parseOperations("request", ing)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := parseOperations("response", ing)
+	if err != nil {
+		return nil, err
+	}
+
+	if request.IsEmpty() && response.IsEmpty() {
+		return nil, ing_errors.ErrMissingAnnotations
+	}
+
+	return &Config{
+		Request:  *request,
+		Response: *response,
+	}, nil
+}
+
+func parseOperations(kind string, ing *extensions.Ingress) (*Operations, error) {
+	ops := &Operations{}
+
+	var err error
+
+	ops.Set, err = parseHeaders(kind, fmt.Sprintf("%v-headers-set", kind), ing)
+	if err != nil {
+		return nil, err
+	}
+
+	ops.Add, err = parseHeaders(kind, fmt.Sprintf("%v-headers-add", kind), ing)
+	if err != nil {
+		return nil, err
+	}
+
+	name := fmt.Sprintf("%v-headers-remove", kind)
+	val, _ := parser.GetStringAnnotation(name, ing)
+	for _, header := range strings.Split(val, ",") {
+		header = strings.TrimSpace(header)
+		if header == "" {
+			continue
+		}
+
+		err := validateName(kind, header)
+		if err != nil {
+			return nil, ing_errors.NewInvalidAnnotationConfiguration(name, err.Error())
+		}
+
+		ops.Remove = append(ops.Remove, header)
+	}
+
+	return ops, nil
+}
+
+func parseHeaders(kind, name string, ing *extensions.Ingress) ([]Header, error) {
+	val, _ := parser.GetStringAnnotation(name, ing)
+
+	var headers []Header
+	for _, line := range strings.Split(val, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			return nil, ing_errors.NewInvalidAnnotationConfiguration(name,
+				fmt.Sprintf("header %q must have the format \"Name: value\"", line))
+		}
+
+		header := Header{
+			Name:  strings.TrimSpace(parts[0]),
+			Value: strings.TrimSpace(parts[1]),
+		}
+
+		err := validateName(kind, header.Name)
+		if err != nil {
+			return nil, ing_errors.NewInvalidAnnotationConfiguration(name, err.Error())
+		}
+
+		if !headerValueRegex.MatchString(header.Value) {
+			return nil, ing_errors.NewInvalidAnnotationConfiguration(name,
+				fmt.Sprintf("value of header %v contains invalid characters", header.Name))
+		}
+
+		headers = append(headers, header)
+	}
+
+	return headers, nil
+}
+
+func validateName(kind, name string) error {
+	if !headerNameRegex.MatchString(name) {
+		return fmt.Errorf("invalid header name %q", name)
+	}
+
+	if kind == "request" && reservedRequestHeaders[strings.ToLower(name)] {
+		return fmt.Errorf("request header %v cannot be modified", name)
+	}
+
+	return nil
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package headers
+
+import (
+	"reflect"
+	"testing"
+
+	api "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+func buildIngress() *extensions.Ingress {
+	return &extensions.Ingress{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+		},
+		Spec: extensions.IngressSpec{},
+	}
+}
+
+func TestParse(t *testing.T) {
+	ing := buildIngress()
+
+	data := map[string]string{}
+	data[parser.GetAnnotationWithPrefix("request-headers-set")] = "X-Env: production\nX-Quote: say \"hi\"\n"
+	data[parser.GetAnnotationWithPrefix("request-headers-add")] = "X-Forwarded-Client: ingress"
+	data[parser.GetAnnotationWithPrefix("request-headers-remove")] = "Cookie, X-Debug"
+	data[parser.GetAnnotationWithPrefix("response-headers-set")] = "Cache-Control: no-store"
+	data[parser.GetAnnotationWithPrefix("response-headers-remove")] = "Server,X-Powered-By"
+	ing.SetAnnotations(data)
+
+	i, err := NewParser(&resolver.Mock{}).Parse(ing)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := &Config{
+		Request: Operations{
+			Set: []Header{
+				{Name: "X-Env", Value: "production"},
+				{Name: "X-Quote", Value: `say "hi"`},
+			},
+			Add:    []Header{{Name: "X-Forwarded-Client", Value: "ingress"}},
+			Remove: []string{"Cookie", "X-Debug"},
+		},
+		Response: Operations{
+			Set:    []Header{{Name: "Cache-Control", Value: "no-store"}},
+			Remove: []string{"Server", "X-Powered-By"},
+		},
+	}
+
+	if !reflect.DeepEqual(i, expected) {
+		t.Errorf("expected %+v but got %+v", expected, i)
+	}
+}
+
+func TestParseWithoutAnnotations(t *testing.T) {
+	_, err := NewParser(&resolver.Mock{}).Parse(buildIngress())
+	if !errors.IsMissingAnnotations(err) {
+		t.Errorf("expected missing annotations error but got %v", err)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := map[string]string{
+		"request-headers-set":     "X-Env production",
+		"request-headers-add":     "X Env: production",
+		"request-headers-remove":  "Host",
+		"response-headers-set":    "X-Env: line\tbreak",
+		"response-headers-remove": "X-Powered-By, Bad Header",
+	}
+
+	for annotation, value := range tests {
+		ing := buildIngress()
+		ing.SetAnnotations(map[string]string{
+			parser.GetAnnotationWithPrefix(annotation): value,
+		})
+
+		_, err := NewParser(&resolver.Mock{}).Parse(ing)
+		if err == nil {
+			t.Errorf("%v: expected error parsing %q", annotation, value)
+		}
+	}
+}
+
+func TestEqual(t *testing.T) {
+	c1 := &Config{Request: Operations{Add: []Header{{"X-A", "1"}, {"X-A", "2"}}}}
+	c2 := &Config{Request: Operations{Add: []Header{{"X-A", "2"}, {"X-A", "1"}}}}
+
+	if !c1.Equal(c1) {
+		t.Errorf("expected a config to be equal to itself")
+	}
+
+	if c1.Equal(c2) {
+		t.Errorf("expected configs appending values in a different order to be different")
+	}
+}
@@ -0,0 +1,44 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package http3altsvc
+
+import (
+	extensions "k8s.io/api/extensions/v1beta1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+type http3AltSvc struct {
+	r resolver.Resolver
+}
+
+// NewParser creates a new HTTP/3 Alt-Svc annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return http3AltSvc{r}
+}
+
+// Parse parses the annotations contained in the ingress rule used to indicate
+// if the HTTPS responses of the server advertise the HTTP/3 listener
+func (a http3AltSvc) Parse(ing *extensions.Ingress) (interface{}, error) {
+	altSvc, err := parser.GetBoolAnnotation("http3-alt-svc", ing)
+	if err != nil {
+		return a.r.GetDefaultBackend().HTTP3AltSvc, nil
+	}
+
+	return altSvc, nil
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package http3altsvc
+
+import (
+	"testing"
+
+	api "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/defaults"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+type mockBackend struct {
+	resolver.Mock
+	altSvc bool
+}
+
+func (m mockBackend) GetDefaultBackend() defaults.Backend {
+	return defaults.Backend{HTTP3AltSvc: m.altSvc}
+}
+
+func TestParse(t *testing.T) {
+	annotation := parser.GetAnnotationWithPrefix("http3-alt-svc")
+
+	testCases := []struct {
+		title       string
+		annotations map[string]string
+		def         bool
+		expected    bool
+	}{
+		{"false - default true", map[string]string{annotation: "false"}, true, false},
+		{"true - default false", map[string]string{annotation: "true"}, false, true},
+		{"invalid - default true", map[string]string{annotation: "maybe"}, true, true},
+		{"no annotation - default true", map[string]string{}, true, true},
+		{"no annotation - default false", nil, false, false},
+	}
+
+	ing := &extensions.Ingress{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+		},
+		Spec: extensions.IngressSpec{},
+	}
+
+	for _, testCase := range testCases {
+		ing.SetAnnotations(testCase.annotations)
+		result, err := NewParser(mockBackend{altSvc: testCase.def}).Parse(ing)
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", testCase.title, err)
+		}
+		if result != testCase.expected {
+			t.Errorf("%v: expected %v but returned %v", testCase.title, testCase.expected, result)
+		}
+	}
+}
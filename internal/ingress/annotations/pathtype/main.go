@@ -0,0 +1,150 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pathtype
+
+import (
+	"fmt"
+	"strings"
+
+	extensions "k8s.io/api/extensions/v1beta1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	ing_errors "k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+const (
+	// Exact matches the path exactly, case sensitive
+	Exact = "Exact"
+	// Prefix matches the path elements split by / of the path, case sensitive.
+	// /foo matches /foo and /foo/bar but not /foobar. The trailing slash of
+	// the path is ignored.
+	Prefix = "Prefix"
+	// ImplementationSpecific uses the NGINX location semantics: a prefix
+	// location or a case insensitive regular expression if the regex
+	// modifier is enforced in the server
+	ImplementationSpecific = "ImplementationSpecific"
+)
+
+var pathTypes = map[string]string{
+	strings.ToLower(Exact):                  Exact,
+	strings.ToLower(Prefix):                 Prefix,
+	strings.ToLower(ImplementationSpecific): ImplementationSpecific,
+}
+
+// Config defines how the paths of an Ingress are matched
+type Config struct {
+	// Default is the type of the paths without their own type
+	Default string `json:"default,omitempty"`
+	// Paths contains the type of specific paths
+	Paths map[string]string `json:"paths,omitempty"`
+}
+
+// Type returns the path type of a path of the Ingress
+func (c Config) Type(path string) string {
+	if pt, ok := c.Paths[path]; ok {
+		return pt
+	}
+
+	if c.Default == "" {
+		return ImplementationSpecific
+	}
+
+	return c.Default
+}
+
+type pathType struct {
+	r resolver.Resolver
+}
+
+// NewParser creates a new path type annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return pathType{r}
+}
+
+// Parse parses the annotations contained in the ingress rule used to
+// indicate how the paths of the Ingress are matched. The annotation contains
+// either the type of all the paths or one "<path> <type>" entry per line,
+// where an entry without path sets the type of the remaining paths. An
+// invalid entry makes the whole annotation invalid.
+func (a pathType) Parse(ing *extensions.Ingress) (interface{}, error) {
+	config := Config{Default: ImplementationSpecific}
+
+	val, err := parser.GetStringAnnotation("path-type", ing)
+	if err != nil {
+		return config, nil
+	}
+
+	for _, line := range strings.Split(val, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		if len(fields) > 2 {
+			return nil, ing_errors.NewInvalidAnnotationConfiguration("path-type",
+				fmt.Sprintf("%q is not a valid entry, expected \"<path> <type>\"", line))
+		}
+
+		pt, ok := pathTypes[strings.ToLower(fields[len(fields)-1])]
+		if !ok {
+			return nil, ing_errors.NewInvalidAnnotationConfiguration("path-type",
+				fmt.Sprintf("%q is not a valid path type, expected %v, %v or %v", fields[len(fields)-1], Exact, Prefix, ImplementationSpecific))
+		}
+
+		if len(fields) == 1 {
+			config.Default = pt
+			continue
+		}
+
+		if config.Paths == nil {
+			config.Paths = make(map[string]string)
+		}
+		config.Paths[fields[0]] = pt
+	}
+
+	return config, nil
+}
+
+// NormalizePath returns the path of a location with the given path type. The
+// trailing slash of a Prefix path is removed, so /foo and /foo/ are the same
+// location.
+func NormalizePath(path, pt string) string {
+	if pt != Prefix {
+		return path
+	}
+
+	path = strings.TrimRight(path, "/")
+	if path == "" {
+		return "/"
+	}
+
+	return path
+}
+
+// Priority returns the precedence of a path type between locations with
+// the same path. Lower values take precedence.
+func Priority(pt string) int {
+	switch pt {
+	case Exact:
+		return 0
+	case Prefix:
+		return 1
+	default:
+		return 2
+	}
+}
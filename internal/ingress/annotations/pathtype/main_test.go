@@ -0,0 +1,114 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pathtype
+
+import (
+	"reflect"
+	"testing"
+
+	api "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		annotations map[string]string
+		expected    Config
+	}{
+		{nil, Config{Default: ImplementationSpecific}},
+		{map[string]string{parser.GetAnnotationWithPrefix("path-type"): "Exact"}, Config{Default: Exact}},
+		{map[string]string{parser.GetAnnotationWithPrefix("path-type"): " prefix "}, Config{Default: Prefix}},
+		{map[string]string{parser.GetAnnotationWithPrefix("path-type"): "ImplementationSpecific"}, Config{Default: ImplementationSpecific}},
+		{map[string]string{parser.GetAnnotationWithPrefix("path-type"): "/foo Exact\n/bar prefix\n"},
+			Config{Default: ImplementationSpecific, Paths: map[string]string{"/foo": Exact, "/bar": Prefix}}},
+		{map[string]string{parser.GetAnnotationWithPrefix("path-type"): "Prefix\n/foo Exact"},
+			Config{Default: Prefix, Paths: map[string]string{"/foo": Exact}}},
+	}
+
+	for _, test := range tests {
+		ing := &extensions.Ingress{
+			ObjectMeta: meta_v1.ObjectMeta{
+				Name:        "foo",
+				Namespace:   api.NamespaceDefault,
+				Annotations: test.annotations,
+			},
+		}
+
+		i, err := NewParser(&resolver.Mock{}).Parse(ing)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(i, test.expected) {
+			t.Errorf("expected %+v but got %+v", test.expected, i)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, val := range []string{"Regex", "/foo Regex", "/foo Exact Prefix", "Prefix\n/foo"} {
+		ing := &extensions.Ingress{
+			ObjectMeta: meta_v1.ObjectMeta{
+				Name:        "foo",
+				Namespace:   api.NamespaceDefault,
+				Annotations: map[string]string{parser.GetAnnotationWithPrefix("path-type"): val},
+			},
+		}
+
+		_, err := NewParser(&resolver.Mock{}).Parse(ing)
+		if err == nil {
+			t.Errorf("%q: expected an error", val)
+		}
+	}
+}
+
+func TestNormalizePath(t *testing.T) {
+	testCases := []struct {
+		path     string
+		pathType string
+		expected string
+	}{
+		{"/foo/", Prefix, "/foo"},
+		{"/foo", Prefix, "/foo"},
+		{"/", Prefix, "/"},
+		{"/foo/", Exact, "/foo/"},
+		{"/foo/", ImplementationSpecific, "/foo/"},
+	}
+
+	for _, tc := range testCases {
+		if actual := NormalizePath(tc.path, tc.pathType); actual != tc.expected {
+			t.Errorf("%v %v: expected %v but got %v", tc.pathType, tc.path, tc.expected, actual)
+		}
+	}
+}
+
+func TestConfigType(t *testing.T) {
+	config := Config{Default: Prefix, Paths: map[string]string{"/foo": Exact}}
+
+	if pt := config.Type("/foo"); pt != Exact {
+		t.Errorf("expected %v but got %v", Exact, pt)
+	}
+	if pt := config.Type("/bar"); pt != Prefix {
+		t.Errorf("expected %v but got %v", Prefix, pt)
+	}
+	if pt := (Config{}).Type("/bar"); pt != ImplementationSpecific {
+		t.Errorf("expected %v but got %v", ImplementationSpecific, pt)
+	}
+}
@@ -0,0 +1,206 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package proxycache
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	extensions "k8s.io/api/extensions/v1beta1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	ing_errors "k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+// DefaultKey includes the host in the key because all the locations share
+// the same upstream (upstream_balancer) in $proxy_host
+const DefaultKey = "$scheme$host$request_uri"
+
+var (
+	// ZoneNameRegex matches a valid name of a cache zone
+	ZoneNameRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+	// TimeRegex matches a valid NGINX time of the cache directives
+	TimeRegex = regexp.MustCompile(`^[0-9]+(ms|s|m|h|d|w|M|y)?$`)
+
+	codeRegex     = regexp.MustCompile(`^([1-5][0-9]{2}|any)$`)
+	variableRegex = regexp.MustCompile(`^\$[a-zA-Z0-9_]+$`)
+	// the key is written between quotes in the configuration
+	keyRegex = regexp.MustCompile(`^[a-zA-Z0-9_$/:.?=&{}-]+$`)
+)
+
+// Valid is the time responses with some status codes are cached
+type Valid struct {
+	Codes []string `json:"codes"`
+	Time  string   `json:"time"`
+}
+
+// Config contains the response caching configuration of a location
+type Config struct {
+	// Zone is the name of a cache zone defined in the proxy-cache-zones
+	// setting of the configuration ConfigMap
+	Zone string `json:"zone,omitempty"`
+	// Key of the cached responses
+	Key   string  `json:"key,omitempty"`
+	Valid []Valid `json:"valid,omitempty"`
+	// Bypass contains variables. The response is not taken from nor saved
+	// to the cache when any of them is not empty and not "0".
+	Bypass []string `json:"bypass,omitempty"`
+	// StaleWhileRevalidate serves the cached responses while they are
+	// updated in the background, or when the upstream fails
+	StaleWhileRevalidate bool `json:"staleWhileRevalidate,omitempty"`
+}
+
+// IsEnabled returns true if the responses of the location are cached
+func (c1 *Config) IsEnabled() bool {
+	return c1.Zone != ""
+}
+
+// Equal tests for equality between two Config types
+func (c1 *Config) Equal(c2 *Config) bool {
+	if c1 == c2 {
+		return true
+	}
+	if c1 == nil || c2 == nil {
+		return false
+	}
+	if c1.Zone != c2.Zone {
+		return false
+	}
+	if c1.Key != c2.Key {
+		return false
+	}
+	if len(c1.Valid) != len(c2.Valid) {
+		return false
+	}
+	for i := range c1.Valid {
+		if c1.Valid[i].Time != c2.Valid[i].Time {
+			return false
+		}
+		if strings.Join(c1.Valid[i].Codes, " ") != strings.Join(c2.Valid[i].Codes, " ") {
+			return false
+		}
+	}
+	if strings.Join(c1.Bypass, " ") != strings.Join(c2.Bypass, " ") {
+		return false
+	}
+	if c1.StaleWhileRevalidate != c2.StaleWhileRevalidate {
+		return false
+	}
+
+	return true
+}
+
+type proxyCache struct {
+	r resolver.Resolver
+}
+
+// NewParser creates a new response caching annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return proxyCache{r}
+}
+
+// Parse parses the annotations contained in the ingress to cache the
+// responses of the locations in a cache zone.
+//
+// The proxy-cache-valid annotation contains a comma separated list of
+// status codes followed by a time, like "200 302 10m, 404 1m". The
+// proxy-cache-bypass annotation contains a comma separated list of NGINX
+// variables, like "$http_pragma, $cookie_nocache".
+func (a proxyCache) Parse(ing *extensions.Ingress) (interface{}, error) {
+	zone, err := parser.GetStringAnnotation("proxy-cache-zone", ing)
+	if err != nil {
+		return nil, err
+	}
+
+	if !ZoneNameRegex.MatchString(zone) {
+		return nil, ing_errors.NewInvalidAnnotationConfiguration("proxy-cache-zone",
+			fmt.Sprintf("invalid cache zone name %q", zone))
+	}
+
+	config := &Config{
+		Zone: zone,
+		Key:  DefaultKey,
+	}
+
+	key, err := parser.GetStringAnnotation("proxy-cache-key", ing)
+	if err == nil {
+		if !keyRegex.MatchString(key) {
+			return nil, ing_errors.NewInvalidAnnotationConfiguration("proxy-cache-key",
+				fmt.Sprintf("cache key %q contains invalid characters", key))
+		}
+
+		config.Key = key
+	}
+
+	valid, _ := parser.GetStringAnnotation("proxy-cache-valid", ing)
+	for _, entry := range strings.Split(valid, ",") {
+		fields := strings.Fields(entry)
+		if len(fields) == 0 {
+			continue
+		}
+
+		v, err := parseValid(fields)
+		if err != nil {
+			return nil, ing_errors.NewInvalidAnnotationConfiguration("proxy-cache-valid", err.Error())
+		}
+
+		config.Valid = append(config.Valid, *v)
+	}
+
+	bypass, _ := parser.GetStringAnnotation("proxy-cache-bypass", ing)
+	for _, variable := range strings.Split(bypass, ",") {
+		variable = strings.TrimSpace(variable)
+		if variable == "" {
+			continue
+		}
+
+		if !variableRegex.MatchString(variable) {
+			return nil, ing_errors.NewInvalidAnnotationConfiguration("proxy-cache-bypass",
+				fmt.Sprintf("invalid variable %q", variable))
+		}
+
+		config.Bypass = append(config.Bypass, variable)
+	}
+
+	config.StaleWhileRevalidate, _ = parser.GetBoolAnnotation("proxy-cache-stale-while-revalidate", ing)
+
+	return config, nil
+}
+
+// parseValid parses a list of status codes followed by the time the
+// responses are cached. Without status codes, 200, 301 and 302 responses
+// are cached.
+func parseValid(fields []string) (*Valid, error) {
+	time := fields[len(fields)-1]
+	if !TimeRegex.MatchString(time) {
+		return nil, fmt.Errorf("invalid time %q", time)
+	}
+
+	codes := fields[:len(fields)-1]
+	for _, code := range codes {
+		if !codeRegex.MatchString(code) {
+			return nil, fmt.Errorf("invalid status code %q", code)
+		}
+	}
+
+	return &Valid{
+		Codes: codes,
+		Time:  time,
+	}, nil
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package proxycache
+
+import (
+	"reflect"
+	"testing"
+
+	api "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+func buildIngress(annotations map[string]string) *extensions.Ingress {
+	data := map[string]string{}
+	for k, v := range annotations {
+		data[parser.GetAnnotationWithPrefix(k)] = v
+	}
+
+	return &extensions.Ingress{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:        "foo",
+			Namespace:   api.NamespaceDefault,
+			Annotations: data,
+		},
+		Spec: extensions.IngressSpec{},
+	}
+}
+
+func TestParse(t *testing.T) {
+	ing := buildIngress(map[string]string{
+		"proxy-cache-zone":                   "static",
+		"proxy-cache-key":                    "$host$uri$is_args$args",
+		"proxy-cache-valid":                  "200 302 10m, 404 1m,any 30s",
+		"proxy-cache-bypass":                 "$http_pragma, $cookie_nocache",
+		"proxy-cache-stale-while-revalidate": "true",
+	})
+
+	i, err := NewParser(&resolver.Mock{}).Parse(ing)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := &Config{
+		Zone: "static",
+		Key:  "$host$uri$is_args$args",
+		Valid: []Valid{
+			{Codes: []string{"200", "302"}, Time: "10m"},
+			{Codes: []string{"404"}, Time: "1m"},
+			{Codes: []string{"any"}, Time: "30s"},
+		},
+		Bypass:               []string{"$http_pragma", "$cookie_nocache"},
+		StaleWhileRevalidate: true,
+	}
+
+	if !reflect.DeepEqual(i, expected) {
+		t.Errorf("expected %+v but got %+v", expected, i)
+	}
+}
+
+func TestParseDefaults(t *testing.T) {
+	i, err := NewParser(&resolver.Mock{}).Parse(buildIngress(map[string]string{
+		"proxy-cache-zone": "static",
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := &Config{
+		Zone: "static",
+		Key:  DefaultKey,
+	}
+
+	if !reflect.DeepEqual(i, expected) {
+		t.Errorf("expected %+v but got %+v", expected, i)
+	}
+}
+
+func TestParseWithoutAnnotations(t *testing.T) {
+	_, err := NewParser(&resolver.Mock{}).Parse(buildIngress(map[string]string{
+		"proxy-cache-valid": "200 10m",
+	}))
+	if !errors.IsMissingAnnotations(err) {
+		t.Errorf("expected missing annotations error but got %v", err)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []map[string]string{
+		{"proxy-cache-zone": "static zone"},
+		{"proxy-cache-zone": "static", "proxy-cache-key": `$host"; return 200;`},
+		{"proxy-cache-zone": "static", "proxy-cache-valid": "200 10 minutes"},
+		{"proxy-cache-zone": "static", "proxy-cache-valid": "600 10m"},
+		{"proxy-cache-zone": "static", "proxy-cache-bypass": "http_pragma"},
+		{"proxy-cache-zone": "static", "proxy-cache-bypass": "$http_pragma $arg_nocache"},
+	}
+
+	for _, annotations := range tests {
+		_, err := NewParser(&resolver.Mock{}).Parse(buildIngress(annotations))
+		if err == nil {
+			t.Errorf("expected error parsing %v", annotations)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package requestvalidation
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	extensions "k8s.io/api/extensions/v1beta1"
+	"k8s.io/client-go/tools/cache"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	ing_errors "k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+const (
+	// SchemaKey is the key of the ConfigMap containing the JSON schema
+	// the body of the requests must match
+	SchemaKey = "schema"
+	// ContentTypesKey is the key of the ConfigMap containing a comma
+	// separated list of the media types allowed in the requests
+	ContentTypesKey = "content-types"
+	// MaxBodySizeKey is the key of the ConfigMap containing the maximum size
+	// of the body of the requests, like 512k or 1m
+	MaxBodySizeKey = "max-body-size"
+
+	defaultStatus = http.StatusBadRequest
+)
+
+var (
+	sizeRegex        = regexp.MustCompile(`^([0-9]+)([kKmMgG]?)$`)
+	contentTypeRegex = regexp.MustCompile(`^[a-z0-9!#$&^_.+-]+/[a-z0-9!#$&^_.+-]+$`)
+)
+
+// Config contains the validation applied to the requests of a location
+// before they are sent to the upstream
+type Config struct {
+	// ConfigMap is the namespace/name of the ConfigMap defining the validation
+	ConfigMap string `json:"configMap,omitempty"`
+	// Schema is the JSON schema the body of the requests must match
+	Schema string `json:"schema,omitempty"`
+	// ContentTypes contains the media types allowed in the requests
+	ContentTypes []string `json:"contentTypes,omitempty"`
+	// MaxBodySize is the maximum size in bytes of the body of the requests
+	MaxBodySize int64 `json:"maxBodySize,omitempty"`
+	// Status is the HTTP status code returned when a request is rejected
+	Status int `json:"status,omitempty"`
+}
+
+// IsEnabled returns true if the requests of the location are validated
+func (c1 *Config) IsEnabled() bool {
+	return c1.ConfigMap != ""
+}
+
+// Equal tests for equality between two Config types
+func (c1 *Config) Equal(c2 *Config) bool {
+	if c1 == c2 {
+		return true
+	}
+	if c1 == nil || c2 == nil {
+		return false
+	}
+	if c1.ConfigMap != c2.ConfigMap {
+		return false
+	}
+	if c1.Schema != c2.Schema {
+		return false
+	}
+	if strings.Join(c1.ContentTypes, ",") != strings.Join(c2.ContentTypes, ",") {
+		return false
+	}
+	if c1.MaxBodySize != c2.MaxBodySize {
+		return false
+	}
+	if c1.Status != c2.Status {
+		return false
+	}
+
+	return true
+}
+
+type requestValidation struct {
+	r resolver.Resolver
+}
+
+// NewParser creates a new request validation annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return requestValidation{r}
+}
+
+// Parse parses the annotations contained in the ingress to validate the
+// requests with the JSON schema, the content types and the maximum body
+// size defined in a ConfigMap
+func (a requestValidation) Parse(ing *extensions.Ingress) (interface{}, error) {
+	cm, err := parser.GetStringAnnotation("request-validation-configmap", ing)
+	if err != nil {
+		return nil, err
+	}
+
+	ns, name, err := cache.SplitMetaNamespaceKey(cm)
+	if err != nil || name == "" {
+		return nil, ing_errors.NewLocationDenied(fmt.Sprintf("invalid request validation ConfigMap %q", cm))
+	}
+
+	if ns == "" {
+		ns = ing.Namespace
+	}
+
+	// the ConfigMap is read with the permissions of the controller, so an
+	// Ingress can only use the ConfigMaps of its own namespace
+	if ns != ing.Namespace {
+		return nil, ing_errors.NewLocationDenied(
+			fmt.Sprintf("request validation ConfigMap %q is not in the namespace of the Ingress", cm))
+	}
+
+	key := fmt.Sprintf("%v/%v", ns, name)
+	configMap, err := a.r.GetConfigMap(key)
+	if err != nil {
+		return nil, ing_errors.LocationDenied{
+			Reason: errors.Wrapf(err, "unexpected error reading request validation ConfigMap %v", key),
+		}
+	}
+
+	if configMap == nil {
+		return nil, ing_errors.NewLocationDenied(fmt.Sprintf("request validation ConfigMap %v not found", key))
+	}
+
+	config := &Config{
+		ConfigMap: key,
+		Status:    defaultStatus,
+	}
+
+	if schema, ok := configMap.Data[SchemaKey]; ok {
+		config.Schema, err = compactSchema(schema)
+		if err != nil {
+			return nil, ing_errors.LocationDenied{
+				Reason: errors.Wrapf(err, "invalid JSON schema in ConfigMap %v", key),
+			}
+		}
+	}
+
+	if contentTypes, ok := configMap.Data[ContentTypesKey]; ok {
+		for _, contentType := range strings.Split(contentTypes, ",") {
+			contentType = strings.ToLower(strings.TrimSpace(contentType))
+			if contentType == "" {
+				continue
+			}
+
+			if !contentTypeRegex.MatchString(contentType) {
+				return nil, ing_errors.NewLocationDenied(
+					fmt.Sprintf("invalid content type %q in ConfigMap %v", contentType, key))
+			}
+
+			config.ContentTypes = append(config.ContentTypes, contentType)
+		}
+	}
+
+	if size, ok := configMap.Data[MaxBodySizeKey]; ok {
+		config.MaxBodySize, err = parseSize(strings.TrimSpace(size))
+		if err != nil {
+			return nil, ing_errors.LocationDenied{
+				Reason: errors.Wrapf(err, "invalid maximum body size in ConfigMap %v", key),
+			}
+		}
+	}
+
+	if config.Schema == "" && len(config.ContentTypes) == 0 && config.MaxBodySize == 0 {
+		return nil, ing_errors.NewLocationDenied(
+			fmt.Sprintf("ConfigMap %v does not define any request validation", key))
+	}
+
+	status, err := parser.GetIntAnnotation("request-validation-status", ing)
+	if err == nil {
+		if status < 400 || status > 599 {
+			return nil, ing_errors.NewInvalidAnnotationConfiguration("request-validation-status",
+				fmt.Sprintf("%v is not a valid error status code", status))
+		}
+
+		config.Status = status
+	}
+
+	return config, nil
+}
+
+// compactSchema checks the schema is a JSON object and removes the
+// whitespace, as it is included in the NGINX configuration
+func compactSchema(schema string) (string, error) {
+	var obj map[string]interface{}
+	err := json.Unmarshal([]byte(schema), &obj)
+	if err != nil {
+		return "", err
+	}
+
+	buf := &bytes.Buffer{}
+	err = json.Compact(buf, []byte(schema))
+	if err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// parseSize parses a size in bytes with an optional k, m or g suffix
+func parseSize(size string) (int64, error) {
+	matches := sizeRegex.FindStringSubmatch(size)
+	if matches == nil {
+		return 0, fmt.Errorf("%q is not a valid size", size)
+	}
+
+	value, err := strconv.ParseInt(matches[1], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	switch strings.ToLower(matches[2]) {
+	case "k":
+		value *= 1024
+	case "m":
+		value *= 1024 * 1024
+	case "g":
+		value *= 1024 * 1024 * 1024
+	}
+
+	return value, nil
+}
@@ -0,0 +1,167 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package requestvalidation
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	api "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+type mockConfigMap struct {
+	resolver.Mock
+	configMaps map[string]*api.ConfigMap
+}
+
+func (m mockConfigMap) GetConfigMap(name string) (*api.ConfigMap, error) {
+	if cm, ok := m.configMaps[name]; ok {
+		return cm, nil
+	}
+
+	return nil, fmt.Errorf("configmap %v not found", name)
+}
+
+func newMockConfigMap(data map[string]map[string]string) resolver.Resolver {
+	m := mockConfigMap{configMaps: map[string]*api.ConfigMap{}}
+	for key, d := range data {
+		m.configMaps[key] = &api.ConfigMap{Data: d}
+	}
+
+	return m
+}
+
+func buildIngress(annotations map[string]string) *extensions.Ingress {
+	data := map[string]string{}
+	for k, v := range annotations {
+		data[parser.GetAnnotationWithPrefix(k)] = v
+	}
+
+	return &extensions.Ingress{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:        "foo",
+			Namespace:   api.NamespaceDefault,
+			Annotations: data,
+		},
+		Spec: extensions.IngressSpec{},
+	}
+}
+
+func TestParse(t *testing.T) {
+	r := newMockConfigMap(map[string]map[string]string{
+		"default/orders": {
+			SchemaKey: `{
+				"type": "object",
+				"required": ["id"]
+			}`,
+			ContentTypesKey: "application/json, Application/Merge-Patch+JSON",
+			MaxBodySizeKey:  "512k",
+		},
+		"default/limits": {
+			MaxBodySizeKey: "1024",
+		},
+	})
+
+	testCases := []struct {
+		annotations map[string]string
+		expected    *Config
+	}{
+		{
+			map[string]string{"request-validation-configmap": "orders"},
+			&Config{
+				ConfigMap:    "default/orders",
+				Schema:       `{"type":"object","required":["id"]}`,
+				ContentTypes: []string{"application/json", "application/merge-patch+json"},
+				MaxBodySize:  512 * 1024,
+				Status:       400,
+			},
+		},
+		{
+			map[string]string{"request-validation-configmap": "default/limits", "request-validation-status": "413"},
+			&Config{
+				ConfigMap:   "default/limits",
+				MaxBodySize: 1024,
+				Status:      413,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		i, err := NewParser(r).Parse(buildIngress(tc.annotations))
+		if err != nil {
+			t.Errorf("unexpected error parsing %v: %v", tc.annotations, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(i, tc.expected) {
+			t.Errorf("expected %+v but got %+v", tc.expected, i)
+		}
+	}
+}
+
+func TestParseWithoutAnnotations(t *testing.T) {
+	_, err := NewParser(&resolver.Mock{}).Parse(buildIngress(nil))
+	if !errors.IsMissingAnnotations(err) {
+		t.Errorf("expected missing annotations error but got %v", err)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	r := newMockConfigMap(map[string]map[string]string{
+		"default/invalid-schema": {SchemaKey: `{"type": "object"`},
+		"default/array-schema":   {SchemaKey: `["object"]`},
+		"default/invalid-type":   {ContentTypesKey: "json"},
+		"default/invalid-size":   {MaxBodySizeKey: "1 megabyte"},
+		"default/empty":          {},
+		"default/valid":          {MaxBodySizeKey: "1m"},
+		"other/valid":            {MaxBodySizeKey: "1m"},
+	})
+
+	testCases := []struct {
+		annotations map[string]string
+		denied      bool
+	}{
+		{map[string]string{"request-validation-configmap": "missing"}, true},
+		{map[string]string{"request-validation-configmap": "a/b/c"}, true},
+		{map[string]string{"request-validation-configmap": "other/valid"}, true},
+		{map[string]string{"request-validation-configmap": "invalid-schema"}, true},
+		{map[string]string{"request-validation-configmap": "array-schema"}, true},
+		{map[string]string{"request-validation-configmap": "invalid-type"}, true},
+		{map[string]string{"request-validation-configmap": "invalid-size"}, true},
+		{map[string]string{"request-validation-configmap": "empty"}, true},
+		{map[string]string{"request-validation-configmap": "valid", "request-validation-status": "302"}, false},
+	}
+
+	for _, tc := range testCases {
+		_, err := NewParser(r).Parse(buildIngress(tc.annotations))
+		if err == nil {
+			t.Errorf("expected error parsing %v", tc.annotations)
+			continue
+		}
+
+		if errors.IsLocationDenied(err) != tc.denied {
+			t.Errorf("expected location denied %v parsing %v but got %v", tc.denied, tc.annotations, err)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package retrypolicy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	extensions "k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	ing_errors "k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+// retriableStatus contains the status codes supported by proxy_next_upstream
+// http://nginx.org/en/docs/http/ngx_http_proxy_module.html#proxy_next_upstream
+var retriableStatus = sets.NewInt(403, 404, 429, 500, 502, 503, 504)
+
+// Config contains the retry policy of a location
+type Config struct {
+	// OnStatus contains the status codes of the responses retried in the
+	// next upstream server, in addition to the conditions of the
+	// proxy-next-upstream annotation
+	OnStatus []int `json:"onStatus,omitempty"`
+	// PerTryTimeout is the idle timeout in seconds of each try, used as the
+	// connect, send and read timeouts of the try
+	PerTryTimeout int `json:"perTryTimeout,omitempty"`
+	// Budget is the maximum percentage of the active requests of the
+	// backend that can be retried at the same time
+	Budget int `json:"budget,omitempty"`
+}
+
+// IsEnforcedByBalancer returns true if the policy requires the Lua balancer
+func (c1 *Config) IsEnforcedByBalancer() bool {
+	return c1.PerTryTimeout > 0 || c1.Budget > 0
+}
+
+// Equal tests for equality between two Config types
+func (c1 *Config) Equal(c2 *Config) bool {
+	if c1 == c2 {
+		return true
+	}
+	if c1 == nil || c2 == nil {
+		return false
+	}
+	if len(c1.OnStatus) != len(c2.OnStatus) {
+		return false
+	}
+	for i := range c1.OnStatus {
+		if c1.OnStatus[i] != c2.OnStatus[i] {
+			return false
+		}
+	}
+	if c1.PerTryTimeout != c2.PerTryTimeout {
+		return false
+	}
+	if c1.Budget != c2.Budget {
+		return false
+	}
+
+	return true
+}
+
+type retryPolicy struct {
+	r resolver.Resolver
+}
+
+// NewParser creates a new retry policy annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return retryPolicy{r}
+}
+
+// Parse parses the annotations contained in the ingress to define the
+// status codes retried, the timeout of each try and the retry budget
+func (a retryPolicy) Parse(ing *extensions.Ingress) (interface{}, error) {
+	config := &Config{}
+	found := false
+
+	onStatus, err := parser.GetStringAnnotation("retry-on-status", ing)
+	if err == nil {
+		found = true
+
+		codes := sets.NewInt()
+		for _, s := range strings.Split(onStatus, ",") {
+			s = strings.TrimSpace(s)
+			if s == "" {
+				continue
+			}
+
+			code, err := strconv.Atoi(s)
+			if err != nil || !retriableStatus.Has(code) {
+				return nil, ing_errors.NewInvalidAnnotationConfiguration("retry-on-status",
+					fmt.Sprintf("%q is not a retriable status code (%v)", s, retriableStatus.List()))
+			}
+
+			codes.Insert(code)
+		}
+
+		config.OnStatus = codes.List()
+
+		nextUpstream, err := parser.GetStringAnnotation("proxy-next-upstream", ing)
+		if err != nil {
+			nextUpstream = a.r.GetDefaultBackend().ProxyNextUpstream
+		}
+
+		if codes.Len() > 0 && sets.NewString(strings.Fields(nextUpstream)...).Has("off") {
+			return nil, ing_errors.NewInvalidAnnotationConfiguration("retry-on-status",
+				"the retries to the next upstream server are disabled by proxy-next-upstream \"off\"")
+		}
+	}
+
+	perTryTimeout, err := parser.GetIntAnnotation("retry-per-try-timeout", ing)
+	if err == nil {
+		found = true
+
+		if perTryTimeout <= 0 {
+			return nil, ing_errors.NewInvalidAnnotationConfiguration("retry-per-try-timeout",
+				"the timeout must be greater than zero")
+		}
+
+		config.PerTryTimeout = perTryTimeout
+	}
+
+	budget, err := parser.GetIntAnnotation("retry-budget", ing)
+	if err == nil {
+		found = true
+
+		if budget <= 0 || budget > 100 {
+			return nil, ing_errors.NewInvalidAnnotationConfiguration("retry-budget",
+				"the budget must be a percentage between 1 and 100")
+		}
+
+		config.Budget = budget
+	}
+
+	if !found {
+		return nil, ing_errors.ErrMissingAnnotations
+	}
+
+	return config, nil
+}
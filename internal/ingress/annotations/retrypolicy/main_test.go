@@ -0,0 +1,103 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package retrypolicy
+
+import (
+	"reflect"
+	"testing"
+
+	api "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+func buildIngress(annotations map[string]string) *extensions.Ingress {
+	data := map[string]string{}
+	for k, v := range annotations {
+		data[parser.GetAnnotationWithPrefix(k)] = v
+	}
+
+	return &extensions.Ingress{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:        "foo",
+			Namespace:   api.NamespaceDefault,
+			Annotations: data,
+		},
+		Spec: extensions.IngressSpec{},
+	}
+}
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		annotations map[string]string
+		expected    *Config
+	}{
+		{
+			map[string]string{
+				"retry-on-status":       "503, 502,503",
+				"retry-per-try-timeout": "2",
+				"retry-budget":          "20",
+			},
+			&Config{OnStatus: []int{502, 503}, PerTryTimeout: 2, Budget: 20},
+		},
+		{
+			map[string]string{"retry-budget": "100"},
+			&Config{Budget: 100},
+		},
+	}
+
+	for _, tc := range testCases {
+		i, err := NewParser(&resolver.Mock{}).Parse(buildIngress(tc.annotations))
+		if err != nil {
+			t.Errorf("unexpected error parsing %v: %v", tc.annotations, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(i, tc.expected) {
+			t.Errorf("expected %+v but got %+v", tc.expected, i)
+		}
+	}
+}
+
+func TestParseWithoutAnnotations(t *testing.T) {
+	_, err := NewParser(&resolver.Mock{}).Parse(buildIngress(nil))
+	if !errors.IsMissingAnnotations(err) {
+		t.Errorf("expected missing annotations error but got %v", err)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []map[string]string{
+		{"retry-on-status": "501"},
+		{"retry-on-status": "502,http_503"},
+		{"retry-per-try-timeout": "0"},
+		{"retry-budget": "0"},
+		{"retry-budget": "101"},
+		{"retry-on-status": "502", "proxy-next-upstream": "off"},
+	}
+
+	for _, annotations := range tests {
+		_, err := NewParser(&resolver.Mock{}).Parse(buildIngress(annotations))
+		if err == nil {
+			t.Errorf("expected error parsing %v", annotations)
+		}
+	}
+}
@@ -0,0 +1,298 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package routing
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	extensions "k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	ing_errors "k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+const (
+	// MatchExact matches a header with the same value
+	MatchExact = "exact"
+	// MatchPrefix matches a header starting with the value
+	MatchPrefix = "prefix"
+)
+
+var (
+	methodRegex      = regexp.MustCompile(`^[A-Z]+$`)
+	headerNameRegex  = regexp.MustCompile("^[A-Za-z0-9!#$%&'*+.^_`|~-]+$")
+	headerValueRegex = regexp.MustCompile(`^[\x20-\x7E]*$`)
+	serviceRegex     = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+	// the gRPC service is the full name, including the package
+	grpcServiceRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)*$`)
+	grpcMethodRegex  = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// HeaderMatch is a condition on the value of a request header
+type HeaderMatch struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	// Type is exact or prefix
+	Type string `json:"type"`
+}
+
+// Route sends the requests matching all of its conditions to a service
+// different than the one defined in the Ingress rule
+type Route struct {
+	// Method of the request. Empty matches any method.
+	Method  string        `json:"method,omitempty"`
+	Headers []HeaderMatch `json:"headers,omitempty"`
+	// GRPCService and GRPCMethod are matched against the path of the gRPC
+	// requests, /<service>/<method>. Empty matches any service or method.
+	GRPCService string `json:"grpcService,omitempty"`
+	GRPCMethod  string `json:"grpcMethod,omitempty"`
+
+	ServiceName string             `json:"serviceName"`
+	ServicePort intstr.IntOrString `json:"servicePort"`
+	// Backend is the name of the upstream of the service, set by the
+	// controller when the route is applied to a location
+	Backend string `json:"backend,omitempty"`
+}
+
+// Equal tests for equality between two Route types
+func (r1 *Route) Equal(r2 *Route) bool {
+	if r1 == r2 {
+		return true
+	}
+	if r1 == nil || r2 == nil {
+		return false
+	}
+	if r1.Method != r2.Method {
+		return false
+	}
+	if len(r1.Headers) != len(r2.Headers) {
+		return false
+	}
+	for i := range r1.Headers {
+		if r1.Headers[i] != r2.Headers[i] {
+			return false
+		}
+	}
+	if r1.GRPCService != r2.GRPCService {
+		return false
+	}
+	if r1.GRPCMethod != r2.GRPCMethod {
+		return false
+	}
+	if r1.ServiceName != r2.ServiceName {
+		return false
+	}
+	if r1.ServicePort != r2.ServicePort {
+		return false
+	}
+	if r1.Backend != r2.Backend {
+		return false
+	}
+
+	return true
+}
+
+// Config contains the method restrictions and the routes of a location
+type Config struct {
+	// AllowedMethods are the only methods accepted by the location.
+	// Empty allows any method.
+	AllowedMethods []string `json:"allowedMethods,omitempty"`
+	// Routes are evaluated in order and the first one matching the
+	// request selects the backend. If no route matches the request is
+	// sent to the backend of the location.
+	Routes []Route `json:"routes,omitempty"`
+}
+
+// IsEnabled returns true if the location restricts the methods or has routes
+func (c1 *Config) IsEnabled() bool {
+	return len(c1.AllowedMethods) > 0 || len(c1.Routes) > 0
+}
+
+// Equal tests for equality between two Config types
+func (c1 *Config) Equal(c2 *Config) bool {
+	if c1 == c2 {
+		return true
+	}
+	if c1 == nil || c2 == nil {
+		return false
+	}
+	if len(c1.AllowedMethods) != len(c2.AllowedMethods) {
+		return false
+	}
+	for i := range c1.AllowedMethods {
+		if c1.AllowedMethods[i] != c2.AllowedMethods[i] {
+			return false
+		}
+	}
+	if len(c1.Routes) != len(c2.Routes) {
+		return false
+	}
+	for i := range c1.Routes {
+		if !(&c1.Routes[i]).Equal(&c2.Routes[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+type routing struct {
+	r resolver.Resolver
+}
+
+// NewParser creates a new routing annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return routing{r}
+}
+
+// Parse parses the annotations contained in the ingress to restrict the
+// methods and route requests by method, headers and gRPC method.
+//
+// The match-routes annotation contains one route per line, with the format
+// "<condition> [&& <condition>...] => <service>:<port>", where a condition
+// is "method == <METHOD>", "header <Name> == <value>",
+// "header <Name> ^= <prefix>", "grpc-service == <package.Service>" or
+// "grpc-method == <Method>".
+func (a routing) Parse(ing *extensions.Ingress) (interface{}, error) {
+	config := &Config{}
+
+	methods, _ := parser.GetStringAnnotation("allowed-methods", ing)
+	for _, method := range strings.Split(methods, ",") {
+		method = strings.ToUpper(strings.TrimSpace(method))
+		if method == "" {
+			continue
+		}
+
+		if !methodRegex.MatchString(method) {
+			return nil, ing_errors.NewInvalidAnnotationConfiguration("allowed-methods",
+				fmt.Sprintf("invalid method %q", method))
+		}
+
+		config.AllowedMethods = append(config.AllowedMethods, method)
+	}
+
+	routes, _ := parser.GetStringAnnotation("match-routes", ing)
+	for _, line := range strings.Split(routes, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		route, err := parseRoute(line)
+		if err != nil {
+			return nil, ing_errors.NewInvalidAnnotationConfiguration("match-routes", err.Error())
+		}
+
+		config.Routes = append(config.Routes, *route)
+	}
+
+	if !config.IsEnabled() {
+		return nil, ing_errors.ErrMissingAnnotations
+	}
+
+	return config, nil
+}
+
+func parseRoute(line string) (*Route, error) {
+	parts := strings.Split(line, "=>")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("route %q must have the format \"<conditions> => <service>:<port>\"", line)
+	}
+
+	backend := strings.Split(strings.TrimSpace(parts[1]), ":")
+	if len(backend) != 2 || !serviceRegex.MatchString(backend[0]) || backend[1] == "" {
+		return nil, fmt.Errorf("invalid service %q in route %q", strings.TrimSpace(parts[1]), line)
+	}
+
+	route := &Route{
+		ServiceName: backend[0],
+		ServicePort: intstr.Parse(backend[1]),
+	}
+
+	for _, condition := range strings.Split(parts[0], "&&") {
+		err := parseCondition(strings.TrimSpace(condition), route)
+		if err != nil {
+			return nil, fmt.Errorf("%v in route %q", err, line)
+		}
+	}
+
+	return route, nil
+}
+
+func parseCondition(condition string, route *Route) error {
+	matchType := MatchExact
+	operands := strings.SplitN(condition, "==", 2)
+	if len(operands) != 2 {
+		matchType = MatchPrefix
+		operands = strings.SplitN(condition, "^=", 2)
+	}
+	if len(operands) != 2 {
+		return fmt.Errorf("invalid condition %q", condition)
+	}
+
+	subject := strings.Fields(operands[0])
+	value := strings.TrimSpace(operands[1])
+
+	switch {
+	case len(subject) == 1 && subject[0] == "method":
+		method := strings.ToUpper(value)
+		if matchType != MatchExact || !methodRegex.MatchString(method) {
+			return fmt.Errorf("invalid method condition %q", condition)
+		}
+		if route.Method != "" {
+			return fmt.Errorf("more than one method condition")
+		}
+
+		route.Method = method
+	case len(subject) == 2 && subject[0] == "header":
+		if !headerNameRegex.MatchString(subject[1]) || !headerValueRegex.MatchString(value) {
+			return fmt.Errorf("invalid header condition %q", condition)
+		}
+
+		route.Headers = append(route.Headers, HeaderMatch{
+			Name:  subject[1],
+			Value: value,
+			Type:  matchType,
+		})
+	case len(subject) == 1 && subject[0] == "grpc-service":
+		if matchType != MatchExact || !grpcServiceRegex.MatchString(value) {
+			return fmt.Errorf("invalid gRPC service condition %q", condition)
+		}
+		if route.GRPCService != "" {
+			return fmt.Errorf("more than one gRPC service condition")
+		}
+
+		route.GRPCService = value
+	case len(subject) == 1 && subject[0] == "grpc-method":
+		if matchType != MatchExact || !grpcMethodRegex.MatchString(value) {
+			return fmt.Errorf("invalid gRPC method condition %q", condition)
+		}
+		if route.GRPCMethod != "" {
+			return fmt.Errorf("more than one gRPC method condition")
+		}
+
+		route.GRPCMethod = value
+	default:
+		return fmt.Errorf("invalid condition %q", condition)
+	}
+
+	return nil
+}
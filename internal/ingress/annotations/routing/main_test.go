@@ -0,0 +1,127 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package routing
+
+import (
+	"reflect"
+	"testing"
+
+	api "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+func buildIngress(annotations map[string]string) *extensions.Ingress {
+	data := map[string]string{}
+	for k, v := range annotations {
+		data[parser.GetAnnotationWithPrefix(k)] = v
+	}
+
+	return &extensions.Ingress{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:        "foo",
+			Namespace:   api.NamespaceDefault,
+			Annotations: data,
+		},
+		Spec: extensions.IngressSpec{},
+	}
+}
+
+func TestParse(t *testing.T) {
+	ing := buildIngress(map[string]string{
+		"allowed-methods": "get, POST",
+		"match-routes": `
+			header X-Api-Version == 2 => api-v2:80
+			header Content-Type ^= application/grpc => grpc:grpc
+			method == POST && header X-Tenant == acme => writer:8080
+			grpc-service == helloworld.Greeter && grpc-method == SayHello => greeter-v2:grpc
+		`,
+	})
+
+	i, err := NewParser(&resolver.Mock{}).Parse(ing)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := &Config{
+		AllowedMethods: []string{"GET", "POST"},
+		Routes: []Route{
+			{
+				Headers:     []HeaderMatch{{Name: "X-Api-Version", Value: "2", Type: MatchExact}},
+				ServiceName: "api-v2",
+				ServicePort: intstr.FromInt(80),
+			},
+			{
+				Headers:     []HeaderMatch{{Name: "Content-Type", Value: "application/grpc", Type: MatchPrefix}},
+				ServiceName: "grpc",
+				ServicePort: intstr.FromString("grpc"),
+			},
+			{
+				Method:      "POST",
+				Headers:     []HeaderMatch{{Name: "X-Tenant", Value: "acme", Type: MatchExact}},
+				ServiceName: "writer",
+				ServicePort: intstr.FromInt(8080),
+			},
+			{
+				GRPCService: "helloworld.Greeter",
+				GRPCMethod:  "SayHello",
+				ServiceName: "greeter-v2",
+				ServicePort: intstr.FromString("grpc"),
+			},
+		},
+	}
+
+	if !reflect.DeepEqual(i, expected) {
+		t.Errorf("expected %+v but got %+v", expected, i)
+	}
+}
+
+func TestParseWithoutAnnotations(t *testing.T) {
+	_, err := NewParser(&resolver.Mock{}).Parse(buildIngress(nil))
+	if !errors.IsMissingAnnotations(err) {
+		t.Errorf("expected missing annotations error but got %v", err)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []map[string]string{
+		{"allowed-methods": "GET, P0ST"},
+		{"match-routes": "header X-Api-Version == 2"},
+		{"match-routes": "header X-Api-Version == 2 => api-v2"},
+		{"match-routes": "header X-Api-Version == 2 => Api_V2:80"},
+		{"match-routes": "header X Api == 2 => api-v2:80"},
+		{"match-routes": "method ^= PO => api-v2:80"},
+		{"match-routes": "method == GET && method == POST => api-v2:80"},
+		{"match-routes": "cookie session == 2 => api-v2:80"},
+		{"match-routes": "grpc-service ^= helloworld => greeter:80"},
+		{"match-routes": "grpc-service == helloworld..Greeter => greeter:80"},
+		{"match-routes": "grpc-method == Say/Hello => greeter:80"},
+		{"match-routes": "grpc-method == SayHello && grpc-method == SayBye => greeter:80"},
+	}
+
+	for _, annotations := range tests {
+		_, err := NewParser(&resolver.Mock{}).Parse(buildIngress(annotations))
+		if err == nil {
+			t.Errorf("expected error parsing %v", annotations)
+		}
+	}
+}
@@ -122,3 +122,23 @@ func matchHostnames(pattern, host string) bool {
 
 	return true
 }
+
+// isWildcardHost returns true if the host of an Ingress rule is a wildcard
+// domain like "*.example.com"
+func isWildcardHost(host string) bool {
+	return strings.HasPrefix(host, "*.")
+}
+
+// matchServerName returns true if the host matches the name of a server.
+// Like server_name in NGINX, a wildcard name matches any number of labels,
+// i.e. "*.example.com" matches "foo.bar.example.com".
+func matchServerName(name, host string) bool {
+	name = toLowerCaseASCII(name)
+	host = toLowerCaseASCII(host)
+
+	if !isWildcardHost(name) {
+		return name == host
+	}
+
+	return strings.HasSuffix(host, name[1:]) && len(host) > len(name)-1
+}
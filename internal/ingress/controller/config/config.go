@@ -19,6 +19,7 @@ package config
 import (
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	"k8s.io/klog"
@@ -376,6 +377,18 @@ type Configuration struct {
 	// Default: true
 	UseHTTP2 bool `json:"use-http2,omitempty"`
 
+	// Sets how long, in seconds, the clients remember that the HTTPS servers
+	// are also available over HTTP/3 (QUIC) when the HTTP/3 listener is enabled
+	// https://tools.ietf.org/html/rfc7838#section-3.1
+	// Default: 86400
+	HTTP3AltSvcMaxAge int `json:"http3-alt-svc-max-age,omitempty"`
+
+	// Sets the UDP port advertised in the Alt-Svc header, the port where the
+	// clients reach the HTTP/3 listener when it is exposed on a different port
+	// than --http3-port, like a Service port
+	// Default: 0, the value of --http3-port
+	HTTP3AltSvcPort int `json:"http3-alt-svc-port,omitempty"`
+
 	// gzip Compression Level that will be used
 	GzipLevel int `json:"gzip-level,omitempty"`
 
@@ -583,6 +596,24 @@ type Configuration struct {
 
 	// Block all requests with given Referer headers
 	BlockReferers []string `json:"block-referers"`
+
+	// ProxyCacheZones defines the cache zones available to the
+	// proxy-cache-zone annotation
+	// http://nginx.org/en/docs/http/ngx_http_proxy_module.html#proxy_cache_path
+	ProxyCacheZones []ProxyCacheZone `json:"proxy-cache-zones"`
+}
+
+// ProxyCacheZone defines a cache zone used to store the responses of the
+// locations with the proxy-cache-zone annotation
+type ProxyCacheZone struct {
+	// Name of the zone
+	Name string `json:"name"`
+	// KeysZoneSize is the size of the shared memory zone storing the keys
+	KeysZoneSize string `json:"keysZoneSize"`
+	// MaxSize is the maximum size of the cache on disk
+	MaxSize string `json:"maxSize,omitempty"`
+	// Inactive is the time after which the data not accessed is removed
+	Inactive string `json:"inactive,omitempty"`
 }
 
 // NewDefault returns the default nginx configuration
@@ -670,6 +701,7 @@ func NewDefault() Configuration {
 		VariablesHashBucketSize:          128,
 		VariablesHashMaxSize:             2048,
 		UseHTTP2:                         true,
+		HTTP3AltSvcMaxAge:                86400,
 		ProxyStreamTimeout:               "600s",
 		Backend: defaults.Backend{
 			ProxyBodySize:          bodySize,
@@ -686,6 +718,7 @@ func NewDefault() Configuration {
 			ProxyRedirectFrom:      "off",
 			ProxyRedirectTo:        "off",
 			SSLRedirect:            true,
+			HTTP3AltSvc:            true,
 			CustomHTTPErrors:       []int{},
 			WhitelistSourceRange:   []string{},
 			SkipAccessLogURLs:      []string{},
@@ -755,11 +788,14 @@ type TemplateConfig struct {
 	PublishService             *apiv1.Service
 	DynamicCertificatesEnabled bool
 	EnableMetrics              bool
-
-	PID          string
-	StatusSocket string
-	StatusPath   string
-	StreamSocket string
+	EnableZoneMetrics          bool
+
+	PID            string
+	StatusSocket   string
+	StatusPath     string
+	ZoneStatusPath string
+	StreamSocket   string
+	ProxyCachePath string
 }
 
 // ListenPorts describe the ports required to run the
@@ -770,4 +806,60 @@ type ListenPorts struct {
 	Health   int
 	Default  int
 	SSLProxy int
+
+	// HTTP3 is the UDP port where NGINX listens for HTTP/3 (QUIC). Zero when
+	// HTTP/3 is disabled
+	HTTP3 int
+
+	// DynamicStream is the range of ports where NGINX listens for the TCP and
+	// UDP services configured without a reload
+	DynamicStream PortRange
+}
+
+// PortRange is a range of ports, including the first and the last port.
+// The zero value is an empty range
+type PortRange struct {
+	First int
+	Last  int
+}
+
+// ParsePortRange parses a range of ports in the form "first-last"
+func ParsePortRange(s string) (PortRange, error) {
+	parts := strings.Split(s, "-")
+	if len(parts) != 2 {
+		return PortRange{}, fmt.Errorf("invalid port range %q: expected the form first-last", s)
+	}
+
+	first, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return PortRange{}, fmt.Errorf("invalid first port in port range %q", s)
+	}
+	last, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err != nil {
+		return PortRange{}, fmt.Errorf("invalid last port in port range %q", s)
+	}
+
+	if first < 1 || last > 65535 || first > last {
+		return PortRange{}, fmt.Errorf("invalid port range %q: the ports must be between 1 and 65535 and the first port cannot be greater than the last one", s)
+	}
+
+	return PortRange{First: first, Last: last}, nil
+}
+
+// Contains returns true if the port is in the range
+func (r PortRange) Contains(port int) bool {
+	return r.First > 0 && port >= r.First && port <= r.Last
+}
+
+// Ports returns the ports of the range in ascending order
+func (r PortRange) Ports() []int {
+	if r.First == 0 {
+		return nil
+	}
+
+	ports := make([]int, 0, r.Last-r.First+1)
+	for port := r.First; port <= r.Last; port++ {
+		ports = append(ports, port)
+	}
+	return ports
 }
@@ -18,6 +18,7 @@ package config
 
 import (
 	"fmt"
+	"reflect"
 	"testing"
 )
 
@@ -44,3 +45,49 @@ func TestBuildLogFormatUpstream(t *testing.T) {
 		}
 	}
 }
+
+func TestParsePortRange(t *testing.T) {
+	testCases := []struct {
+		value    string
+		expected PortRange
+		err      bool
+	}{
+		{"10000-10002", PortRange{First: 10000, Last: 10002}, false},
+		{"10000-10000", PortRange{First: 10000, Last: 10000}, false},
+		{" 5000 - 5010 ", PortRange{First: 5000, Last: 5010}, false},
+		{"10000", PortRange{}, true},
+		{"10002-10000", PortRange{}, true},
+		{"0-10", PortRange{}, true},
+		{"65000-70000", PortRange{}, true},
+		{"a-b", PortRange{}, true},
+	}
+
+	for _, tc := range testCases {
+		r, err := ParsePortRange(tc.value)
+		if tc.err != (err != nil) {
+			t.Errorf("parsing %q: expected error %v but got %v", tc.value, tc.err, err)
+		}
+		if r != tc.expected {
+			t.Errorf("parsing %q: expected %v but got %v", tc.value, tc.expected, r)
+		}
+	}
+}
+
+func TestPortRange(t *testing.T) {
+	r := PortRange{First: 10000, Last: 10002}
+
+	for port, expected := range map[int]bool{9999: false, 10000: true, 10002: true, 10003: false} {
+		if r.Contains(port) != expected {
+			t.Errorf("expected Contains(%v) to be %v", port, expected)
+		}
+	}
+
+	if ports := r.Ports(); !reflect.DeepEqual(ports, []int{10000, 10001, 10002}) {
+		t.Errorf("unexpected ports %v", ports)
+	}
+
+	var empty PortRange
+	if empty.Contains(0) || empty.Ports() != nil {
+		t.Errorf("expected the zero value to be an empty range")
+	}
+}
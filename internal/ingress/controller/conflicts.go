@@ -0,0 +1,382 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"sort"
+
+	apiv1 "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog"
+
+	"k8s.io/ingress-nginx/internal/ingress"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/pathtype"
+	"k8s.io/ingress-nginx/internal/k8s"
+)
+
+// policies applied to the Ingresses defining the same configuration of a host
+const (
+	// ConflictPolicyOldestWins keeps the configuration of the oldest Ingress
+	// and ignores the conflicting parts of the newer ones
+	ConflictPolicyOldestWins = "oldest-wins"
+	// ConflictPolicyRejectNewer ignores the newer Ingresses with a conflict
+	ConflictPolicyRejectNewer = "reject-newer"
+	// ConflictPolicyNamespaceOwnsHost ignores the Ingresses defining a host
+	// already defined by an older Ingress of a different namespace. The
+	// conflicts between Ingresses of the same namespace are handled as with
+	// oldest-wins.
+	ConflictPolicyNamespaceOwnsHost = "namespace-owns-host"
+)
+
+// types of conflicts between Ingresses
+const (
+	conflictPath          = "path"
+	conflictServerSnippet = "server_snippet"
+	conflictAlias         = "alias"
+	conflictHost          = "host"
+	conflictHostOwnership = "host_ownership"
+)
+
+// IsValidConflictPolicy returns true if the policy is a known conflict policy
+func IsValidConflictPolicy(policy string) bool {
+	switch policy {
+	case ConflictPolicyOldestWins, ConflictPolicyRejectNewer, ConflictPolicyNamespaceOwnsHost:
+		return true
+	}
+
+	return false
+}
+
+// ingressConflict is a part of the configuration of an Ingress ignored
+// because it is already defined by another one
+type ingressConflict struct {
+	// Ingress is the Ingress losing the conflict
+	Ingress *ingress.Ingress
+	// Owner is the Ingress whose configuration is used. Empty in host
+	// ownership conflicts.
+	Owner *ingress.Ingress
+
+	Type string
+	Host string
+	// Path contains the path and path type of a path conflict
+	Path string
+	// Pattern is the entry of the host ownership policy matching the host
+	Pattern string
+
+	// Rejected is true when the whole Ingress is ignored
+	Rejected bool
+}
+
+// key identifies a conflict across synchronizations
+func (c ingressConflict) key() string {
+	return fmt.Sprintf("%v|%v|%v|%v|%v|%v|%v",
+		k8s.MetaNamespaceKey(c.Ingress), c.owner(), c.Type, c.Host, c.Path, c.Pattern, c.Rejected)
+}
+
+func (c ingressConflict) owner() string {
+	if c.Owner == nil {
+		return ""
+	}
+
+	return k8s.MetaNamespaceKey(c.Owner)
+}
+
+func (c ingressConflict) message() string {
+	owner := c.owner()
+
+	var msg string
+	switch c.Type {
+	case conflictPath:
+		msg = fmt.Sprintf("Path %v of host %q is already defined by Ingress %v", c.Path, c.Host, owner)
+	case conflictServerSnippet:
+		msg = fmt.Sprintf("Server snippet of host %q is already defined by Ingress %v", c.Host, owner)
+	case conflictAlias:
+		msg = fmt.Sprintf("Alias of host %q is already defined by Ingress %v", c.Host, owner)
+	case conflictHost:
+		msg = fmt.Sprintf("Host %q belongs to namespace %v (Ingress %v)", c.Host, c.Owner.Namespace, owner)
+	case conflictHostOwnership:
+		if c.Pattern == anyHost {
+			msg = fmt.Sprintf("Host %q cannot be checked because the host ownership policy is not available", c.Host)
+			break
+		}
+		msg = fmt.Sprintf("Namespace %v is not allowed to use host %q (%v) by the host ownership policy", c.Ingress.Namespace, c.Host, c.Pattern)
+	}
+
+	if c.Rejected {
+		return msg + ". The Ingress is ignored."
+	}
+
+	return msg + ". The configuration is ignored."
+}
+
+// hostClaims contains the Ingresses defining the configuration of a host
+type hostClaims struct {
+	owner         *ingress.Ingress
+	paths         map[string]*ingress.Ingress
+	serverSnippet *ingress.Ingress
+	alias         *ingress.Ingress
+}
+
+// detectConflicts finds the Ingresses defining the same paths, server
+// snippet or alias of a host, from the oldest to the newest Ingress, and
+// applies the conflict policy. It returns the Ingresses to configure,
+// ordered by creation timestamp and name, and the conflicts found.
+func detectConflicts(ingresses []*ingress.Ingress, policy string) ([]*ingress.Ingress, []ingressConflict) {
+	sorted := make([]*ingress.Ingress, len(ingresses))
+	copy(sorted, ingresses)
+
+	// Ingresses created in the same second are ordered by name, so the
+	// winner of a conflict does not depend on the order of the store
+	sort.SliceStable(sorted, func(i, j int) bool {
+		ti, tj := sorted[i].CreationTimestamp, sorted[j].CreationTimestamp
+		if !ti.Equal(&tj) {
+			return ti.Before(&tj)
+		}
+
+		return k8s.MetaNamespaceKey(sorted[i]) < k8s.MetaNamespaceKey(sorted[j])
+	})
+
+	hosts := make(map[string]*hostClaims)
+	claimsOf := func(host string) *hostClaims {
+		claims, ok := hosts[host]
+		if !ok {
+			claims = &hostClaims{paths: make(map[string]*ingress.Ingress)}
+			hosts[host] = claims
+		}
+		return claims
+	}
+
+	var accepted []*ingress.Ingress
+	var conflicts []ingressConflict
+
+	for _, ing := range sorted {
+		ingConflicts := ingressConflicts(ing, hosts, policy)
+
+		rejected := false
+		for _, c := range ingConflicts {
+			if policy == ConflictPolicyRejectNewer || c.Type == conflictHost {
+				rejected = true
+			}
+		}
+
+		for _, c := range ingConflicts {
+			c.Rejected = rejected
+			conflicts = append(conflicts, c)
+		}
+
+		if rejected {
+			continue
+		}
+
+		accepted = append(accepted, ing)
+
+		// the first Ingress defining a configuration keeps it
+		anns := ing.ParsedAnnotations
+		for _, rule := range ing.Spec.Rules {
+			host := ingressHost(rule.Host)
+			claims := claimsOf(host)
+
+			if claims.owner == nil {
+				claims.owner = ing
+			}
+
+			if anns.Canary.Enabled {
+				continue
+			}
+
+			if anns.ServerSnippet != "" && claims.serverSnippet == nil {
+				claims.serverSnippet = ing
+			}
+
+			if anns.Alias != "" && claims.alias == nil {
+				claims.alias = ing
+			}
+
+			for _, path := range ingressPaths(ing, rule.HTTP) {
+				if _, ok := claims.paths[path]; !ok {
+					claims.paths[path] = ing
+				}
+			}
+		}
+	}
+
+	return accepted, conflicts
+}
+
+// ingressConflicts returns the configuration of an Ingress already defined
+// by older Ingresses
+func ingressConflicts(ing *ingress.Ingress, hosts map[string]*hostClaims, policy string) []ingressConflict {
+	var conflicts []ingressConflict
+
+	anns := ing.ParsedAnnotations
+	isOther := func(owner *ingress.Ingress) bool {
+		return owner != nil && k8s.MetaNamespaceKey(owner) != k8s.MetaNamespaceKey(ing)
+	}
+
+	for _, rule := range ing.Spec.Rules {
+		host := ingressHost(rule.Host)
+
+		claims, ok := hosts[host]
+		if !ok {
+			continue
+		}
+
+		// the catch-all server does not belong to any namespace
+		if policy == ConflictPolicyNamespaceOwnsHost && host != defServerName &&
+			claims.owner != nil && claims.owner.Namespace != ing.Namespace {
+			conflicts = append(conflicts, ingressConflict{
+				Ingress: ing,
+				Owner:   claims.owner,
+				Type:    conflictHost,
+				Host:    host,
+			})
+			continue
+		}
+
+		// canary Ingresses are merged into the paths of other Ingresses
+		if anns.Canary.Enabled {
+			continue
+		}
+
+		if anns.ServerSnippet != "" && isOther(claims.serverSnippet) {
+			conflicts = append(conflicts, ingressConflict{
+				Ingress: ing,
+				Owner:   claims.serverSnippet,
+				Type:    conflictServerSnippet,
+				Host:    host,
+			})
+		}
+
+		if anns.Alias != "" && isOther(claims.alias) {
+			conflicts = append(conflicts, ingressConflict{
+				Ingress: ing,
+				Owner:   claims.alias,
+				Type:    conflictAlias,
+				Host:    host,
+			})
+		}
+
+		for _, path := range ingressPaths(ing, rule.HTTP) {
+			if owner := claims.paths[path]; isOther(owner) {
+				conflicts = append(conflicts, ingressConflict{
+					Ingress: ing,
+					Owner:   owner,
+					Type:    conflictPath,
+					Host:    host,
+					Path:    path,
+				})
+			}
+		}
+	}
+
+	return conflicts
+}
+
+func ingressHost(host string) string {
+	if host == "" {
+		return defServerName
+	}
+
+	return host
+}
+
+// ingressPaths returns the paths of a rule with the format "<path> (<type>)",
+// which is also used to report the conflicts
+func ingressPaths(ing *ingress.Ingress, http *extensions.HTTPIngressRuleValue) []string {
+	if http == nil {
+		return nil
+	}
+
+	var paths []string
+	for _, path := range http.Paths {
+		nginxPath := rootLocation
+		if path.Path != "" {
+			nginxPath = path.Path
+		}
+
+		pathType := ing.ParsedAnnotations.PathType.Type(nginxPath)
+		paths = append(paths, fmt.Sprintf("%q (%v)", pathtype.NormalizePath(nginxPath, pathType), pathType))
+	}
+
+	return paths
+}
+
+// resolveConflicts applies the host ownership and conflict policies to the
+// Ingresses, reporting the new conflicts with events, and returns the
+// Ingresses to configure
+func (n *NGINXController) resolveConflicts(ingresses []*ingress.Ingress) []*ingress.Ingress {
+	policy := n.cfg.ConflictPolicy
+	if policy == "" {
+		policy = ConflictPolicyOldestWins
+	}
+
+	allowed, conflicts := checkHostOwnership(ingresses, n.getHostOwnership())
+
+	accepted, policyConflicts := detectConflicts(allowed, policy)
+	conflicts = append(conflicts, policyConflicts...)
+
+	active := make(map[string]ingressConflict, len(conflicts))
+	rejected := sets.NewString()
+	counts := make(map[string]int)
+
+	n.conflictsLock.Lock()
+	defer n.conflictsLock.Unlock()
+
+	for _, c := range conflicts {
+		key := c.key()
+		active[key] = c
+		counts[c.Type]++
+
+		if c.Rejected {
+			rejected.Insert(k8s.MetaNamespaceKey(c.Ingress))
+		}
+
+		if _, ok := n.activeConflicts[key]; ok {
+			continue
+		}
+
+		klog.Warningf("Ingress %v: %v", k8s.MetaNamespaceKey(c.Ingress), c.message())
+
+		if n.recorder == nil {
+			continue
+		}
+
+		reason := "IngressConflict"
+		if c.Rejected {
+			reason = "IngressRejected"
+		}
+
+		n.recorder.Event(&c.Ingress.Ingress, apiv1.EventTypeWarning, reason, c.message())
+	}
+
+	n.activeConflicts = active
+	n.rejectedIngresses = rejected
+	n.metricCollector.SetIngressConflicts(counts)
+
+	return accepted
+}
+
+// IsRejected returns true if the Ingress is ignored because of the conflict
+// policy. The load-balancer status of rejected Ingresses is removed.
+func (n *NGINXController) IsRejected(ing *ingress.Ingress) bool {
+	n.conflictsLock.RLock()
+	defer n.conflictsLock.RUnlock()
+
+	return n.rejectedIngresses.Has(k8s.MetaNamespaceKey(ing))
+}
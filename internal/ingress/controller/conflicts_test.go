@@ -0,0 +1,229 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	extensions "k8s.io/api/extensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/record"
+
+	"k8s.io/ingress-nginx/internal/ingress"
+	"k8s.io/ingress-nginx/internal/ingress/annotations"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/canary"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/pathtype"
+	"k8s.io/ingress-nginx/internal/ingress/metric"
+)
+
+func newConflictIngress(namespace, name string, created int64, host string, anns *annotations.Ingress, paths ...string) *ingress.Ingress {
+	var httpPaths []extensions.HTTPIngressPath
+	for _, path := range paths {
+		httpPaths = append(httpPaths, extensions.HTTPIngressPath{
+			Path: path,
+			Backend: extensions.IngressBackend{
+				ServiceName: name,
+				ServicePort: intstr.FromInt(80),
+			},
+		})
+	}
+
+	if anns == nil {
+		anns = &annotations.Ingress{}
+	}
+
+	return &ingress.Ingress{
+		Ingress: extensions.Ingress{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				Namespace:         namespace,
+				CreationTimestamp: metav1.NewTime(time.Unix(created, 0)),
+			},
+			Spec: extensions.IngressSpec{
+				Rules: []extensions.IngressRule{
+					{
+						Host: host,
+						IngressRuleValue: extensions.IngressRuleValue{
+							HTTP: &extensions.HTTPIngressRuleValue{
+								Paths: httpPaths,
+							},
+						},
+					},
+				},
+			},
+		},
+		ParsedAnnotations: anns,
+	}
+}
+
+func ingressNames(ingresses []*ingress.Ingress) string {
+	var names []string
+	for _, ing := range ingresses {
+		names = append(names, ing.Name)
+	}
+
+	return strings.Join(names, ",")
+}
+
+func TestDetectConflicts(t *testing.T) {
+	testCases := []struct {
+		name      string
+		policy    string
+		ingresses []*ingress.Ingress
+		accepted  string
+		conflicts []string
+	}{
+		{
+			name:   "ingresses without conflicts",
+			policy: ConflictPolicyOldestWins,
+			ingresses: []*ingress.Ingress{
+				newConflictIngress("a", "second", 2, "example.com", nil, "/bar"),
+				newConflictIngress("a", "first", 1, "example.com", nil, "/foo"),
+			},
+			accepted: "first,second",
+		},
+		{
+			name:   "oldest wins keeps the newer Ingress",
+			policy: ConflictPolicyOldestWins,
+			ingresses: []*ingress.Ingress{
+				newConflictIngress("b", "newer", 2, "example.com", nil, "/foo", "/bar"),
+				newConflictIngress("a", "older", 1, "example.com", nil, "/foo"),
+			},
+			accepted:  "older,newer",
+			conflicts: []string{`b/newer path example.com "/foo" (ImplementationSpecific) a/older false`},
+		},
+		{
+			name:   "Ingresses created at the same time are ordered by name",
+			policy: ConflictPolicyOldestWins,
+			ingresses: []*ingress.Ingress{
+				newConflictIngress("a", "second", 1, "example.com", nil, "/foo"),
+				newConflictIngress("a", "first", 1, "example.com", nil, "/foo"),
+			},
+			accepted:  "first,second",
+			conflicts: []string{`a/second path example.com "/foo" (ImplementationSpecific) a/first false`},
+		},
+		{
+			name:   "paths with different path types do not conflict",
+			policy: ConflictPolicyRejectNewer,
+			ingresses: []*ingress.Ingress{
+				newConflictIngress("a", "first", 1, "example.com", nil, "/foo"),
+				newConflictIngress("a", "second", 2, "example.com", &annotations.Ingress{PathType: pathtype.Config{Default: pathtype.Exact}}, "/foo"),
+			},
+			accepted: "first,second",
+		},
+		{
+			name:   "reject newer ignores the newer Ingress",
+			policy: ConflictPolicyRejectNewer,
+			ingresses: []*ingress.Ingress{
+				newConflictIngress("a", "first", 1, "example.com", &annotations.Ingress{ServerSnippet: "a"}, "/foo"),
+				newConflictIngress("a", "second", 2, "example.com", &annotations.Ingress{ServerSnippet: "b"}, "/bar"),
+				newConflictIngress("a", "third", 3, "example.com", nil, "/bar"),
+			},
+			accepted:  "first,third",
+			conflicts: []string{`a/second server_snippet example.com  a/first true`},
+		},
+		{
+			name:   "canary Ingresses do not conflict",
+			policy: ConflictPolicyRejectNewer,
+			ingresses: []*ingress.Ingress{
+				newConflictIngress("a", "first", 1, "example.com", nil, "/foo"),
+				newConflictIngress("a", "canary", 2, "example.com", &annotations.Ingress{Canary: canary.Config{Enabled: true}}, "/foo"),
+			},
+			accepted: "first,canary",
+		},
+		{
+			name:   "namespace owns host",
+			policy: ConflictPolicyNamespaceOwnsHost,
+			ingresses: []*ingress.Ingress{
+				newConflictIngress("a", "first", 1, "example.com", nil, "/foo"),
+				newConflictIngress("b", "second", 2, "example.com", nil, "/bar"),
+				newConflictIngress("a", "third", 3, "example.com", &annotations.Ingress{Alias: "www.example.com"}, "/foo"),
+				newConflictIngress("b", "catch-all", 4, "", nil, "/"),
+			},
+			accepted: "first,third,catch-all",
+			conflicts: []string{
+				`b/second host example.com  a/first true`,
+				`a/third path example.com "/foo" (ImplementationSpecific) a/first false`,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			accepted, conflicts := detectConflicts(tc.ingresses, tc.policy)
+
+			if names := ingressNames(accepted); names != tc.accepted {
+				t.Errorf("expected accepted Ingresses %v but got %v", tc.accepted, names)
+			}
+
+			if len(conflicts) != len(tc.conflicts) {
+				t.Fatalf("expected %v conflicts but got %v: %v", len(tc.conflicts), len(conflicts), conflicts)
+			}
+
+			for i, c := range conflicts {
+				actual := strings.Join([]string{c.Ingress.Namespace + "/" + c.Ingress.Name, c.Type, c.Host, c.Path,
+					c.Owner.Namespace + "/" + c.Owner.Name, map[bool]string{true: "true", false: "false"}[c.Rejected]}, " ")
+				if actual != tc.conflicts[i] {
+					t.Errorf("expected conflict %q but got %q", tc.conflicts[i], actual)
+				}
+			}
+		})
+	}
+}
+
+func TestResolveConflicts(t *testing.T) {
+	recorder := record.NewFakeRecorder(10)
+	n := &NGINXController{
+		cfg:             &Configuration{ConflictPolicy: ConflictPolicyRejectNewer},
+		recorder:        recorder,
+		metricCollector: metric.DummyCollector{},
+		conflictsLock:   &sync.RWMutex{},
+	}
+
+	older := newConflictIngress("a", "older", 1, "example.com", nil, "/foo")
+	newer := newConflictIngress("b", "newer", 2, "example.com", nil, "/foo")
+
+	for i := 0; i < 2; i++ {
+		accepted := n.resolveConflicts([]*ingress.Ingress{newer, older})
+		if names := ingressNames(accepted); names != "older" {
+			t.Errorf("expected accepted Ingresses older but got %v", names)
+		}
+	}
+
+	if !n.IsRejected(newer) || n.IsRejected(older) {
+		t.Errorf("expected only the newer Ingress to be rejected")
+	}
+
+	if len(recorder.Events) != 1 {
+		t.Fatalf("expected one event but got %v", len(recorder.Events))
+	}
+
+	event := <-recorder.Events
+	expected := `Warning IngressRejected Path "/foo" (ImplementationSpecific) of host "example.com" is already defined by Ingress a/older. The Ingress is ignored.`
+	if event != expected {
+		t.Errorf("expected event %q but got %q", expected, event)
+	}
+
+	n.resolveConflicts([]*ingress.Ingress{older})
+	if n.IsRejected(newer) {
+		t.Errorf("expected the Ingress to not be rejected once the conflict is solved")
+	}
+}
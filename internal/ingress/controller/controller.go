@@ -36,8 +36,13 @@ import (
 
 	"k8s.io/ingress-nginx/internal/ingress"
 	"k8s.io/ingress-nginx/internal/ingress/annotations"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/ipwhitelist"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/pathtype"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/proxy"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/proxycache"
 	ngx_config "k8s.io/ingress-nginx/internal/ingress/controller/config"
+	"k8s.io/ingress-nginx/internal/ingress/controller/streams"
+	"k8s.io/ingress-nginx/internal/ingress/metric"
 	"k8s.io/ingress-nginx/internal/k8s"
 )
 
@@ -47,6 +52,26 @@ const (
 	rootLocation    = "/"
 )
 
+// phases of the synchronization loop reported in the sync duration metrics
+const (
+	syncPhaseGetBackendServers     = "get_backend_servers"
+	syncPhaseRenderTemplate        = "render_template"
+	syncPhaseTestTemplate          = "test_template"
+	syncPhaseReload                = "reload"
+	syncPhaseConfigureDynamically  = "configure_dynamically"
+	syncPhaseConfigureCertificates = "configure_certificates"
+)
+
+// parts of the configuration that require a reload when they change
+const (
+	reloadReasonInitialSync = "initial_sync"
+	reloadReasonServers     = "servers"
+	reloadReasonTCPServices = "tcp_services"
+	reloadReasonUDPServices = "udp_services"
+	reloadReasonPassthrough = "ssl_passthrough"
+	reloadReasonConfigMap   = "configmap"
+)
+
 // Configuration contains all the settings required by an Ingress controller
 type Configuration struct {
 	APIServerHost  string
@@ -64,6 +89,8 @@ type Configuration struct {
 	TCPConfigMapName string
 	// +optional
 	UDPConfigMapName string
+	// +optional
+	HostOwnershipConfigMapName string
 
 	HealthCheckTimeout    time.Duration
 	DefaultSSLCertificate string
@@ -83,8 +110,12 @@ type Configuration struct {
 
 	EnableProfiling bool
 
-	EnableMetrics  bool
-	MetricsPerHost bool
+	EnableMetrics     bool
+	MetricsPerHost    bool
+	EnableZoneMetrics bool
+
+	// +optional
+	StatsD metric.StatsDConfig
 
 	EnableSSLChainCompletion bool
 
@@ -96,6 +127,14 @@ type Configuration struct {
 	DynamicCertificatesEnabled bool
 
 	DisableCatchAll bool
+
+	// ConflictPolicy defines how the Ingresses defining the same
+	// configuration of a host are handled
+	ConflictPolicy string
+
+	// ReloadHistorySize is the number of configuration diffs that required
+	// a reload kept in memory
+	ReloadHistorySize int
 }
 
 // GetPublishService returns the Service used to set the load-balancer status of Ingresses.
@@ -118,9 +157,12 @@ func (n *NGINXController) syncIngress(interface{}) error {
 		return nil
 	}
 
-	ings := n.store.ListIngresses()
+	ings := n.resolveConflicts(n.store.ListIngresses())
 
+	start := time.Now()
 	upstreams, servers := n.getBackendServers(ings)
+	n.metricCollector.ObserveSyncPhase(syncPhaseGetBackendServers, time.Since(start))
+
 	var passUpstreams []*ingress.SSLPassthroughBackend
 
 	hosts := sets.NewString()
@@ -152,10 +194,15 @@ func (n *NGINXController) syncIngress(interface{}) error {
 		}
 	}
 
+	tcpServices := n.getStreamServices(n.cfg.TCPConfigMapName, apiv1.ProtocolTCP)
+	if n.cfg.EnableSSLPassthrough {
+		tcpServices, passUpstreams = splitPassthroughStreamServices(tcpServices, passUpstreams, hosts, n.cfg.ListenPorts.HTTPS)
+	}
+
 	pcfg := &ingress.Configuration{
 		Backends:              upstreams,
 		Servers:               servers,
-		TCPEndpoints:          n.getStreamServices(n.cfg.TCPConfigMapName, apiv1.ProtocolTCP),
+		TCPEndpoints:          tcpServices,
 		UDPEndpoints:          n.getStreamServices(n.cfg.UDPConfigMapName, apiv1.ProtocolUDP),
 		PassthroughBackends:   passUpstreams,
 		BackendConfigChecksum: n.store.GetBackendConfiguration().Checksum,
@@ -167,8 +214,9 @@ func (n *NGINXController) syncIngress(interface{}) error {
 		return nil
 	}
 
-	if !n.IsDynamicConfigurationEnough(pcfg) {
-		klog.Infof("Configuration changes detected, backend reload required.")
+	reloadReasons := n.getReloadReasons(pcfg)
+	if len(reloadReasons) > 0 {
+		klog.Infof("Configuration changes detected, backend reload required (%v).", strings.Join(reloadReasons, ", "))
 
 		hash, _ := hashstructure.Hash(pcfg, &hashstructure.HashOptions{
 			TagName: "json",
@@ -176,7 +224,16 @@ func (n *NGINXController) syncIngress(interface{}) error {
 
 		pcfg.ConfigurationChecksum = fmt.Sprintf("%v", hash)
 
+		runningCopy, pcfgCopy := n.withoutDynamicParts(pcfg)
+		diff := diffConfigurations(runningCopy, pcfgCopy)
+		diff.Reasons = reloadReasons
+
 		err := n.OnUpdate(*pcfg)
+		if err != nil {
+			diff.Error = err.Error()
+		}
+		n.reloadHistory.Add(diff)
+
 		if err != nil {
 			n.metricCollector.IncReloadErrorCount()
 			n.metricCollector.ConfigSuccess(hash, false)
@@ -189,6 +246,7 @@ func (n *NGINXController) syncIngress(interface{}) error {
 		klog.Infof("Backend successfully reloaded.")
 		n.metricCollector.ConfigSuccess(hash, true)
 		n.metricCollector.IncReloadCount()
+		n.metricCollector.IncConfigUpdateCount(false, reloadReasons)
 
 		if n.isLeader() {
 			klog.V(2).Infof("Updating ssl expiration metrics.")
@@ -212,23 +270,47 @@ func (n *NGINXController) syncIngress(interface{}) error {
 	}
 
 	err := wait.ExponentialBackoff(retry, func() (bool, error) {
-		err := configureDynamically(pcfg, n.cfg.DynamicCertificatesEnabled)
-		if err == nil {
-			klog.V(2).Infof("Dynamic reconfiguration succeeded.")
-			return true, nil
+		start := time.Now()
+		err := configureDynamically(pcfg)
+		n.metricCollector.ObserveSyncPhase(syncPhaseConfigureDynamically, time.Since(start))
+		if err != nil {
+			klog.Warningf("Dynamic reconfiguration failed: %v", err)
+			return false, err
+		}
+
+		if n.cfg.DynamicCertificatesEnabled {
+			start = time.Now()
+			err = configureCertificates(pcfg)
+			n.metricCollector.ObserveSyncPhase(syncPhaseConfigureCertificates, time.Since(start))
+			if err != nil {
+				klog.Warningf("Dynamic reconfiguration failed: %v", err)
+				return false, err
+			}
 		}
 
-		klog.Warningf("Dynamic reconfiguration failed: %v", err)
-		return false, err
+		klog.V(2).Infof("Dynamic reconfiguration succeeded.")
+		return true, nil
 	})
 	if err != nil {
 		klog.Errorf("Unexpected failure reconfiguring NGINX:\n%v", err)
 		return err
 	}
 
+	if n.cfg.EnableMetrics && n.cfg.EnableZoneMetrics {
+		err = configureZoneStatus(pcfg)
+		if err != nil {
+			klog.Warningf("Error removing the zone counters of the removed servers and upstreams: %v", err)
+		}
+	}
+
+	if len(reloadReasons) == 0 {
+		n.metricCollector.IncConfigUpdateCount(true, nil)
+	}
+
 	ri := getRemovedIngresses(n.runningConfig, pcfg)
 	re := getRemovedHosts(n.runningConfig, pcfg)
 	n.metricCollector.RemoveMetrics(ri, re)
+	n.metricCollector.RemoveStreamMetrics(getRemovedStreamServices(n.runningConfig, pcfg))
 
 	n.runningConfig = pcfg
 
@@ -251,7 +333,6 @@ func (n *NGINXController) getStreamServices(configmapName string, proto apiv1.Pr
 		return []ingress.L4Service{}
 	}
 	var svcs []ingress.L4Service
-	var svcProxyProtocol ingress.ProxyProtocol
 	rp := []int{
 		n.cfg.ListenPorts.HTTP,
 		n.cfg.ListenPorts.HTTPS,
@@ -259,41 +340,58 @@ func (n *NGINXController) getStreamServices(configmapName string, proto apiv1.Pr
 		n.cfg.ListenPorts.Health,
 		n.cfg.ListenPorts.Default,
 	}
+	if proto == apiv1.ProtocolUDP && n.cfg.ListenPorts.HTTP3 != 0 {
+		rp = append(rp, n.cfg.ListenPorts.HTTP3)
+	}
 	reserverdPorts := sets.NewInt(rp...)
-	// svcRef format: <(str)namespace>/<(str)service>:<(intstr)port>[:<("PROXY")decode>:<("PROXY")encode>]
-	for port, svcRef := range configmap.Data {
-		externalPort, err := strconv.Atoi(port)
+	// the invalid entries are reported as events in the ConfigMap
+	invalid := sets.NewString()
+	defer n.reportStreamServiceErrors(configmap, proto, invalid)
+	// key format: <(int)port>[.<(str)hostname>]
+	// svcRef format: <(str)namespace>/<(str)service>:<(intstr)port>[:<("PROXY")decode>:<("PROXY")encode>][:tls=<secret>[:tls-client-ca=<secret>]]
+	// or a structured definition in YAML or JSON
+	for key, svcRef := range configmap.Data {
+		externalPort, hostname, err := streams.ParseKey(key, proto)
 		if err != nil {
-			klog.Warningf("%q is not a valid %v port number", port, proto)
+			invalid.Insert(fmt.Sprintf("Invalid %v stream service key %q: %v", proto, key, err))
 			continue
 		}
-		if reserverdPorts.Has(externalPort) {
-			klog.Warningf("Port %d cannot be used for %v stream services. It is reserved for the Ingress controller.", externalPort, proto)
+		// the HTTPS port can be shared with the Ingress rules using the SSL Passthrough proxy
+		sharedHTTPSPort := hostname != "" && n.cfg.EnableSSLPassthrough && externalPort == n.cfg.ListenPorts.HTTPS
+		if reserverdPorts.Has(externalPort) && !sharedHTTPSPort {
+			invalid.Insert(fmt.Sprintf("Port %d cannot be used for %v stream services. It is reserved for the Ingress controller.", externalPort, proto))
 			continue
 		}
-		nsSvcPort := strings.Split(svcRef, ":")
-		if len(nsSvcPort) < 2 {
-			klog.Warningf("Invalid Service reference %q for %v port %d", svcRef, proto, externalPort)
+		ref, err := streams.ParseReference(svcRef, proto)
+		if err != nil {
+			invalid.Insert(fmt.Sprintf("Invalid Service reference %q for %v stream service %q: %v", svcRef, proto, key, err))
 			continue
 		}
-		nsName := nsSvcPort[0]
-		svcPort := nsSvcPort[1]
-		svcProxyProtocol.Decode = false
-		svcProxyProtocol.Encode = false
-		// Proxy Protocol is only compatible with TCP Services
-		if len(nsSvcPort) >= 3 && proto == apiv1.ProtocolTCP {
-			if len(nsSvcPort) >= 3 && strings.ToUpper(nsSvcPort[2]) == "PROXY" {
-				svcProxyProtocol.Decode = true
-			}
-			if len(nsSvcPort) == 4 && strings.ToUpper(nsSvcPort[3]) == "PROXY" {
-				svcProxyProtocol.Encode = true
-			}
+		// the TLS connections routed by hostname are passed through to the Service
+		if hostname != "" && len(ref.Secrets()) > 0 {
+			invalid.Insert(fmt.Sprintf("TLS termination is not supported by the %v stream service %q routed by hostname", proto, key))
+			continue
 		}
-		svcNs, svcName, err := k8s.ParseNameNS(nsName)
-		if err != nil {
-			klog.Warningf("%v", err)
+		// the connections of the HTTPS port are proxied by the SSL Passthrough
+		// proxy and not by an NGINX server
+		if sharedHTTPSPort && ref.HasServerSettings() {
+			invalid.Insert(fmt.Sprintf("The %v stream service %q in the HTTPS port does not support the Proxy Protocol, timeouts, access log and access control settings", proto, key))
 			continue
 		}
+		// the ports of the dynamic range share the same NGINX servers
+		if n.cfg.ListenPorts.DynamicStream.Contains(externalPort) {
+			if hostname != "" {
+				invalid.Insert(fmt.Sprintf("Routing by hostname is not supported by the %v stream service %q in the dynamic port range", proto, key))
+				continue
+			}
+			if ref.HasServerSettings() {
+				invalid.Insert(fmt.Sprintf("The %v stream service %q in the dynamic port range only supports the load balancing settings", proto, key))
+				continue
+			}
+		}
+		nsName := ref.Service
+		svcPort := ref.Port
+		svcNs, svcName, _ := k8s.ParseNameNS(nsName)
 		svc, err := n.store.GetService(nsName)
 		if err != nil {
 			klog.Warningf("Error getting Service %q: %v", nsName, err)
@@ -329,24 +427,159 @@ func (n *NGINXController) getStreamServices(configmapName string, proto apiv1.Pr
 			klog.Warningf("Service %q does not have any active Endpoint for %v port %v", nsName, proto, svcPort)
 			continue
 		}
-		svcs = append(svcs, ingress.L4Service{
-			Port: externalPort,
+		l4Service := ingress.L4Service{
+			Port:     externalPort,
+			Hostname: hostname,
 			Backend: ingress.L4Backend{
 				Name:          svcName,
 				Namespace:     svcNs,
 				Port:          intstr.FromString(svcPort),
 				Protocol:      proto,
-				ProxyProtocol: svcProxyProtocol,
+				ProxyProtocol: ref.ProxyProtocol,
 			},
-			Endpoints: endps,
-			Service:   svc,
-		})
+			Endpoints:           endps,
+			Service:             svc,
+			ProxyTimeout:        ref.ProxyTimeout,
+			ProxyConnectTimeout: ref.ProxyConnectTimeout,
+			DisableAccessLog:    ref.DisableAccessLog,
+			LoadBalancing:       ref.LoadBalancing,
+			UpstreamHashBy:      ref.UpstreamHashBy,
+			AllowedSourceRange:  ipwhitelist.SourceRange{CIDR: ref.AllowedSourceRanges},
+			DeniedSourceRange:   ipwhitelist.SourceRange{CIDR: ref.DeniedSourceRanges},
+			ConnectionLimit:     ref.ConnectionLimit,
+		}
+		// the port is not exposed without TLS when the certificate is not available
+		if ref.TLSSecret != "" {
+			cert, err := n.store.GetLocalSSLCert(ref.TLSSecret)
+			if err != nil {
+				klog.Warningf("Error getting SSL certificate %q for %v port %d: %v", ref.TLSSecret, proto, externalPort, err)
+				continue
+			}
+			l4Service.SSLCert = cert
+		}
+		if ref.ClientCASecret != "" {
+			caCert, err := n.store.GetAuthCertificate(ref.ClientCASecret)
+			if err != nil {
+				klog.Warningf("Error getting client CA certificate %q for %v port %d: %v", ref.ClientCASecret, proto, externalPort, err)
+				continue
+			}
+			l4Service.ClientCACert = *caCert
+		}
+		svcs = append(svcs, l4Service)
 	}
 	// Keep upstream order sorted to reduce unnecessary nginx config reloads.
 	sort.SliceStable(svcs, func(i, j int) bool {
+		if svcs[i].Port == svcs[j].Port {
+			return svcs[i].Hostname < svcs[j].Hostname
+		}
 		return svcs[i].Port < svcs[j].Port
 	})
-	return svcs
+	return filterSNIStreamServices(svcs, proto)
+}
+
+// reportStreamServiceErrors logs the invalid entries of a stream services
+// ConfigMap and reports the new ones as events in the ConfigMap.
+func (n *NGINXController) reportStreamServiceErrors(configmap *apiv1.ConfigMap, proto apiv1.Protocol, invalid sets.String) {
+	if n.streamServiceErrors == nil {
+		n.streamServiceErrors = map[string]sets.String{}
+	}
+
+	key := fmt.Sprintf("%v/%v", proto, k8s.MetaNamespaceKey(configmap))
+	reported := n.streamServiceErrors[key]
+	n.streamServiceErrors[key] = invalid
+
+	for _, msg := range invalid.List() {
+		klog.Warning(msg)
+
+		if n.recorder == nil || reported.Has(msg) {
+			continue
+		}
+
+		n.recorder.Event(configmap, apiv1.EventTypeWarning, "InvalidStreamService", msg)
+	}
+}
+
+// filterSNIStreamServices removes the stream services routed by hostname
+// that cannot share their port with the rest of the services of the port.
+// The services must be sorted by port and hostname.
+func filterSNIStreamServices(svcs []ingress.L4Service, proto apiv1.Protocol) []ingress.L4Service {
+	plainPorts := sets.NewInt()
+	for _, svc := range svcs {
+		if svc.Hostname == "" {
+			plainPorts.Insert(svc.Port)
+		}
+	}
+
+	var filtered []ingress.L4Service
+	sniPorts := map[int]ingress.L4Service{}
+	sniHosts := sets.NewString()
+	for _, svc := range svcs {
+		if svc.Hostname == "" {
+			filtered = append(filtered, svc)
+			continue
+		}
+		if plainPorts.Has(svc.Port) {
+			klog.Warningf("Ignoring %v stream service for hostname %q. Port %d is used by a service without hostname.", proto, svc.Hostname, svc.Port)
+			continue
+		}
+		key := fmt.Sprintf("%v.%v", svc.Port, svc.Hostname)
+		if sniHosts.Has(key) {
+			klog.Warningf("Ignoring duplicated %v stream service for hostname %q in port %d", proto, svc.Hostname, svc.Port)
+			continue
+		}
+		first, ok := sniPorts[svc.Port]
+		if !ok {
+			sniPorts[svc.Port] = svc
+			sniHosts.Insert(key)
+			filtered = append(filtered, svc)
+			continue
+		}
+		if !sameStreamServerSettings(&first, &svc) {
+			klog.Warningf("Ignoring %v stream service for hostname %q. The PROXY protocol, timeouts, access log and access control settings must be the same for all the hostnames of port %d.", proto, svc.Hostname, svc.Port)
+			continue
+		}
+		sniHosts.Insert(key)
+		filtered = append(filtered, svc)
+	}
+
+	return filtered
+}
+
+// sameStreamServerSettings returns true when two stream services routed by
+// hostname can share the NGINX server of their port
+func sameStreamServerSettings(svc1, svc2 *ingress.L4Service) bool {
+	return svc1.Backend.ProxyProtocol == svc2.Backend.ProxyProtocol &&
+		svc1.ProxyTimeout == svc2.ProxyTimeout &&
+		svc1.ProxyConnectTimeout == svc2.ProxyConnectTimeout &&
+		svc1.DisableAccessLog == svc2.DisableAccessLog &&
+		(&svc1.AllowedSourceRange).Equal(&svc2.AllowedSourceRange) &&
+		(&svc1.DeniedSourceRange).Equal(&svc2.DeniedSourceRange) &&
+		svc1.ConnectionLimit == svc2.ConnectionLimit
+}
+
+// splitPassthroughStreamServices moves the TCP stream services routed by
+// hostname in the HTTPS port to the backends of the SSL Passthrough proxy.
+// The hostnames of the Ingress rules take precedence.
+func splitPassthroughStreamServices(svcs []ingress.L4Service, passUpstreams []*ingress.SSLPassthroughBackend, hostnames sets.String, httpsPort int) ([]ingress.L4Service, []*ingress.SSLPassthroughBackend) {
+	var streamSvcs []ingress.L4Service
+	for _, svc := range svcs {
+		if svc.Hostname == "" || svc.Port != httpsPort {
+			streamSvcs = append(streamSvcs, svc)
+			continue
+		}
+		if hostnames.Has(svc.Hostname) {
+			klog.Warningf("Ignoring TCP stream service for hostname %q. The hostname is used by an Ingress rule.", svc.Hostname)
+			continue
+		}
+		passUpstreams = append(passUpstreams, &ingress.SSLPassthroughBackend{
+			Service:  svc.Service,
+			Port:     svc.Backend.Port,
+			Backend:  fmt.Sprintf("tcp-%v-%v-%v", svc.Backend.Namespace, svc.Backend.Name, svc.Backend.Port.String()),
+			Hostname: svc.Hostname,
+		})
+	}
+
+	return streamSvcs, passUpstreams
 }
 
 // getDefaultUpstream returns the upstream associated with the default backend.
@@ -446,9 +679,13 @@ func (n *NGINXController) getBackendServers(ingresses []*ingress.Ingress) ([]*in
 					nginxPath = path.Path
 				}
 
+				pathType := anns.PathType.Type(nginxPath)
+				nginxPath = pathtype.NormalizePath(nginxPath, pathType)
+
 				addLoc := true
 				for _, loc := range server.Locations {
-					if loc.Path == nginxPath {
+					// the location of the default backend is replaced by any root path
+					if loc.Path == nginxPath && (loc.PathType == pathType || loc.IsDefBackend) {
 						addLoc = false
 
 						if !loc.IsDefBackend {
@@ -460,6 +697,7 @@ func (n *NGINXController) getBackendServers(ingresses []*ingress.Ingress) ([]*in
 						klog.V(3).Infof("Replacing location %q for server %q with upstream %q to use upstream %q (Ingress %q)",
 							loc.Path, server.Hostname, loc.Backend, ups.Name, ingKey)
 
+						loc.PathType = pathType
 						loc.Backend = ups.Name
 						loc.IsDefBackend = false
 						loc.Port = ups.Port
@@ -481,6 +719,7 @@ func (n *NGINXController) getBackendServers(ingresses []*ingress.Ingress) ([]*in
 
 					loc := &ingress.Location{
 						Path:         nginxPath,
+						PathType:     pathType,
 						Backend:      ups.Name,
 						IsDefBackend: false,
 						Service:      ups.Service,
@@ -581,15 +820,22 @@ func (n *NGINXController) getBackendServers(ingresses []*ingress.Ingress) ([]*in
 		}
 	}
 
+	cacheZones := sets.NewString()
+	for _, zone := range n.store.GetBackendConfiguration().ProxyCacheZones {
+		cacheZones.Insert(zone.Name)
+	}
+
 	aServers := make([]*ingress.Server, 0, len(servers))
 	for _, value := range servers {
-		sort.SliceStable(value.Locations, func(i, j int) bool {
-			return value.Locations[i].Path > value.Locations[j].Path
-		})
+		for _, location := range value.Locations {
+			if location.ProxyCache.IsEnabled() && !cacheZones.Has(location.ProxyCache.Zone) {
+				klog.Warningf("Cache zone %q of location %q in server %q is not defined in the configuration. Disabling response caching.",
+					location.ProxyCache.Zone, location.Path, value.Hostname)
+				location.ProxyCache = proxycache.Config{}
+			}
+		}
 
-		sort.SliceStable(value.Locations, func(i, j int) bool {
-			return len(value.Locations[i].Path) > len(value.Locations[j].Path)
-		})
+		sortLocations(value.Locations)
 		aServers = append(aServers, value)
 	}
 
@@ -633,6 +879,10 @@ func (n *NGINXController) createUpstreams(data []*ingress.Ingress, du *ingress.B
 				upstreams[defBackend].LoadBalancing = anns.LoadBalancing
 			}
 
+			if upstreams[defBackend].ConcurrencyLimit.MaxRequests == 0 {
+				upstreams[defBackend].ConcurrencyLimit = anns.ConcurrencyLimit
+			}
+
 			svcKey := fmt.Sprintf("%v/%v", ing.Namespace, ing.Spec.Backend.ServiceName)
 
 			// add the service ClusterIP as a single Endpoint instead of individual Endpoints
@@ -701,6 +951,10 @@ func (n *NGINXController) createUpstreams(data []*ingress.Ingress, du *ingress.B
 					upstreams[name].LoadBalancing = anns.LoadBalancing
 				}
 
+				if upstreams[name].ConcurrencyLimit.MaxRequests == 0 {
+					upstreams[name].ConcurrencyLimit = anns.ConcurrencyLimit
+				}
+
 				svcKey := fmt.Sprintf("%v/%v", ing.Namespace, path.Backend.ServiceName)
 
 				// add the service ClusterIP as a single Endpoint instead of individual Endpoints
@@ -742,6 +996,42 @@ func (n *NGINXController) createUpstreams(data []*ingress.Ingress, du *ingress.B
 				upstreams[name].Service = s
 			}
 		}
+
+		// services selected by the routes of the locations
+		for _, route := range anns.Routing.Routes {
+			name := upstreamName(ing.Namespace, route.ServiceName, route.ServicePort)
+
+			if _, ok := upstreams[name]; ok {
+				continue
+			}
+
+			klog.V(3).Infof("Creating upstream %q for route", name)
+			upstreams[name] = newUpstream(name)
+			upstreams[name].Port = route.ServicePort
+			upstreams[name].SecureCACert = anns.SecureUpstream.CACert
+			upstreams[name].UpstreamHashBy.UpstreamHashBy = anns.UpstreamHashBy.UpstreamHashBy
+			upstreams[name].UpstreamHashBy.UpstreamHashBySubset = anns.UpstreamHashBy.UpstreamHashBySubset
+			upstreams[name].UpstreamHashBy.UpstreamHashBySubsetSize = anns.UpstreamHashBy.UpstreamHashBySubsetSize
+			upstreams[name].LoadBalancing = anns.LoadBalancing
+			upstreams[name].ConcurrencyLimit = anns.ConcurrencyLimit
+
+			svcKey := fmt.Sprintf("%v/%v", ing.Namespace, route.ServiceName)
+
+			endp, err := n.serviceEndpoints(svcKey, route.ServicePort.String())
+			if err != nil {
+				klog.Warningf("Error obtaining Endpoints for Service %q: %v", svcKey, err)
+				continue
+			}
+			upstreams[name].Endpoints = endp
+
+			s, err := n.store.GetService(svcKey)
+			if err != nil {
+				klog.Warningf("Error obtaining Service %q: %v", svcKey, err)
+				continue
+			}
+
+			upstreams[name].Service = s
+		}
 	}
 
 	return upstreams
@@ -879,9 +1169,11 @@ func (n *NGINXController) createServers(data []*ingress.Ingress,
 			PemFileName: defaultPemFileName,
 			PemSHA:      defaultPemSHA,
 		},
+		HTTP3AltSvc: n.store.GetBackendConfiguration().HTTP3AltSvc,
 		Locations: []*ingress.Location{
 			{
 				Path:         rootLocation,
+				PathType:     pathtype.ImplementationSpecific,
 				IsDefBackend: true,
 				Backend:      du.Name,
 				Proxy:        ngxProxy,
@@ -949,6 +1241,7 @@ func (n *NGINXController) createServers(data []*ingress.Ingress,
 
 			loc := &ingress.Location{
 				Path:         rootLocation,
+				PathType:     pathtype.ImplementationSpecific,
 				IsDefBackend: true,
 				Backend:      un,
 				Service:      &apiv1.Service{},
@@ -962,6 +1255,7 @@ func (n *NGINXController) createServers(data []*ingress.Ingress,
 				},
 				SSLPassthrough: anns.SSLPassthrough,
 				SSLCiphers:     anns.SSLCiphers,
+				HTTP3AltSvc:    anns.HTTP3AltSvc,
 			}
 		}
 	}
@@ -1099,9 +1393,40 @@ func locationApplyAnnotations(loc *ingress.Location, anns *annotations.Ingress)
 	loc.InfluxDB = anns.InfluxDB
 	loc.DefaultBackend = anns.DefaultBackend
 	loc.BackendProtocol = anns.BackendProtocol
+	loc.GRPCWeb = anns.GRPCWeb
 	loc.CustomHTTPErrors = anns.CustomHTTPErrors
 	loc.ModSecurity = anns.ModSecurity
 	loc.Satisfy = anns.Satisfy
+	loc.Headers = anns.Headers
+	loc.ProxyCache = anns.ProxyCache
+	loc.RequestValidation = anns.RequestValidation
+	loc.RetryPolicy = anns.RetryPolicy
+
+	loc.Routing = anns.Routing
+	loc.Routing.Routes = nil
+	for _, route := range anns.Routing.Routes {
+		route.Backend = upstreamName(anns.Namespace, route.ServiceName, route.ServicePort)
+		loc.Routing.Routes = append(loc.Routing.Routes, route)
+	}
+}
+
+// sortLocations orders the locations of a server by descending path length,
+// which is the precedence of the regular expressions in NGINX. Locations with
+// the same length are ordered by path and then by path type.
+func sortLocations(locations []*ingress.Location) {
+	sort.SliceStable(locations, func(i, j int) bool {
+		li, lj := locations[i], locations[j]
+
+		if len(li.Path) != len(lj.Path) {
+			return len(li.Path) > len(lj.Path)
+		}
+
+		if li.Path != lj.Path {
+			return li.Path > lj.Path
+		}
+
+		return pathtype.Priority(li.PathType) < pathtype.Priority(lj.PathType)
+	})
 }
 
 // OK to merge canary ingresses iff there exists one or more ingresses to potentially merge into
@@ -1223,6 +1548,17 @@ func extractTLSSecretName(host string, ing *ingress.Ingress,
 		}
 	}
 
+	// a wildcard TLS host covers a single label, like wildcard certificates
+	if !isWildcardHost(host) {
+		for _, tls := range ing.Spec.TLS {
+			for _, tlsHost := range tls.Hosts {
+				if isWildcardHost(tlsHost) && matchHostnames(toLowerCaseASCII(tlsHost), toLowerCaseASCII(host)) {
+					return tls.SecretName
+				}
+			}
+		}
+	}
+
 	// no TLS host matching host name, try each TLS host for matching SAN or CN
 	for _, tls := range ing.Spec.TLS {
 
@@ -1308,6 +1644,24 @@ func getRemovedIngresses(rucfg, newcfg *ingress.Configuration) []string {
 	return oldIngresses.Difference(newIngresses).List()
 }
 
+// getRemovedStreamServices returns the TCP and UDP services of the running
+// configuration missing in the new one, using the format of the keys of the
+// stream metrics: <protocol>/<namespace>/<service>/<port>
+func getRemovedStreamServices(rucfg, newcfg *ingress.Configuration) []string {
+	streamServices := func(cfg *ingress.Configuration) sets.String {
+		services := sets.NewString()
+		for _, svcs := range [][]ingress.L4Service{cfg.TCPEndpoints, cfg.UDPEndpoints} {
+			for _, svc := range svcs {
+				services.Insert(fmt.Sprintf("%v/%v/%v/%v",
+					strings.ToLower(string(svc.Backend.Protocol)), svc.Backend.Namespace, svc.Backend.Name, svc.Port))
+			}
+		}
+		return services
+	}
+
+	return streamServices(rucfg).Difference(streamServices(newcfg)).List()
+}
+
 // checks conditions for whether or not an upstream should be created for a custom default backend
 func shouldCreateUpstreamForLocationDefaultBackend(upstream *ingress.Backend, location *ingress.Location) bool {
 	return (upstream.Name == location.Backend) &&
@@ -30,11 +30,15 @@ import (
 	extensions "k8s.io/api/extensions/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/ingress-nginx/internal/file"
 	"k8s.io/ingress-nginx/internal/ingress"
 	"k8s.io/ingress-nginx/internal/ingress/annotations"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/canary"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/pathtype"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/routing"
 	ngx_config "k8s.io/ingress-nginx/internal/ingress/controller/config"
 	"k8s.io/ingress-nginx/internal/ingress/controller/store"
 	"k8s.io/ingress-nginx/internal/k8s"
@@ -531,6 +535,83 @@ func TestExtractTLSSecretName(t *testing.T) {
 			},
 			"demo",
 		},
+		"ingress tls, wildcard tls host": {
+			"test.foo.bar",
+			&ingress.Ingress{
+				Ingress: extensions.Ingress{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "test",
+					},
+					Spec: extensions.IngressSpec{
+						TLS: []extensions.IngressTLS{
+							{
+								Hosts:      []string{"*.foo.bar"},
+								SecretName: "demo",
+							},
+						},
+						Rules: []extensions.IngressRule{
+							{
+								Host: "test.foo.bar",
+							},
+						},
+					},
+				},
+			},
+			func(string) (*ingress.SSLCert, error) {
+				return nil, nil
+			},
+			"demo",
+		},
+		"ingress tls, wildcard tls host does not match nested host": {
+			"nested.test.foo.bar",
+			&ingress.Ingress{
+				Ingress: extensions.Ingress{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "test",
+					},
+					Spec: extensions.IngressSpec{
+						TLS: []extensions.IngressTLS{
+							{
+								Hosts:      []string{"*.foo.bar"},
+								SecretName: "demo",
+							},
+						},
+					},
+				},
+			},
+			func(string) (*ingress.SSLCert, error) {
+				return nil, nil
+			},
+			"",
+		},
+		"ingress tls, wildcard rule host, wildcard cert": {
+			"*.foo.bar",
+			&ingress.Ingress{
+				Ingress: extensions.Ingress{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "test",
+					},
+					Spec: extensions.IngressSpec{
+						TLS: []extensions.IngressTLS{
+							{
+								SecretName: "demo",
+							},
+						},
+						Rules: []extensions.IngressRule{
+							{
+								Host: "*.foo.bar",
+							},
+						},
+					},
+				},
+			},
+			func(string) (*ingress.SSLCert, error) {
+				return &ingress.SSLCert{
+					Certificate: fakeX509Cert([]string{"*.foo.bar"}),
+				}, nil
+			},
+			"demo",
+		},
 		"ingress tls, no host, wildcard cert with matching cn": {
 			"foo.bar",
 			&ingress.Ingress{
@@ -877,6 +958,259 @@ func TestGetBackendServers(t *testing.T) {
 	}
 }
 
+func TestGetBackendServersWithRoutes(t *testing.T) {
+	ctl := newNGINXController(t)
+
+	ings := []*ingress.Ingress{
+		{
+			Ingress: extensions.Ingress{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "api",
+					Namespace: "example",
+				},
+				Spec: extensions.IngressSpec{
+					Rules: []extensions.IngressRule{
+						{
+							Host: "example.com",
+							IngressRuleValue: extensions.IngressRuleValue{
+								HTTP: &extensions.HTTPIngressRuleValue{
+									Paths: []extensions.HTTPIngressPath{
+										{
+											Path: "/",
+											Backend: extensions.IngressBackend{
+												ServiceName: "api",
+												ServicePort: intstr.FromInt(80),
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			ParsedAnnotations: &annotations.Ingress{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "api",
+					Namespace: "example",
+				},
+				Routing: routing.Config{
+					Routes: []routing.Route{
+						{
+							Headers:     []routing.HeaderMatch{{Name: "X-Api-Version", Value: "2", Type: routing.MatchExact}},
+							ServiceName: "api-v2",
+							ServicePort: intstr.FromInt(80),
+						},
+					},
+				},
+			},
+		},
+	}
+
+	upstreams, servers := ctl.getBackendServers(ings)
+
+	found := false
+	for _, upstream := range upstreams {
+		if upstream.Name == "example-api-v2-80" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected an upstream for the service of the route")
+	}
+
+	for _, server := range servers {
+		if server.Hostname != "example.com" {
+			continue
+		}
+
+		routes := server.Locations[0].Routing.Routes
+		if len(routes) != 1 || routes[0].Backend != "example-api-v2-80" {
+			t.Errorf("expected a route to backend example-api-v2-80 but got %+v", routes)
+		}
+
+		if ings[0].ParsedAnnotations.Routing.Routes[0].Backend != "" {
+			t.Errorf("expected the annotations of the Ingress to be unchanged")
+		}
+	}
+}
+
+func TestSortLocations(t *testing.T) {
+	locations := []*ingress.Location{
+		{Path: "/", PathType: pathtype.ImplementationSpecific},
+		{Path: "/foo", PathType: pathtype.ImplementationSpecific},
+		{Path: "/bar", PathType: pathtype.Prefix},
+		{Path: "/foo", PathType: pathtype.Prefix},
+		{Path: "/foo", PathType: pathtype.Exact},
+		{Path: "/foo/bar", PathType: pathtype.Prefix},
+	}
+
+	sortLocations(locations)
+
+	expected := []string{
+		"Prefix /foo/bar",
+		"Exact /foo",
+		"Prefix /foo",
+		"ImplementationSpecific /foo",
+		"Prefix /bar",
+		"ImplementationSpecific /",
+	}
+
+	for i, location := range locations {
+		if actual := location.PathType + " " + location.Path; actual != expected[i] {
+			t.Errorf("expected location %v to be %q but got %q", i, expected[i], actual)
+		}
+	}
+}
+
+func TestReportStreamServiceErrors(t *testing.T) {
+	recorder := record.NewFakeRecorder(10)
+	n := &NGINXController{recorder: recorder}
+
+	configmap := &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "ingress-nginx", Name: "tcp-services"}}
+
+	n.reportStreamServiceErrors(configmap, v1.ProtocolTCP, sets.NewString("invalid key", "invalid reference"))
+	n.reportStreamServiceErrors(configmap, v1.ProtocolTCP, sets.NewString("invalid key"))
+	n.reportStreamServiceErrors(configmap, v1.ProtocolTCP, sets.NewString("invalid key", "invalid reference"))
+
+	if len(recorder.Events) != 3 {
+		t.Fatalf("expected 3 events but got %v", len(recorder.Events))
+	}
+
+	expected := []string{"invalid key", "invalid reference", "invalid reference"}
+	for _, e := range expected {
+		event := <-recorder.Events
+		if event != "Warning InvalidStreamService "+e {
+			t.Errorf("expected event %q but got %q", e, event)
+		}
+	}
+}
+
+func TestFilterSNIStreamServices(t *testing.T) {
+	proxy := ingress.ProxyProtocol{Decode: true}
+	svcs := []ingress.L4Service{
+		{Port: 5432, Hostname: "a.db.example.com"},
+		{Port: 5432, Hostname: "b.db.example.com"},
+		{Port: 5432, Hostname: "b.db.example.com"},
+		{Port: 5432, Hostname: "c.db.example.com", Backend: ingress.L4Backend{ProxyProtocol: proxy}},
+		{Port: 6379},
+		{Port: 6379, Hostname: "a.redis.example.com"},
+		{Port: 8443, Hostname: "a.example.com", Backend: ingress.L4Backend{ProxyProtocol: proxy}},
+		{Port: 8443, Hostname: "b.example.com", Backend: ingress.L4Backend{ProxyProtocol: proxy}},
+	}
+
+	filtered := filterSNIStreamServices(svcs, v1.ProtocolTCP)
+
+	expected := []string{
+		"5432 a.db.example.com",
+		"5432 b.db.example.com",
+		"6379 ",
+		"8443 a.example.com",
+		"8443 b.example.com",
+	}
+
+	if len(filtered) != len(expected) {
+		t.Fatalf("expected %v stream services but got %v", len(expected), len(filtered))
+	}
+
+	for i, svc := range filtered {
+		if actual := fmt.Sprintf("%v %v", svc.Port, svc.Hostname); actual != expected[i] {
+			t.Errorf("expected stream service %v to be %q but got %q", i, expected[i], actual)
+		}
+	}
+}
+
+func TestSplitPassthroughStreamServices(t *testing.T) {
+	backend := ingress.L4Backend{Name: "postgres", Namespace: "default", Port: intstr.FromString("5432")}
+	svcs := []ingress.L4Service{
+		{Port: 443, Hostname: "a.db.example.com", Backend: backend},
+		{Port: 443, Hostname: "app.example.com", Backend: backend},
+		{Port: 5432, Hostname: "b.db.example.com", Backend: backend},
+		{Port: 6379, Backend: backend},
+	}
+	passUpstreams := []*ingress.SSLPassthroughBackend{
+		{Hostname: "app.example.com", Backend: "default-app-443"},
+	}
+
+	streamSvcs, passUpstreams := splitPassthroughStreamServices(svcs, passUpstreams, sets.NewString("app.example.com"), 443)
+
+	if len(streamSvcs) != 2 || streamSvcs[0].Port != 5432 || streamSvcs[1].Port != 6379 {
+		t.Errorf("expected the stream services of ports 5432 and 6379 but got %+v", streamSvcs)
+	}
+
+	if len(passUpstreams) != 2 {
+		t.Fatalf("expected 2 SSL Passthrough backends but got %v", len(passUpstreams))
+	}
+
+	pb := passUpstreams[1]
+	if pb.Hostname != "a.db.example.com" || pb.Backend != "tcp-default-postgres-5432" || pb.Port.String() != "5432" {
+		t.Errorf("unexpected SSL Passthrough backend %+v", pb)
+	}
+}
+
+func TestGetBackendServersPathTypes(t *testing.T) {
+	ctl := newNGINXController(t)
+
+	newIngress := func(name string, pathType pathtype.Config) *ingress.Ingress {
+		return &ingress.Ingress{
+			Ingress: extensions.Ingress{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: "example",
+				},
+				Spec: extensions.IngressSpec{
+					Rules: []extensions.IngressRule{
+						{
+							Host: "example.com",
+							IngressRuleValue: extensions.IngressRuleValue{
+								HTTP: &extensions.HTTPIngressRuleValue{
+									Paths: []extensions.HTTPIngressPath{
+										{
+											Path: "/api",
+											Backend: extensions.IngressBackend{
+												ServiceName: name,
+												ServicePort: intstr.FromInt(80),
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			ParsedAnnotations: &annotations.Ingress{
+				PathType: pathType,
+			},
+		}
+	}
+
+	_, servers := ctl.getBackendServers([]*ingress.Ingress{
+		newIngress("first", pathtype.Config{Default: pathtype.Prefix}),
+		newIngress("second", pathtype.Config{Paths: map[string]string{"/api": pathtype.Exact}}),
+		newIngress("third", pathtype.Config{Default: pathtype.Prefix}),
+	})
+
+	for _, server := range servers {
+		if server.Hostname != "example.com" {
+			continue
+		}
+
+		// root location of the default backend, Exact and Prefix /api
+		if len(server.Locations) != 3 {
+			t.Fatalf("expected 3 locations but got %v", len(server.Locations))
+		}
+
+		if server.Locations[0].PathType != pathtype.Exact || server.Locations[0].Ingress.Name != "second" {
+			t.Errorf("expected the first location to be the Exact path of Ingress second")
+		}
+
+		if server.Locations[1].PathType != pathtype.Prefix || server.Locations[1].Ingress.Name != "first" {
+			t.Errorf("expected the second location to be the Prefix path of Ingress first")
+		}
+	}
+}
+
 func newNGINXController(t *testing.T) *NGINXController {
 	ns := v1.NamespaceDefault
 	pod := &k8s.PodInfo{
@@ -910,6 +1244,7 @@ func newNGINXController(t *testing.T) *NGINXController {
 		fmt.Sprintf("%v/tcp", ns),
 		fmt.Sprintf("%v/udp", ns),
 		"",
+		"",
 		10*time.Minute,
 		clientSet,
 		fs,
@@ -0,0 +1,283 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"k8s.io/ingress-nginx/internal/ingress"
+	"k8s.io/ingress-nginx/internal/ingress/reload"
+)
+
+const (
+	changeAdded    = "added"
+	changeRemoved  = "removed"
+	changeModified = "modified"
+)
+
+// diffConfigurations computes the structured difference between two
+// configurations. Only the parts that cannot be applied dynamically must be
+// present in the configurations.
+func diffConfigurations(running, pcfg *ingress.Configuration) reload.ConfigurationDiff {
+	diff := reload.ConfigurationDiff{
+		Timestamp: time.Now(),
+	}
+
+	runningServers := make(map[string]*ingress.Server)
+	for _, server := range running.Servers {
+		runningServers[server.Hostname] = server
+	}
+
+	hostnames := sets.NewString()
+	for _, server := range pcfg.Servers {
+		hostnames.Insert(server.Hostname)
+
+		rs, ok := runningServers[server.Hostname]
+		if !ok {
+			diff.Servers = append(diff.Servers, reload.ServerDiff{
+				Hostname:  server.Hostname,
+				Change:    changeAdded,
+				Locations: locationChanges(server.Locations, changeAdded),
+			})
+			continue
+		}
+
+		if rs.Equal(server) {
+			continue
+		}
+
+		diff.Servers = append(diff.Servers, reload.ServerDiff{
+			Hostname:  server.Hostname,
+			Change:    changeModified,
+			Fields:    changedFields(rs, server, sets.NewString("locations")),
+			Locations: diffLocations(rs.Locations, server.Locations),
+		})
+	}
+
+	for _, server := range running.Servers {
+		if hostnames.Has(server.Hostname) {
+			continue
+		}
+
+		diff.Servers = append(diff.Servers, reload.ServerDiff{
+			Hostname:  server.Hostname,
+			Change:    changeRemoved,
+			Locations: locationChanges(server.Locations, changeRemoved),
+		})
+	}
+
+	diff.StreamServices = append(diff.StreamServices,
+		diffStreamServices(apiv1.ProtocolTCP, running.TCPEndpoints, pcfg.TCPEndpoints)...)
+	diff.StreamServices = append(diff.StreamServices,
+		diffStreamServices(apiv1.ProtocolUDP, running.UDPEndpoints, pcfg.UDPEndpoints)...)
+
+	return diff
+}
+
+func diffLocations(running, locations []*ingress.Location) []reload.LocationDiff {
+	// the same path can be defined with different path types
+	locationKey := func(location *ingress.Location) string {
+		return location.PathType + " " + location.Path
+	}
+
+	runningLocations := make(map[string]*ingress.Location)
+	for _, location := range running {
+		runningLocations[locationKey(location)] = location
+	}
+
+	var diffs []reload.LocationDiff
+
+	paths := sets.NewString()
+	for _, location := range locations {
+		paths.Insert(locationKey(location))
+
+		rl, ok := runningLocations[locationKey(location)]
+		if !ok {
+			diffs = append(diffs, locationChanges([]*ingress.Location{location}, changeAdded)...)
+			continue
+		}
+
+		if rl.Equal(location) {
+			continue
+		}
+
+		diffs = append(diffs, reload.LocationDiff{
+			Path:    location.Path,
+			Ingress: ingressKey(location),
+			Change:  changeModified,
+			Fields:  changedFields(rl, location, sets.NewString("ingress")),
+		})
+	}
+
+	for _, location := range running {
+		if !paths.Has(locationKey(location)) {
+			diffs = append(diffs, locationChanges([]*ingress.Location{location}, changeRemoved)...)
+		}
+	}
+
+	return diffs
+}
+
+func locationChanges(locations []*ingress.Location, change string) []reload.LocationDiff {
+	var diffs []reload.LocationDiff
+	for _, location := range locations {
+		diffs = append(diffs, reload.LocationDiff{
+			Path:    location.Path,
+			Ingress: ingressKey(location),
+			Change:  change,
+		})
+	}
+
+	return diffs
+}
+
+func diffStreamServices(proto apiv1.Protocol, running, services []ingress.L4Service) []reload.StreamServiceDiff {
+	runningServices := make(map[int]ingress.L4Service)
+	for _, svc := range running {
+		runningServices[svc.Port] = svc
+	}
+
+	var diffs []reload.StreamServiceDiff
+
+	ports := sets.NewInt()
+	for _, svc := range services {
+		ports.Insert(svc.Port)
+
+		rs, ok := runningServices[svc.Port]
+		if !ok {
+			diffs = append(diffs, reload.StreamServiceDiff{Protocol: proto, Port: svc.Port, Change: changeAdded})
+			continue
+		}
+
+		if !(&rs).Equal(&svc) {
+			diffs = append(diffs, reload.StreamServiceDiff{Protocol: proto, Port: svc.Port, Change: changeModified})
+		}
+	}
+
+	for _, svc := range running {
+		if !ports.Has(svc.Port) {
+			diffs = append(diffs, reload.StreamServiceDiff{Protocol: proto, Port: svc.Port, Change: changeRemoved})
+		}
+	}
+
+	return diffs
+}
+
+// changedFields returns the JSON names of the fields that differ between two
+// structs of the same type, like two Servers or two Locations. Fields with an
+// Equal method are compared using it, and Services are compared by name.
+func changedFields(a, b interface{}, skip sets.String) []string {
+	va := reflect.Indirect(reflect.ValueOf(a))
+	vb := reflect.Indirect(reflect.ValueOf(b))
+
+	var fields []string
+	for i := 0; i < va.NumField(); i++ {
+		field := va.Type().Field(i)
+
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			name = field.Name
+		}
+
+		if skip.Has(name) {
+			continue
+		}
+
+		if !fieldEqual(va.Field(i), vb.Field(i)) {
+			fields = append(fields, name)
+		}
+	}
+
+	return fields
+}
+
+var serviceType = reflect.TypeOf(&apiv1.Service{})
+
+func fieldEqual(a, b reflect.Value) bool {
+	if a.Type() == serviceType {
+		s1, s2 := a.Interface().(*apiv1.Service), b.Interface().(*apiv1.Service)
+		if s1 == nil || s2 == nil {
+			return s1 == s2
+		}
+
+		return s1.Namespace == s2.Namespace && s1.Name == s2.Name
+	}
+
+	if a.CanAddr() {
+		if equal := a.Addr().MethodByName("Equal"); equal.IsValid() &&
+			equal.Type().NumIn() == 1 && equal.Type().In(0) == a.Addr().Type() {
+			return equal.Call([]reflect.Value{b.Addr()})[0].Bool()
+		}
+	}
+
+	return reflect.DeepEqual(a.Interface(), b.Interface())
+}
+
+func ingressKey(location *ingress.Location) string {
+	if location.Ingress == nil {
+		return ""
+	}
+
+	return fmt.Sprintf("%v/%v", location.Ingress.Namespace, location.Ingress.Name)
+}
+
+// reloadHistory keeps the last configuration diffs that required a reload
+type reloadHistory struct {
+	mu sync.RWMutex
+
+	size  int
+	diffs []reload.ConfigurationDiff
+}
+
+func newReloadHistory(size int) *reloadHistory {
+	return &reloadHistory{
+		size: size,
+	}
+}
+
+// Add records a diff, removing the oldest one if the history is full
+func (h *reloadHistory) Add(diff reload.ConfigurationDiff) {
+	if h.size <= 0 {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.diffs = append(h.diffs, diff)
+	if len(h.diffs) > h.size {
+		h.diffs = h.diffs[len(h.diffs)-h.size:]
+	}
+}
+
+// List returns the recorded diffs, from the oldest to the newest
+func (h *reloadHistory) List() []reload.ConfigurationDiff {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	diffs := make([]reload.ConfigurationDiff, len(h.diffs))
+	copy(diffs, h.diffs)
+
+	return diffs
+}
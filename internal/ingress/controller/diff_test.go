@@ -0,0 +1,153 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	apiv1 "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/ingress-nginx/internal/ingress"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/rewrite"
+	"k8s.io/ingress-nginx/internal/ingress/reload"
+)
+
+func TestDiffConfigurations(t *testing.T) {
+	ing := &ingress.Ingress{
+		Ingress: extensions.Ingress{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "demo",
+				Namespace: "default",
+			},
+		},
+	}
+
+	running := &ingress.Configuration{
+		Servers: []*ingress.Server{
+			{
+				Hostname: "example.com",
+				Locations: []*ingress.Location{
+					{Path: "/", Backend: "default-demo-80", Ingress: ing},
+					{Path: "/old", Backend: "default-demo-80", Ingress: ing},
+				},
+			},
+			{
+				Hostname: "removed.com",
+			},
+		},
+		TCPEndpoints: []ingress.L4Service{{Port: 5432}},
+	}
+
+	pcfg := &ingress.Configuration{
+		Servers: []*ingress.Server{
+			{
+				Hostname:      "example.com",
+				ServerSnippet: "return 200;",
+				Locations: []*ingress.Location{
+					{Path: "/", Backend: "default-demo-80", Ingress: ing, Rewrite: rewrite.Config{Target: "/new"}},
+					{Path: "/new", Backend: "default-demo-80", Ingress: ing},
+				},
+			},
+			{
+				Hostname: "added.com",
+			},
+		},
+		UDPEndpoints: []ingress.L4Service{{Port: 53}},
+	}
+
+	diff := diffConfigurations(running, pcfg)
+
+	expectedServers := []reload.ServerDiff{
+		{
+			Hostname: "example.com",
+			Change:   changeModified,
+			Fields:   []string{"serverSnippet"},
+			Locations: []reload.LocationDiff{
+				{Path: "/", Ingress: "default/demo", Change: changeModified, Fields: []string{"rewrite"}},
+				{Path: "/new", Ingress: "default/demo", Change: changeAdded},
+				{Path: "/old", Ingress: "default/demo", Change: changeRemoved},
+			},
+		},
+		{
+			Hostname: "added.com",
+			Change:   changeAdded,
+		},
+		{
+			Hostname: "removed.com",
+			Change:   changeRemoved,
+		},
+	}
+	if !reflect.DeepEqual(diff.Servers, expectedServers) {
+		t.Errorf("Expected server diffs\n%+v\nbut got\n%+v", expectedServers, diff.Servers)
+	}
+
+	expectedStreams := []reload.StreamServiceDiff{
+		{Protocol: apiv1.ProtocolTCP, Port: 5432, Change: changeRemoved},
+		{Protocol: apiv1.ProtocolUDP, Port: 53, Change: changeAdded},
+	}
+	if !reflect.DeepEqual(diff.StreamServices, expectedStreams) {
+		t.Errorf("Expected stream service diffs %+v but got %+v", expectedStreams, diff.StreamServices)
+	}
+}
+
+func TestChangedFieldsComparesServicesByName(t *testing.T) {
+	l1 := &ingress.Location{
+		Path:    "/",
+		Service: &apiv1.Service{ObjectMeta: metav1.ObjectMeta{Name: "demo", Namespace: "default", ResourceVersion: "1"}},
+	}
+	l2 := &ingress.Location{
+		Path:    "/",
+		Service: &apiv1.Service{ObjectMeta: metav1.ObjectMeta{Name: "demo", Namespace: "default", ResourceVersion: "2"}},
+	}
+
+	fields := changedFields(l1, l2, nil)
+	if len(fields) != 0 {
+		t.Errorf("Expected no changed fields but got %v", fields)
+	}
+
+	l2.Service.Name = "other"
+	fields = changedFields(l1, l2, nil)
+	if !reflect.DeepEqual(fields, []string{"service"}) {
+		t.Errorf("Expected the service field to change but got %v", fields)
+	}
+}
+
+func TestReloadHistory(t *testing.T) {
+	h := newReloadHistory(2)
+
+	h.Add(reload.ConfigurationDiff{Reasons: []string{"a"}})
+	h.Add(reload.ConfigurationDiff{Reasons: []string{"b"}})
+	h.Add(reload.ConfigurationDiff{Reasons: []string{"c"}})
+
+	diffs := h.List()
+	if len(diffs) != 2 {
+		t.Fatalf("Expected 2 diffs but got %v", len(diffs))
+	}
+
+	if diffs[0].Reasons[0] != "b" || diffs[1].Reasons[0] != "c" {
+		t.Errorf("Expected the oldest diff to be removed but got %+v", diffs)
+	}
+
+	h = newReloadHistory(0)
+	h.Add(reload.ConfigurationDiff{})
+	if len(h.List()) != 0 {
+		t.Errorf("Expected an empty history when the size is zero")
+	}
+}
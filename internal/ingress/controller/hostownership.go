@@ -0,0 +1,171 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog"
+
+	"k8s.io/ingress-nginx/internal/ingress"
+)
+
+// hostOwnership contains the namespaces allowed to define the hosts
+// matching a hostname or a wildcard domain
+type hostOwnership map[string]sets.String
+
+// anyHost is the entry of the policy used when the host ownership
+// ConfigMap cannot be read. It matches any host and no namespace is
+// allowed to use it.
+const anyHost = "*"
+
+// denyAllHosts is the policy rejecting the Ingresses defining any host
+var denyAllHosts = hostOwnership{anyHost: sets.NewString()}
+
+// parseHostOwnership reads the host ownership policy from the data of a
+// ConfigMap. The key is a namespace and the value a comma separated list
+// of the hostnames and wildcard domains ("*.example.com") the namespace is
+// allowed to use.
+func parseHostOwnership(data map[string]string) hostOwnership {
+	ownership := make(hostOwnership)
+
+	for namespace, hosts := range data {
+		for _, host := range strings.Split(hosts, ",") {
+			host = strings.ToLower(strings.TrimSpace(host))
+			if host == "" {
+				continue
+			}
+
+			if strings.Contains(host[1:], "*") || (strings.HasPrefix(host, "*") && !strings.HasPrefix(host, "*.")) {
+				klog.Warningf("Invalid host %q for namespace %v in the host ownership policy", host, namespace)
+				continue
+			}
+
+			if _, ok := ownership[host]; !ok {
+				ownership[host] = sets.NewString()
+			}
+			ownership[host].Insert(namespace)
+		}
+	}
+
+	return ownership
+}
+
+// match returns the most specific hostname or wildcard domain of the policy
+// matching the host. Hosts not matching any entry are not restricted.
+func (ho hostOwnership) match(host string) (string, bool) {
+	if _, ok := ho[anyHost]; ok {
+		return anyHost, true
+	}
+
+	host = strings.ToLower(host)
+	if _, ok := ho[host]; ok {
+		return host, true
+	}
+
+	// a wildcard domain matches any number of labels, like server_name
+	domain := strings.TrimPrefix(host, "*.")
+	for {
+		i := strings.Index(domain, ".")
+		if i < 0 {
+			return "", false
+		}
+
+		domain = domain[i+1:]
+		if _, ok := ho["*."+domain]; ok {
+			return "*." + domain, true
+		}
+	}
+}
+
+// ownershipConflicts returns the hosts defined by Ingresses of namespaces
+// not allowed to use them
+func (ho hostOwnership) ownershipConflicts(ing *ingress.Ingress) []ingressConflict {
+	var conflicts []ingressConflict
+
+	for _, rule := range ing.Spec.Rules {
+		if rule.Host == "" {
+			continue
+		}
+
+		pattern, ok := ho.match(rule.Host)
+		if !ok || ho[pattern].Has(ing.Namespace) {
+			continue
+		}
+
+		conflicts = append(conflicts, ingressConflict{
+			Ingress: ing,
+			Type:    conflictHostOwnership,
+			Host:    rule.Host,
+			Pattern: pattern,
+		})
+	}
+
+	return conflicts
+}
+
+// getHostOwnership returns the host ownership policy defined in the
+// ConfigMap of the --host-ownership-configmap flag. When the ConfigMap
+// cannot be read, because it was deleted or is not synchronized yet, the
+// last policy is kept and, without one, all the hosts are rejected.
+func (n *NGINXController) getHostOwnership() hostOwnership {
+	configmapName := n.cfg.HostOwnershipConfigMapName
+	if configmapName == "" {
+		return nil
+	}
+
+	configmap, err := n.store.GetConfigMap(configmapName)
+	if err != nil {
+		if n.hostOwnership == nil {
+			klog.Errorf("Error getting host ownership ConfigMap %q, rejecting the Ingresses defining hosts: %v", configmapName, err)
+			return denyAllHosts
+		}
+
+		klog.Errorf("Error getting host ownership ConfigMap %q, using the last policy: %v", configmapName, err)
+		return n.hostOwnership
+	}
+
+	n.hostOwnership = parseHostOwnership(configmap.Data)
+	return n.hostOwnership
+}
+
+// checkHostOwnership rejects the Ingresses defining hosts their namespace is
+// not allowed to use
+func checkHostOwnership(ingresses []*ingress.Ingress, ownership hostOwnership) ([]*ingress.Ingress, []ingressConflict) {
+	if len(ownership) == 0 {
+		return ingresses, nil
+	}
+
+	var allowed []*ingress.Ingress
+	var conflicts []ingressConflict
+
+	for _, ing := range ingresses {
+		ingConflicts := ownership.ownershipConflicts(ing)
+		if len(ingConflicts) == 0 {
+			allowed = append(allowed, ing)
+			continue
+		}
+
+		for _, c := range ingConflicts {
+			c.Rejected = true
+			conflicts = append(conflicts, c)
+		}
+	}
+
+	return allowed, conflicts
+}
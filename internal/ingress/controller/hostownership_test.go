@@ -0,0 +1,132 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+
+	"k8s.io/ingress-nginx/internal/ingress"
+)
+
+func TestHostOwnershipMatch(t *testing.T) {
+	ownership := parseHostOwnership(map[string]string{
+		"team-a": "*.example.com, api.example.com",
+		"team-b": "API.example.com,*.b.example.com",
+		"team-c": "foo*.example.com, *example.org",
+	})
+
+	testCases := []struct {
+		host    string
+		pattern string
+		matches bool
+	}{
+		{"api.example.com", "api.example.com", true},
+		{"www.example.com", "*.example.com", true},
+		{"foo.www.example.com", "*.example.com", true},
+		{"www.b.example.com", "*.b.example.com", true},
+		{"*.b.example.com", "*.b.example.com", true},
+		{"*.c.example.com", "*.example.com", true},
+		{"example.com", "", false},
+		{"foo.example.org", "", false},
+	}
+
+	for _, tc := range testCases {
+		pattern, ok := ownership.match(tc.host)
+		if ok != tc.matches || pattern != tc.pattern {
+			t.Errorf("expected host %v to match %q (%v) but got %q (%v)", tc.host, tc.pattern, tc.matches, pattern, ok)
+		}
+	}
+
+	if !ownership["api.example.com"].HasAll("team-a", "team-b") {
+		t.Errorf("expected team-a and team-b to own api.example.com but got %v", ownership["api.example.com"].List())
+	}
+}
+
+func TestCheckHostOwnership(t *testing.T) {
+	ownership := parseHostOwnership(map[string]string{
+		"team-a": "*.example.com",
+		"team-b": "b.example.com",
+	})
+
+	ingresses := []*ingress.Ingress{
+		newConflictIngress("team-a", "a", 1, "a.example.com", nil, "/"),
+		newConflictIngress("team-b", "b", 2, "b.example.com", nil, "/"),
+		newConflictIngress("team-b", "hijack", 3, "a.example.com", nil, "/"),
+		newConflictIngress("team-a", "other", 4, "b.example.com", nil, "/"),
+		newConflictIngress("team-c", "free", 5, "example.org", nil, "/"),
+		newConflictIngress("team-c", "catch-all", 6, "", nil, "/"),
+	}
+
+	allowed, conflicts := checkHostOwnership(ingresses, ownership)
+
+	if names := ingressNames(allowed); names != "a,b,free,catch-all" {
+		t.Errorf("expected allowed Ingresses a,b,free,catch-all but got %v", names)
+	}
+
+	if len(conflicts) != 2 {
+		t.Fatalf("expected 2 conflicts but got %v", len(conflicts))
+	}
+
+	expected := `Namespace team-b is not allowed to use host "a.example.com" (*.example.com) by the host ownership policy. The Ingress is ignored.`
+	if msg := conflicts[0].message(); msg != expected {
+		t.Errorf("expected message %q but got %q", expected, msg)
+	}
+
+	if conflicts[1].Ingress.Name != "other" || conflicts[1].Pattern != "b.example.com" || !conflicts[1].Rejected {
+		t.Errorf("unexpected conflict %+v", conflicts[1])
+	}
+
+	allowed, conflicts = checkHostOwnership(ingresses, nil)
+	if len(allowed) != len(ingresses) || len(conflicts) != 0 {
+		t.Errorf("expected all the Ingresses to be allowed without a policy")
+	}
+}
+
+func TestGetHostOwnershipFailsClosed(t *testing.T) {
+	n := newNGINXController(t)
+
+	if ownership := n.getHostOwnership(); ownership != nil {
+		t.Errorf("expected no policy without ConfigMap name but got %v", ownership)
+	}
+
+	n.cfg.HostOwnershipConfigMapName = "default/host-ownership"
+
+	ingresses := []*ingress.Ingress{
+		newConflictIngress("team-a", "a", 1, "a.example.com", nil, "/"),
+		newConflictIngress("team-c", "catch-all", 2, "", nil, "/"),
+	}
+
+	allowed, conflicts := checkHostOwnership(ingresses, n.getHostOwnership())
+	if names := ingressNames(allowed); names != "catch-all" {
+		t.Errorf("expected only the Ingress without host to be allowed but got %v", names)
+	}
+	if len(conflicts) != 1 {
+		t.Fatalf("expected 1 conflict but got %v", len(conflicts))
+	}
+
+	expected := `Host "a.example.com" cannot be checked because the host ownership policy is not available. The Ingress is ignored.`
+	if msg := conflicts[0].message(); msg != expected {
+		t.Errorf("expected message %q but got %q", expected, msg)
+	}
+
+	n.hostOwnership = parseHostOwnership(map[string]string{"team-a": "*.example.com"})
+
+	allowed, conflicts = checkHostOwnership(ingresses, n.getHostOwnership())
+	if len(allowed) != len(ingresses) || len(conflicts) != 0 {
+		t.Errorf("expected the last policy to be used when the ConfigMap is not available")
+	}
+}
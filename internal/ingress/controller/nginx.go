@@ -56,6 +56,7 @@ import (
 	"k8s.io/ingress-nginx/internal/ingress/controller/store"
 	ngx_template "k8s.io/ingress-nginx/internal/ingress/controller/template"
 	"k8s.io/ingress-nginx/internal/ingress/metric"
+	"k8s.io/ingress-nginx/internal/ingress/reload"
 	"k8s.io/ingress-nginx/internal/ingress/status"
 	"k8s.io/ingress-nginx/internal/k8s"
 	ing_net "k8s.io/ingress-nginx/internal/net"
@@ -103,9 +104,12 @@ func NewNGINXController(config *Configuration, mc metric.Collector, fs file.File
 
 		stopLock: &sync.Mutex{},
 
+		conflictsLock: &sync.RWMutex{},
+
 		fileSystem: fs,
 
 		runningConfig: new(ingress.Configuration),
+		reloadHistory: newReloadHistory(config.ReloadHistorySize),
 
 		Proxy: &TCPProxy{},
 
@@ -124,6 +128,7 @@ func NewNGINXController(config *Configuration, mc metric.Collector, fs file.File
 		config.ConfigMapName,
 		config.TCPConfigMapName,
 		config.UDPConfigMapName,
+		config.HostOwnershipConfigMapName,
 		config.DefaultSSLCertificate,
 		config.ResyncPeriod,
 		config.Client,
@@ -141,6 +146,7 @@ func NewNGINXController(config *Configuration, mc metric.Collector, fs file.File
 			PublishService:         config.PublishService,
 			PublishStatusAddress:   config.PublishStatusAddress,
 			IngressLister:          n.store,
+			ConflictChecker:        n,
 			UpdateStatusOnShutdown: config.UpdateStatusOnShutdown,
 			UseNodeInternalIP:      config.UseNodeInternalIP,
 		})
@@ -241,6 +247,10 @@ type NGINXController struct {
 	// runningConfig contains the running configuration in the Backend
 	runningConfig *ingress.Configuration
 
+	// reloadHistory contains the last configuration changes that required
+	// a reload of the Backend
+	reloadHistory *reloadHistory
+
 	t *ngx_template.Template
 
 	resolver []net.IP
@@ -258,6 +268,22 @@ type NGINXController struct {
 	metricCollector metric.Collector
 
 	currentLeader uint32
+
+	// activeConflicts contains the conflicts between Ingresses found in the
+	// last synchronization, used to report only the new ones
+	activeConflicts map[string]ingressConflict
+	// rejectedIngresses contains the Ingresses ignored by the conflict policy
+	rejectedIngresses sets.String
+	conflictsLock     *sync.RWMutex
+
+	// hostOwnership is the last host ownership policy read from the
+	// ConfigMap, used when the ConfigMap cannot be read
+	hostOwnership hostOwnership
+
+	// streamServiceErrors contains the invalid entries found in the last
+	// synchronization by stream services ConfigMap, used to report only
+	// the new ones
+	streamServiceErrors map[string]sets.String
 }
 
 // Start starts a new NGINX master process running in the foreground.
@@ -428,6 +454,12 @@ func (n *NGINXController) start(cmd *exec.Cmd) {
 	}()
 }
 
+// ReloadHistory returns the last configuration changes that required a
+// reload of NGINX, from the oldest to the newest
+func (n *NGINXController) ReloadHistory() []reload.ConfigurationDiff {
+	return n.reloadHistory.List()
+}
+
 // DefaultEndpoint returns the default endpoint to be use as default server that returns 404.
 func (n NGINXController) DefaultEndpoint() ingress.Endpoint {
 	return ingress.Endpoint{
@@ -566,6 +598,12 @@ func (n *NGINXController) OnUpdate(ingressCfg ingress.Configuration) error {
 		cfg.MaxWorkerConnections = maxWorkerConnections
 	}
 
+	// QUIC requires TLSv1.3
+	if n.cfg.ListenPorts != nil && n.cfg.ListenPorts.HTTP3 != 0 && !strings.Contains(cfg.SSLProtocols, "TLSv1.3") {
+		klog.V(3).Infof("Adding TLSv1.3 to SSLProtocols variable, required by HTTP/3")
+		cfg.SSLProtocols = strings.TrimSpace(cfg.SSLProtocols + " TLSv1.3")
+	}
+
 	setHeaders := map[string]string{}
 	if cfg.ProxySetHeaders != "" {
 		cmap, err := n.store.GetConfigMap(cfg.ProxySetHeaders)
@@ -628,17 +666,22 @@ func (n *NGINXController) OnUpdate(ingressCfg ingress.Configuration) error {
 		PublishService:             n.GetPublishService(),
 		DynamicCertificatesEnabled: n.cfg.DynamicCertificatesEnabled,
 		EnableMetrics:              n.cfg.EnableMetrics,
+		EnableZoneMetrics:          n.cfg.EnableMetrics && n.cfg.EnableZoneMetrics,
 
-		HealthzURI:   nginx.HealthPath,
-		PID:          nginx.PID,
-		StatusSocket: nginx.StatusSocket,
-		StatusPath:   nginx.StatusPath,
-		StreamSocket: nginx.StreamSocket,
+		HealthzURI:     nginx.HealthPath,
+		PID:            nginx.PID,
+		StatusSocket:   nginx.StatusSocket,
+		StatusPath:     nginx.StatusPath,
+		ZoneStatusPath: nginx.ZoneStatusPath,
+		StreamSocket:   nginx.StreamSocket,
+		ProxyCachePath: nginx.ProxyCachePath,
 	}
 
 	tc.Cfg.Checksum = ingressCfg.ConfigurationChecksum
 
+	start := time.Now()
 	content, err := n.t.Write(tc)
+	n.metricCollector.ObserveSyncPhase(syncPhaseRenderTemplate, time.Since(start))
 	if err != nil {
 		return err
 	}
@@ -650,7 +693,14 @@ func (n *NGINXController) OnUpdate(ingressCfg ingress.Configuration) error {
 		}
 	}
 
+	err = createProxyCacheDirs(nginx.ProxyCachePath, cfg.ProxyCacheZones)
+	if err != nil {
+		return err
+	}
+
+	start = time.Now()
 	err = n.testTemplate(content)
+	n.metricCollector.ObserveSyncPhase(syncPhaseTestTemplate, time.Since(start))
 	if err != nil {
 		return err
 	}
@@ -686,11 +736,15 @@ func (n *NGINXController) OnUpdate(ingressCfg ingress.Configuration) error {
 		return err
 	}
 
+	start = time.Now()
 	o, err := nginxExecCommand("-s", "reload").CombinedOutput()
+	n.metricCollector.ObserveSyncPhase(syncPhaseReload, time.Since(start))
 	if err != nil {
 		return fmt.Errorf("%v\n%v", err, string(o))
 	}
 
+	removeStaleProxyCacheDirs(nginx.ProxyCachePath, cfg.ProxyCacheZones)
+
 	return nil
 }
 
@@ -785,19 +839,41 @@ func clearL4serviceEndpoints(config *ingress.Configuration) {
 	var clearedUDPL4Services []ingress.L4Service
 	for _, service := range config.TCPEndpoints {
 		copyofService := ingress.L4Service{
-			Port:      service.Port,
-			Backend:   service.Backend,
-			Endpoints: []ingress.Endpoint{},
-			Service:   nil,
+			Port:                service.Port,
+			Hostname:            service.Hostname,
+			Backend:             service.Backend,
+			Endpoints:           []ingress.Endpoint{},
+			Service:             nil,
+			SSLCert:             service.SSLCert,
+			ClientCACert:        service.ClientCACert,
+			ProxyTimeout:        service.ProxyTimeout,
+			ProxyConnectTimeout: service.ProxyConnectTimeout,
+			DisableAccessLog:    service.DisableAccessLog,
+			LoadBalancing:       service.LoadBalancing,
+			UpstreamHashBy:      service.UpstreamHashBy,
+			AllowedSourceRange:  service.AllowedSourceRange,
+			DeniedSourceRange:   service.DeniedSourceRange,
+			ConnectionLimit:     service.ConnectionLimit,
 		}
 		clearedTCPL4Services = append(clearedTCPL4Services, copyofService)
 	}
 	for _, service := range config.UDPEndpoints {
 		copyofService := ingress.L4Service{
-			Port:      service.Port,
-			Backend:   service.Backend,
-			Endpoints: []ingress.Endpoint{},
-			Service:   nil,
+			Port:                service.Port,
+			Hostname:            service.Hostname,
+			Backend:             service.Backend,
+			Endpoints:           []ingress.Endpoint{},
+			Service:             nil,
+			SSLCert:             service.SSLCert,
+			ClientCACert:        service.ClientCACert,
+			ProxyTimeout:        service.ProxyTimeout,
+			ProxyConnectTimeout: service.ProxyConnectTimeout,
+			DisableAccessLog:    service.DisableAccessLog,
+			LoadBalancing:       service.LoadBalancing,
+			UpstreamHashBy:      service.UpstreamHashBy,
+			AllowedSourceRange:  service.AllowedSourceRange,
+			DeniedSourceRange:   service.DeniedSourceRange,
+			ConnectionLimit:     service.ConnectionLimit,
 		}
 		clearedUDPL4Services = append(clearedUDPL4Services, copyofService)
 	}
@@ -805,9 +881,73 @@ func clearL4serviceEndpoints(config *ingress.Configuration) {
 	config.UDPEndpoints = clearedUDPL4Services
 }
 
+// clearDynamicStreamServices removes the stream services listening in the
+// dynamic port range, which are configured without a reload.
+func clearDynamicStreamServices(config *ingress.Configuration, ports ngx_config.PortRange) {
+	without := func(svcs []ingress.L4Service) []ingress.L4Service {
+		var cleared []ingress.L4Service
+		for _, svc := range svcs {
+			if !ports.Contains(svc.Port) {
+				cleared = append(cleared, svc)
+			}
+		}
+		return cleared
+	}
+
+	config.TCPEndpoints = without(config.TCPEndpoints)
+	config.UDPEndpoints = without(config.UDPEndpoints)
+}
+
 // IsDynamicConfigurationEnough returns whether a Configuration can be
 // dynamically applied, without reloading the backend.
 func (n *NGINXController) IsDynamicConfigurationEnough(pcfg *ingress.Configuration) bool {
+	copyOfRunningConfig, copyOfPcfg := n.withoutDynamicParts(pcfg)
+	return copyOfRunningConfig.Equal(copyOfPcfg)
+}
+
+// getReloadReasons returns the parts of a Configuration that differ from the
+// running configuration and cannot be applied dynamically.
+func (n *NGINXController) getReloadReasons(pcfg *ingress.Configuration) []string {
+	if n.runningConfig.Equal(&ingress.Configuration{}) {
+		return []string{reloadReasonInitialSync}
+	}
+
+	copyOfRunningConfig, copyOfPcfg := n.withoutDynamicParts(pcfg)
+
+	parts := []struct {
+		reason  string
+		extract func(*ingress.Configuration) *ingress.Configuration
+	}{
+		{reloadReasonServers, func(c *ingress.Configuration) *ingress.Configuration {
+			return &ingress.Configuration{Servers: c.Servers}
+		}},
+		{reloadReasonTCPServices, func(c *ingress.Configuration) *ingress.Configuration {
+			return &ingress.Configuration{TCPEndpoints: c.TCPEndpoints}
+		}},
+		{reloadReasonUDPServices, func(c *ingress.Configuration) *ingress.Configuration {
+			return &ingress.Configuration{UDPEndpoints: c.UDPEndpoints}
+		}},
+		{reloadReasonPassthrough, func(c *ingress.Configuration) *ingress.Configuration {
+			return &ingress.Configuration{PassthroughBackends: c.PassthroughBackends}
+		}},
+		{reloadReasonConfigMap, func(c *ingress.Configuration) *ingress.Configuration {
+			return &ingress.Configuration{BackendConfigChecksum: c.BackendConfigChecksum}
+		}},
+	}
+
+	var reasons []string
+	for _, part := range parts {
+		if !part.extract(copyOfRunningConfig).Equal(part.extract(copyOfPcfg)) {
+			reasons = append(reasons, part.reason)
+		}
+	}
+
+	return reasons
+}
+
+// withoutDynamicParts returns copies of the running configuration and pcfg
+// without the fields that are applied dynamically.
+func (n *NGINXController) withoutDynamicParts(pcfg *ingress.Configuration) (*ingress.Configuration, *ingress.Configuration) {
 	copyOfRunningConfig := *n.runningConfig
 	copyOfPcfg := *pcfg
 
@@ -817,6 +957,11 @@ func (n *NGINXController) IsDynamicConfigurationEnough(pcfg *ingress.Configurati
 	clearL4serviceEndpoints(&copyOfRunningConfig)
 	clearL4serviceEndpoints(&copyOfPcfg)
 
+	if n.cfg.ListenPorts != nil {
+		clearDynamicStreamServices(&copyOfRunningConfig, n.cfg.ListenPorts.DynamicStream)
+		clearDynamicStreamServices(&copyOfPcfg, n.cfg.ListenPorts.DynamicStream)
+	}
+
 	copyOfRunningConfig.ControllerPodsCount = 0
 	copyOfPcfg.ControllerPodsCount = 0
 
@@ -825,12 +970,13 @@ func (n *NGINXController) IsDynamicConfigurationEnough(pcfg *ingress.Configurati
 		clearCertificates(&copyOfPcfg)
 	}
 
-	return copyOfRunningConfig.Equal(&copyOfPcfg)
+	return &copyOfRunningConfig, &copyOfPcfg
 }
 
 // configureDynamically encodes new Backends in JSON format and POSTs the
-// payload to an internal HTTP endpoint handled by Lua.
-func configureDynamically(pcfg *ingress.Configuration, isDynamicCertificatesEnabled bool) error {
+// payload to an internal HTTP endpoint handled by Lua. Certificates are
+// configured separately by configureCertificates.
+func configureDynamically(pcfg *ingress.Configuration) error {
 	backends := make([]*ingress.Backend, len(pcfg.Backends))
 
 	for i, backend := range pcfg.Backends {
@@ -845,6 +991,7 @@ func configureDynamically(pcfg *ingress.Configuration, isDynamicCertificatesEnab
 			SessionAffinity:      backend.SessionAffinity,
 			UpstreamHashBy:       backend.UpstreamHashBy,
 			LoadBalancing:        backend.LoadBalancing,
+			ConcurrencyLimit:     backend.ConcurrencyLimit,
 			Service:              service,
 			NoServer:             backend.NoServer,
 			TrafficShapingPolicy: backend.TrafficShapingPolicy,
@@ -881,10 +1028,12 @@ func configureDynamically(pcfg *ingress.Configuration, isDynamicCertificatesEnab
 
 		key := fmt.Sprintf("tcp-%v-%v-%v", ep.Backend.Namespace, ep.Backend.Name, ep.Backend.Port.String())
 		streams = append(streams, ingress.Backend{
-			Name:      key,
-			Endpoints: ep.Endpoints,
-			Port:      intstr.FromInt(ep.Port),
-			Service:   service,
+			Name:           key,
+			Endpoints:      ep.Endpoints,
+			Port:           intstr.FromInt(ep.Port),
+			Service:        service,
+			LoadBalancing:  ep.LoadBalancing,
+			UpstreamHashBy: ingress.UpstreamHashByConfig{UpstreamHashBy: ep.UpstreamHashBy},
 		})
 	}
 	for _, ep := range pcfg.UDPEndpoints {
@@ -895,14 +1044,23 @@ func configureDynamically(pcfg *ingress.Configuration, isDynamicCertificatesEnab
 
 		key := fmt.Sprintf("udp-%v-%v-%v", ep.Backend.Namespace, ep.Backend.Name, ep.Backend.Port.String())
 		streams = append(streams, ingress.Backend{
-			Name:      key,
-			Endpoints: ep.Endpoints,
-			Port:      intstr.FromInt(ep.Port),
-			Service:   service,
+			Name:           key,
+			Endpoints:      ep.Endpoints,
+			Port:           intstr.FromInt(ep.Port),
+			Service:        service,
+			LoadBalancing:  ep.LoadBalancing,
+			UpstreamHashBy: ingress.UpstreamHashByConfig{UpstreamHashBy: ep.UpstreamHashBy},
 		})
 	}
 
-	err = updateStreamConfiguration(streams)
+	// the services of the TCP and UDP ports without hostname, used by the
+	// servers of the dynamic port range
+	routes := map[string]map[string]streamRoute{
+		"tcp": streamRoutes(pcfg.TCPEndpoints, "tcp"),
+		"udp": streamRoutes(pcfg.UDPEndpoints, "udp"),
+	}
+
+	err = updateStreamConfiguration(streams, routes)
 	if err != nil {
 		return err
 	}
@@ -918,55 +1076,102 @@ func configureDynamically(pcfg *ingress.Configuration, isDynamicCertificatesEnab
 		return fmt.Errorf("unexpected error code: %d", statusCode)
 	}
 
-	if isDynamicCertificatesEnabled {
-		err = configureCertificates(pcfg)
-		if err != nil {
-			return err
+	return nil
+}
+
+// streamRoute is the upstream of a stream service and the namespace and name
+// used to report its metrics
+type streamRoute struct {
+	Upstream  string `json:"upstream"`
+	Namespace string `json:"namespace"`
+	Service   string `json:"service"`
+}
+
+// streamRoutes returns the routes of the stream services without hostname
+// indexed by port
+func streamRoutes(svcs []ingress.L4Service, proto string) map[string]streamRoute {
+	routes := map[string]streamRoute{}
+	for _, svc := range svcs {
+		if svc.Hostname != "" {
+			continue
 		}
-	}
 
-	return nil
+		routes[strconv.Itoa(svc.Port)] = streamRoute{
+			Upstream:  fmt.Sprintf("%v-%v-%v-%v", proto, svc.Backend.Namespace, svc.Backend.Name, svc.Backend.Port.String()),
+			Namespace: svc.Backend.Namespace,
+			Service:   svc.Backend.Name,
+		}
+	}
+	return routes
 }
 
-func updateStreamConfiguration(streams []ingress.Backend) error {
+// updateStreamConfiguration sends the backends and the routes by port of the
+// stream services to the stream block, each encoded in JSON in one line.
+func updateStreamConfiguration(streams []ingress.Backend, routes map[string]map[string]streamRoute) error {
 	conn, err := net.Dial("unix", nginx.StreamSocket)
 	if err != nil {
 		return err
 	}
 	defer conn.Close()
 
-	buf, err := json.Marshal(streams)
-	if err != nil {
-		return err
+	for _, data := range []interface{}{streams, routes} {
+		buf, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+
+		_, err = conn.Write(buf)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(conn, "\r\n")
+		if err != nil {
+			return err
+		}
 	}
 
-	_, err = conn.Write(buf)
+	return nil
+}
+
+// configureCertificates JSON encodes certificates and POSTs it to an internal HTTP endpoint
+// that is handled by Lua
+func configureCertificates(pcfg *ingress.Configuration) error {
+	servers := certificateServers(pcfg.Servers)
+
+	statusCode, _, err := nginx.NewPostStatusRequest("/configuration/servers", "application/json", servers)
 	if err != nil {
 		return err
 	}
-	_, err = fmt.Fprintf(conn, "\r\n")
-	if err != nil {
-		return err
+
+	if statusCode != http.StatusCreated {
+		return fmt.Errorf("unexpected error code: %d", statusCode)
 	}
 
 	return nil
 }
 
-// configureCertificates JSON encodes certificates and POSTs it to an internal HTTP endpoint
-// that is handled by Lua
-func configureCertificates(pcfg *ingress.Configuration) error {
-	var servers []*ingress.Server
+// zoneStatusZones contains the names of the servers and the upstreams of
+// the configuration
+type zoneStatusZones struct {
+	Servers   []string `json:"servers"`
+	Upstreams []string `json:"upstreams"`
+}
 
+// configureZoneStatus POSTs the servers and upstreams of the configuration to
+// the zone status endpoint, which removes the counters of the other zones
+func configureZoneStatus(pcfg *ingress.Configuration) error {
+	zones := zoneStatusZones{
+		Servers:   []string{},
+		Upstreams: []string{},
+	}
 	for _, server := range pcfg.Servers {
-		servers = append(servers, &ingress.Server{
-			Hostname: server.Hostname,
-			SSLCert: ingress.SSLCert{
-				PemCertKey: server.SSLCert.PemCertKey,
-			},
-		})
+		zones.Servers = append(zones.Servers, server.Hostname)
+	}
+	for _, backend := range pcfg.Backends {
+		zones.Upstreams = append(zones.Upstreams, backend.Name)
 	}
 
-	statusCode, _, err := nginx.NewPostStatusRequest("/configuration/servers", "application/json", servers)
+	statusCode, _, err := nginx.NewPostStatusRequest(nginx.ZoneStatusPath, "application/json", zones)
 	if err != nil {
 		return err
 	}
@@ -978,6 +1183,41 @@ func configureCertificates(pcfg *ingress.Configuration) error {
 	return nil
 }
 
+// certificateServers returns the certificate of each hostname the Lua
+// certificate lookup receives as SNI. The alias of a server shares its
+// server block, so the requests to the alias must use the same certificate
+// instead of the default one, unless another server defines the alias.
+func certificateServers(pcfgServers []*ingress.Server) []*ingress.Server {
+	var servers []*ingress.Server
+
+	hostnames := sets.NewString()
+	for _, server := range pcfgServers {
+		hostnames.Insert(server.Hostname)
+	}
+
+	for _, server := range pcfgServers {
+		servers = append(servers, &ingress.Server{
+			Hostname: server.Hostname,
+			SSLCert: ingress.SSLCert{
+				PemCertKey: server.SSLCert.PemCertKey,
+			},
+		})
+
+		// the SNI of the requests to an alias is the alias
+		if server.Alias != "" && !hostnames.Has(server.Alias) {
+			hostnames.Insert(server.Alias)
+			servers = append(servers, &ingress.Server{
+				Hostname: server.Alias,
+				SSLCert: ingress.SSLCert{
+					PemCertKey: server.SSLCert.PemCertKey,
+				},
+			})
+		}
+	}
+
+	return servers
+}
+
 const zipkinTmpl = `{
   "service_name": "{{ .ZipkinServiceName }}",
   "collector_host": "{{ .ZipkinCollectorHost }}",
@@ -1084,6 +1324,12 @@ func buildRedirects(servers []*ingress.Server) []*redirect {
 
 		to := srv.Hostname
 
+		// www.*.example.com is not a valid server name
+		if isWildcardHost(to) {
+			klog.V(3).Infof("Skipping redirect from/to www for wildcard server %q", to)
+			continue
+		}
+
 		var from string
 		if strings.HasPrefix(to, "www.") {
 			from = strings.TrimPrefix(to, "www.")
@@ -24,14 +24,18 @@ import (
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
 
 	jsoniter "github.com/json-iterator/go"
 	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 
 	"k8s.io/ingress-nginx/internal/ingress"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/concurrencylimit"
+	ngx_config "k8s.io/ingress-nginx/internal/ingress/controller/config"
 	"k8s.io/ingress-nginx/internal/nginx"
 )
 
@@ -150,6 +154,121 @@ func TestIsDynamicConfigurationEnough(t *testing.T) {
 	}
 }
 
+func TestIsDynamicConfigurationEnoughWithStreamCertificates(t *testing.T) {
+	tcpService := func(cert *ingress.SSLCert) []ingress.L4Service {
+		return []ingress.L4Service{{
+			Port:    5432,
+			Backend: ingress.L4Backend{Name: "postgres", Namespace: "default", Protocol: apiv1.ProtocolTCP},
+			SSLCert: cert,
+		}}
+	}
+
+	n := &NGINXController{
+		runningConfig: &ingress.Configuration{
+			TCPEndpoints: tcpService(&ingress.SSLCert{PemFileName: "default-postgres-tls.pem", PemSHA: "1", PemCertKey: "fake-certificate"}),
+		},
+		cfg: &Configuration{
+			DynamicCertificatesEnabled: true,
+		},
+	}
+
+	// the stream servers use the certificate of the configuration file
+	newConfig := &ingress.Configuration{
+		TCPEndpoints: tcpService(&ingress.SSLCert{PemFileName: "default-postgres-tls.pem", PemSHA: "2", PemCertKey: "new-fake-certificate"}),
+	}
+	if n.IsDynamicConfigurationEnough(newConfig) {
+		t.Errorf("Expected to not be dynamically configurable when the certificate of a TCP service changes")
+	}
+
+	newConfig = &ingress.Configuration{
+		TCPEndpoints: tcpService(nil),
+	}
+	if n.IsDynamicConfigurationEnough(newConfig) {
+		t.Errorf("Expected to not be dynamically configurable when TLS is disabled in a TCP service")
+	}
+}
+
+func TestIsDynamicConfigurationEnoughWithDynamicStreamPorts(t *testing.T) {
+	tcpService := func(port int, name string) ingress.L4Service {
+		return ingress.L4Service{
+			Port:    port,
+			Backend: ingress.L4Backend{Name: name, Namespace: "default", Protocol: apiv1.ProtocolTCP},
+		}
+	}
+
+	n := &NGINXController{
+		runningConfig: &ingress.Configuration{
+			TCPEndpoints: []ingress.L4Service{tcpService(5432, "postgres"), tcpService(10000, "redis")},
+		},
+		cfg: &Configuration{
+			ListenPorts: &ngx_config.ListenPorts{
+				DynamicStream: ngx_config.PortRange{First: 10000, Last: 10099},
+			},
+		},
+	}
+
+	newConfig := &ingress.Configuration{
+		TCPEndpoints: []ingress.L4Service{tcpService(5432, "postgres"), tcpService(10000, "memcached"), tcpService(10001, "redis")},
+	}
+	if !n.IsDynamicConfigurationEnough(newConfig) {
+		t.Errorf("Expected to be dynamically configurable when only the services of the dynamic port range change")
+	}
+
+	newConfig = &ingress.Configuration{
+		TCPEndpoints: []ingress.L4Service{tcpService(10000, "redis")},
+	}
+	if n.IsDynamicConfigurationEnough(newConfig) {
+		t.Errorf("Expected to not be dynamically configurable when a service out of the dynamic port range is removed")
+	}
+}
+
+func TestGetReloadReasons(t *testing.T) {
+	servers := []*ingress.Server{{
+		Hostname: "myapp.fake",
+		Locations: []*ingress.Location{
+			{
+				Path:    "/",
+				Backend: "fakenamespace-myapp-80",
+			},
+		},
+	}}
+
+	n := &NGINXController{
+		runningConfig: &ingress.Configuration{},
+		cfg:           &Configuration{},
+	}
+
+	reasons := n.getReloadReasons(&ingress.Configuration{Servers: servers})
+	if !reflect.DeepEqual(reasons, []string{reloadReasonInitialSync}) {
+		t.Errorf("Expected the initial sync to be the only reload reason but got %v", reasons)
+	}
+
+	n.runningConfig = &ingress.Configuration{
+		Backends:              []*ingress.Backend{{Name: "fakenamespace-myapp-80"}},
+		Servers:               servers,
+		BackendConfigChecksum: "1",
+	}
+
+	reasons = n.getReloadReasons(&ingress.Configuration{
+		Backends:              []*ingress.Backend{{Name: "fakenamespace-myapp-8080"}},
+		Servers:               servers,
+		BackendConfigChecksum: "1",
+	})
+	if len(reasons) != 0 {
+		t.Errorf("Expected no reload reasons when only backends change but got %v", reasons)
+	}
+
+	reasons = n.getReloadReasons(&ingress.Configuration{
+		Servers:               []*ingress.Server{{Hostname: "myapp1.fake"}},
+		TCPEndpoints:          []ingress.L4Service{{Port: 5432}},
+		BackendConfigChecksum: "2",
+	})
+	expected := []string{reloadReasonServers, reloadReasonTCPServices, reloadReasonConfigMap}
+	if !reflect.DeepEqual(reasons, expected) {
+		t.Errorf("Expected reload reasons %v but got %v", expected, reasons)
+	}
+}
+
 func TestConfigureDynamically(t *testing.T) {
 	listener, err := net.Listen("unix", nginx.StatusSocket)
 	if err != nil {
@@ -191,6 +310,10 @@ func TestConfigureDynamically(t *testing.T) {
 						if !strings.Contains(body, "service") {
 							t.Errorf("service reference should be present in JSON content: %v", body)
 						}
+
+						if !strings.Contains(body, `"concurrencyLimit":{"maxRequests":10}`) {
+							t.Errorf("concurrency limit should be present in JSON content: %v", body)
+						}
 					}
 				case "/configuration/general":
 					{
@@ -210,8 +333,9 @@ func TestConfigureDynamically(t *testing.T) {
 	target := &apiv1.ObjectReference{}
 
 	backends := []*ingress.Backend{{
-		Name:    "fakenamespace-myapp-80",
-		Service: &apiv1.Service{},
+		Name:             "fakenamespace-myapp-80",
+		Service:          &apiv1.Service{},
+		ConcurrencyLimit: concurrencylimit.Config{MaxRequests: 10},
 		Endpoints: []ingress.Endpoint{
 			{
 				Address: "10.0.0.1",
@@ -237,17 +361,59 @@ func TestConfigureDynamically(t *testing.T) {
 		},
 	}}
 
+	streamData := make(chan string, 1)
+	go func() {
+		conn, err := streamListener.Accept()
+		if err != nil {
+			streamData <- ""
+			return
+		}
+		defer conn.Close()
+		b, _ := ioutil.ReadAll(conn)
+		streamData <- string(b)
+	}()
+
 	commonConfig := &ingress.Configuration{
 		Backends:            backends,
 		Servers:             servers,
 		ControllerPodsCount: 2,
-	}
-
-	err = configureDynamically(commonConfig, false)
+		TCPEndpoints: []ingress.L4Service{{
+			Port:          5432,
+			Backend:       ingress.L4Backend{Namespace: "default", Name: "postgres", Port: intstr.FromString("5432")},
+			LoadBalancing: "least_conn",
+		}},
+		UDPEndpoints: []ingress.L4Service{{
+			Port:           53,
+			Backend:        ingress.L4Backend{Namespace: "kube-system", Name: "kube-dns", Port: intstr.FromString("53")},
+			LoadBalancing:  "chash",
+			UpstreamHashBy: "$remote_addr",
+		}},
+	}
+
+	err = configureDynamically(commonConfig)
 	if err != nil {
 		t.Errorf("unexpected error posting dynamic configuration: %v", err)
 	}
 
+	select {
+	case body := <-streamData:
+		expected := []string{
+			`"name":"tcp-default-postgres-5432"`,
+			`"load-balance":"least_conn"`,
+			`"name":"udp-kube-system-kube-dns-53"`,
+			`"upstreamHashByConfig":{"upstream-hash-by":"$remote_addr"}`,
+			`"tcp":{"5432":{"upstream":"tcp-default-postgres-5432","namespace":"default","service":"postgres"}}`,
+			`"udp":{"53":{"upstream":"udp-kube-system-kube-dns-53","namespace":"kube-system","service":"kube-dns"}}`,
+		}
+		for _, e := range expected {
+			if !strings.Contains(body, e) {
+				t.Errorf("%v should be present in the stream configuration: %v", e, body)
+			}
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("expected the stream configuration to be sent")
+	}
+
 	if commonConfig.Backends[0].Endpoints[0].Target != target {
 		t.Errorf("unexpected change in the configuration object after configureDynamically invocation")
 	}
@@ -320,6 +486,81 @@ func TestConfigureCertificates(t *testing.T) {
 	}
 }
 
+func TestConfigureZoneStatus(t *testing.T) {
+	listener, err := net.Listen("unix", nginx.StatusSocket)
+	if err != nil {
+		t.Errorf("crating unix listener: %s", err)
+	}
+	defer listener.Close()
+	defer os.Remove(nginx.StatusSocket)
+
+	var posted zoneStatusZones
+	server := &httptest.Server{
+		Listener: listener,
+		Config: &http.Server{
+			Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusCreated)
+
+				if r.Method != "POST" || r.URL.Path != nginx.ZoneStatusPath {
+					t.Errorf("expected a 'POST' request to %v, got '%s' to %v", nginx.ZoneStatusPath, r.Method, r.URL.Path)
+				}
+
+				b, err := ioutil.ReadAll(r.Body)
+				if err != nil && err != io.EOF {
+					t.Fatal(err)
+				}
+				err = jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal(b, &posted)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}),
+		},
+	}
+	defer server.Close()
+	server.Start()
+
+	err = configureZoneStatus(&ingress.Configuration{
+		Servers:  []*ingress.Server{{Hostname: "_"}, {Hostname: "myapp.fake"}},
+		Backends: []*ingress.Backend{{Name: "upstream-default-backend"}, {Name: "fakenamespace-myapp-80"}},
+	})
+	if err != nil {
+		t.Errorf("unexpected error posting the zones: %v", err)
+	}
+
+	expected := zoneStatusZones{
+		Servers:   []string{"_", "myapp.fake"},
+		Upstreams: []string{"upstream-default-backend", "fakenamespace-myapp-80"},
+	}
+	if !reflect.DeepEqual(posted, expected) {
+		t.Errorf("expected the zones %v but %v were posted", expected, posted)
+	}
+}
+
+func TestCertificateServers(t *testing.T) {
+	servers := certificateServers([]*ingress.Server{
+		{Hostname: "example.com", Alias: "www.example.com", SSLCert: ingress.SSLCert{PemCertKey: "example-cert"}},
+		{Hostname: "foo.com", Alias: "bar.com", SSLCert: ingress.SSLCert{PemCertKey: "foo-cert"}},
+		{Hostname: "bar.com", SSLCert: ingress.SSLCert{PemCertKey: "bar-cert"}},
+	})
+
+	expected := map[string]string{
+		"example.com":     "example-cert",
+		"www.example.com": "example-cert",
+		"foo.com":         "foo-cert",
+		"bar.com":         "bar-cert",
+	}
+
+	if len(servers) != len(expected) {
+		t.Fatalf("expected %v servers but got %v", len(expected), len(servers))
+	}
+
+	for _, server := range servers {
+		if cert := expected[server.Hostname]; server.SSLCert.PemCertKey != cert {
+			t.Errorf("expected certificate %q for %v but got %q", cert, server.Hostname, server.SSLCert.PemCertKey)
+		}
+	}
+}
+
 func TestNginxHashBucketSize(t *testing.T) {
 	tests := []struct {
 		n        int
@@ -0,0 +1,71 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog"
+
+	ngx_config "k8s.io/ingress-nginx/internal/ingress/controller/config"
+)
+
+// createProxyCacheDirs creates the directories of the cache zones. NGINX
+// does not start if the path of a proxy_cache_path directive is missing.
+func createProxyCacheDirs(base string, zones []ngx_config.ProxyCacheZone) error {
+	for _, zone := range zones {
+		err := os.MkdirAll(filepath.Join(base, zone.Name), 0700)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// removeStaleProxyCacheDirs removes the cached responses of the zones not
+// present in the configuration anymore. It must be called after the reload
+// to avoid removing files still used by the old worker processes.
+func removeStaleProxyCacheDirs(base string, zones []ngx_config.ProxyCacheZone) {
+	files, err := ioutil.ReadDir(base)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			klog.Warningf("Error reading the cache directory %v: %v", base, err)
+		}
+		return
+	}
+
+	names := sets.NewString()
+	for _, zone := range zones {
+		names.Insert(zone.Name)
+	}
+
+	for _, f := range files {
+		if !f.IsDir() || names.Has(f.Name()) {
+			continue
+		}
+
+		klog.Infof("Removing directory of stale cache zone %v", f.Name())
+		err := os.RemoveAll(filepath.Join(base, f.Name()))
+		if err != nil {
+			klog.Warningf("Error removing directory of stale cache zone %v: %v", f.Name(), err)
+		}
+	}
+}
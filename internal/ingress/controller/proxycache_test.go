@@ -0,0 +1,55 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	ngx_config "k8s.io/ingress-nginx/internal/ingress/controller/config"
+)
+
+func TestProxyCacheDirs(t *testing.T) {
+	base, err := ioutil.TempDir("", "nginx-cache")
+	if err != nil {
+		t.Fatalf("unexpected error creating temporal directory: %v", err)
+	}
+	defer os.RemoveAll(base)
+
+	zones := []ngx_config.ProxyCacheZone{{Name: "static"}, {Name: "api"}}
+	err = createProxyCacheDirs(base, zones)
+	if err != nil {
+		t.Fatalf("unexpected error creating cache directories: %v", err)
+	}
+
+	for _, name := range []string{"static", "api"} {
+		if _, err := os.Stat(filepath.Join(base, name)); err != nil {
+			t.Errorf("expected directory for cache zone %v: %v", name, err)
+		}
+	}
+
+	removeStaleProxyCacheDirs(base, zones[1:])
+
+	if _, err := os.Stat(filepath.Join(base, "static")); !os.IsNotExist(err) {
+		t.Errorf("expected directory of stale cache zone static to be removed")
+	}
+	if _, err := os.Stat(filepath.Join(base, "api")); err != nil {
+		t.Errorf("expected directory for cache zone api: %v", err)
+	}
+}
@@ -49,6 +49,7 @@ import (
 	"k8s.io/ingress-nginx/internal/ingress/annotations/class"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
 	ngx_config "k8s.io/ingress-nginx/internal/ingress/controller/config"
+	"k8s.io/ingress-nginx/internal/ingress/controller/streams"
 	ngx_template "k8s.io/ingress-nginx/internal/ingress/controller/template"
 	"k8s.io/ingress-nginx/internal/ingress/defaults"
 	"k8s.io/ingress-nginx/internal/ingress/errors"
@@ -207,6 +208,14 @@ type k8sStore struct {
 	// secret in the annotations.
 	secretIngressMap ObjectRefMap
 
+	// configMapIngressMap contains information about which ingress references
+	// a configmap in the annotations.
+	configMapIngressMap ObjectRefMap
+
+	// secretStreamMap contains information about which TCP services
+	// configmap references a secret.
+	secretStreamMap ObjectRefMap
+
 	filesystem file.Filesystem
 
 	// updateCh
@@ -227,7 +236,7 @@ type k8sStore struct {
 
 // New creates a new object store to be used in the ingress controller
 func New(checkOCSP bool,
-	namespace, configmap, tcp, udp, defaultSSLCertificate string,
+	namespace, configmap, tcp, udp, hostOwnership, defaultSSLCertificate string,
 	resyncPeriod time.Duration,
 	client clientset.Interface,
 	fs file.Filesystem,
@@ -247,6 +256,8 @@ func New(checkOCSP bool,
 		syncSecretMu:                 &sync.Mutex{},
 		backendConfigMu:              &sync.RWMutex{},
 		secretIngressMap:             NewObjectRefMap(),
+		configMapIngressMap:          NewObjectRefMap(),
+		secretStreamMap:              NewObjectRefMap(),
 		defaultSSLCertificate:        defaultSSLCertificate,
 		isDynamicCertificatesEnabled: isDynamicCertificatesEnabled,
 		pod:                          pod,
@@ -333,6 +344,7 @@ func New(checkOCSP bool,
 
 		key := k8s.MetaNamespaceKey(ing)
 		store.secretIngressMap.Delete(key)
+		store.configMapIngressMap.Delete(key)
 
 		updateCh.In() <- Event{
 			Type: DeleteEvent,
@@ -356,6 +368,7 @@ func New(checkOCSP bool,
 
 			store.syncIngress(ing)
 			store.updateSecretIngressMap(ing)
+			store.updateConfigMapIngressMap(ing)
 			store.syncSecrets(ing)
 
 			updateCh.In() <- Event{
@@ -396,6 +409,7 @@ func New(checkOCSP bool,
 
 			store.syncIngress(curIng)
 			store.updateSecretIngressMap(curIng)
+			store.updateConfigMapIngressMap(curIng)
 			store.syncSecrets(curIng)
 
 			updateCh.In() <- Event{
@@ -414,6 +428,16 @@ func New(checkOCSP bool,
 				store.syncSecret(store.defaultSSLCertificate)
 			}
 
+			// find references in stream services and update local ssl certs
+			if store.secretStreamMap.Has(key) {
+				klog.Infof("secret %v was added and it is used in stream services", key)
+				store.syncSecret(key)
+				updateCh.In() <- Event{
+					Type: CreateEvent,
+					Obj:  obj,
+				}
+			}
+
 			// find references in ingresses and update local ssl certs
 			if ings := store.secretIngressMap.Reference(key); len(ings) > 0 {
 				klog.Infof("secret %v was added and it is used in ingress annotations. Parsing...", key)
@@ -441,6 +465,16 @@ func New(checkOCSP bool,
 					store.syncSecret(store.defaultSSLCertificate)
 				}
 
+				// find references in stream services and update local ssl certs
+				if store.secretStreamMap.Has(key) {
+					klog.Infof("secret %v was updated and it is used in stream services", key)
+					store.syncSecret(key)
+					updateCh.In() <- Event{
+						Type: UpdateEvent,
+						Obj:  cur,
+					}
+				}
+
 				// find references in ingresses and update local ssl certs
 				if ings := store.secretIngressMap.Reference(key); len(ings) > 0 {
 					klog.Infof("secret %v was updated and it is used in ingress annotations. Parsing...", key)
@@ -480,6 +514,15 @@ func New(checkOCSP bool,
 
 			key := k8s.MetaNamespaceKey(sec)
 
+			// find references in stream services
+			if store.secretStreamMap.Has(key) {
+				klog.Infof("secret %v was deleted and it is used in stream services", key)
+				updateCh.In() <- Event{
+					Type: DeleteEvent,
+					Obj:  obj,
+				}
+			}
+
 			// find references in ingresses
 			if ings := store.secretIngressMap.Reference(key); len(ings) > 0 {
 				klog.Infof("secret %v was deleted and it is used in ingress annotations. Parsing...", key)
@@ -529,27 +572,43 @@ func New(checkOCSP bool,
 			cm := obj.(*corev1.ConfigMap)
 			key := k8s.MetaNamespaceKey(cm)
 			// updates to configuration configmaps can trigger an update
-			if key == configmap || key == tcp || key == udp {
+			if key == configmap || key == tcp || key == udp || key == hostOwnership {
 				recorder.Eventf(cm, corev1.EventTypeNormal, "CREATE", fmt.Sprintf("ConfigMap %v", key))
 				if key == configmap {
 					store.setConfig(cm)
 				}
+				if key == tcp {
+					store.updateSecretStreamMap(cm)
+				}
 				updateCh.In() <- Event{
 					Type: ConfigurationEvent,
 					Obj:  obj,
 				}
 			}
+
+			// find references in ingress annotations
+			if ings := store.configMapIngressMap.Reference(key); len(ings) > 0 {
+				klog.Infof("configmap %v was added and it is used in ingress annotations. Parsing...", key)
+				store.syncReferencingIngresses(ings)
+				updateCh.In() <- Event{
+					Type: CreateEvent,
+					Obj:  obj,
+				}
+			}
 		},
 		UpdateFunc: func(old, cur interface{}) {
 			if !reflect.DeepEqual(old, cur) {
 				cm := cur.(*corev1.ConfigMap)
 				key := k8s.MetaNamespaceKey(cm)
 				// updates to configuration configmaps can trigger an update
-				if key == configmap || key == tcp || key == udp {
+				if key == configmap || key == tcp || key == udp || key == hostOwnership {
 					recorder.Eventf(cm, corev1.EventTypeNormal, "UPDATE", fmt.Sprintf("ConfigMap %v", key))
 					if key == configmap {
 						store.setConfig(cm)
 					}
+					if key == tcp {
+						store.updateSecretStreamMap(cm)
+					}
 
 					ings := store.listers.IngressWithAnnotation.List()
 					for _, ingKey := range ings {
@@ -567,6 +626,57 @@ func New(checkOCSP bool,
 						Obj:  cur,
 					}
 				}
+
+				// find references in ingress annotations
+				if ings := store.configMapIngressMap.Reference(key); len(ings) > 0 {
+					klog.Infof("configmap %v was updated and it is used in ingress annotations. Parsing...", key)
+					store.syncReferencingIngresses(ings)
+					updateCh.In() <- Event{
+						Type: UpdateEvent,
+						Obj:  cur,
+					}
+				}
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			cm, ok := obj.(*corev1.ConfigMap)
+			if !ok {
+				// If we reached here it means the configmap was deleted but its final state is unrecorded.
+				tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+				if !ok {
+					klog.Errorf("couldn't get object from tombstone %#v", obj)
+					return
+				}
+				cm, ok = tombstone.Obj.(*corev1.ConfigMap)
+				if !ok {
+					klog.Errorf("Tombstone contained object that is not a ConfigMap: %#v", obj)
+					return
+				}
+			}
+
+			key := k8s.MetaNamespaceKey(cm)
+
+			if key == tcp {
+				store.secretStreamMap.Delete(key)
+			}
+
+			// the host ownership policy is checked again without the ConfigMap
+			if key == hostOwnership {
+				recorder.Eventf(cm, corev1.EventTypeWarning, "DELETE", "ConfigMap %v", key)
+				updateCh.In() <- Event{
+					Type: ConfigurationEvent,
+					Obj:  obj,
+				}
+			}
+
+			// find references in ingress annotations
+			if ings := store.configMapIngressMap.Reference(key); len(ings) > 0 {
+				klog.Infof("configmap %v was deleted and it is used in ingress annotations. Parsing...", key)
+				store.syncReferencingIngresses(ings)
+				updateCh.In() <- Event{
+					Type: DeleteEvent,
+					Obj:  obj,
+				}
 			}
 		},
 	}
@@ -697,6 +807,75 @@ func (s *k8sStore) updateSecretIngressMap(ing *extensions.Ingress) {
 	s.secretIngressMap.Insert(key, refSecrets...)
 }
 
+// updateConfigMapIngressMap takes an Ingress and updates all ConfigMap
+// objects it references in configMapIngressMap.
+func (s *k8sStore) updateConfigMapIngressMap(ing *extensions.Ingress) {
+	key := k8s.MetaNamespaceKey(ing)
+	klog.V(3).Infof("updating references to configmaps for ingress %v", key)
+
+	// delete all existing references first
+	s.configMapIngressMap.Delete(key)
+
+	var refConfigMaps []string
+
+	configMapAnnotations := []string{
+		"request-validation-configmap",
+	}
+	for _, ann := range configMapAnnotations {
+		cmKey, err := objectRefAnnotationNsKey(ann, ing)
+		if err != nil && !errors.IsMissingAnnotations(err) {
+			klog.Errorf("error reading configmap reference in annotation %q: %s", ann, err)
+			continue
+		}
+		if cmKey != "" {
+			refConfigMaps = append(refConfigMaps, cmKey)
+		}
+	}
+
+	// populate map with all configmap references
+	s.configMapIngressMap.Insert(key, refConfigMaps...)
+}
+
+// updateSecretStreamMap takes the TCP services ConfigMap and updates all
+// Secret objects it references in secretStreamMap, synchronizing them with
+// the local store.
+func (s *k8sStore) updateSecretStreamMap(cm *corev1.ConfigMap) {
+	key := k8s.MetaNamespaceKey(cm)
+	klog.V(3).Infof("updating references to secrets for stream services configmap %v", key)
+
+	// delete all existing references first
+	s.secretStreamMap.Delete(key)
+
+	var refSecrets []string
+	for _, svcRef := range cm.Data {
+		ref, err := streams.ParseReference(svcRef, corev1.ProtocolTCP)
+		if err != nil {
+			continue
+		}
+		refSecrets = append(refSecrets, ref.Secrets()...)
+	}
+
+	// populate map with all secret references
+	s.secretStreamMap.Insert(key, refSecrets...)
+
+	for _, secrKey := range refSecrets {
+		s.syncSecret(secrKey)
+	}
+}
+
+// syncReferencingIngresses parses again the annotations of the Ingresses
+// referencing an object that changed
+func (s *k8sStore) syncReferencingIngresses(ings []string) {
+	for _, ingKey := range ings {
+		ing, err := s.getIngress(ingKey)
+		if err != nil {
+			klog.Errorf("could not find Ingress %v in local store", ingKey)
+			continue
+		}
+		s.syncIngress(ing)
+	}
+}
+
 // objectRefAnnotationNsKey returns an object reference formatted as a
 // 'namespace/name' key from the given annotation name.
 func objectRefAnnotationNsKey(ann string, ing *extensions.Ingress) (string, error) {
@@ -77,6 +77,7 @@ func TestStore(t *testing.T) {
 			fmt.Sprintf("%v/tcp", ns),
 			fmt.Sprintf("%v/udp", ns),
 			"",
+			"",
 			10*time.Minute,
 			clientSet,
 			fs,
@@ -159,6 +160,7 @@ func TestStore(t *testing.T) {
 			fmt.Sprintf("%v/tcp", ns),
 			fmt.Sprintf("%v/udp", ns),
 			"",
+			"",
 			10*time.Minute,
 			clientSet,
 			fs,
@@ -313,6 +315,7 @@ func TestStore(t *testing.T) {
 			fmt.Sprintf("%v/tcp", ns),
 			fmt.Sprintf("%v/udp", ns),
 			"",
+			"",
 			10*time.Minute,
 			clientSet,
 			fs,
@@ -422,6 +425,7 @@ func TestStore(t *testing.T) {
 			fmt.Sprintf("%v/tcp", ns),
 			fmt.Sprintf("%v/udp", ns),
 			"",
+			"",
 			10*time.Minute,
 			clientSet,
 			fs,
@@ -513,6 +517,7 @@ func TestStore(t *testing.T) {
 			fmt.Sprintf("%v/tcp", ns),
 			fmt.Sprintf("%v/udp", ns),
 			"",
+			"",
 			10*time.Minute,
 			clientSet,
 			fs,
@@ -627,6 +632,7 @@ func TestStore(t *testing.T) {
 			fmt.Sprintf("%v/tcp", ns),
 			fmt.Sprintf("%v/udp", ns),
 			"",
+			"",
 			10*time.Minute,
 			clientSet,
 			fs,
@@ -867,14 +873,17 @@ func newStore(t *testing.T) *k8sStore {
 			Ingress:               IngressLister{cache.NewStore(cache.MetaNamespaceKeyFunc)},
 			IngressWithAnnotation: IngressWithAnnotationsLister{cache.NewStore(cache.DeletionHandlingMetaNamespaceKeyFunc)},
 			Pod:                   PodLister{cache.NewStore(cache.MetaNamespaceKeyFunc)},
+			Secret:                SecretLister{cache.NewStore(cache.MetaNamespaceKeyFunc)},
 		},
-		sslStore:         NewSSLCertTracker(),
-		filesystem:       fs,
-		updateCh:         channels.NewRingChannel(10),
-		syncSecretMu:     new(sync.Mutex),
-		backendConfigMu:  new(sync.RWMutex),
-		secretIngressMap: NewObjectRefMap(),
-		pod:              pod,
+		sslStore:            NewSSLCertTracker(),
+		filesystem:          fs,
+		updateCh:            channels.NewRingChannel(10),
+		syncSecretMu:        new(sync.Mutex),
+		backendConfigMu:     new(sync.RWMutex),
+		secretIngressMap:    NewObjectRefMap(),
+		configMapIngressMap: NewObjectRefMap(),
+		secretStreamMap:     NewObjectRefMap(),
+		pod:                 pod,
 	}
 }
 
@@ -942,6 +951,82 @@ func TestUpdateSecretIngressMap(t *testing.T) {
 	})
 }
 
+func TestUpdateConfigMapIngressMap(t *testing.T) {
+	s := newStore(t)
+
+	ingTpl := &extensions.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "testns",
+		},
+	}
+	s.listers.Ingress.Add(ingTpl)
+
+	t.Run("with annotation in simple name format", func(t *testing.T) {
+		ing := ingTpl.DeepCopy()
+		ing.ObjectMeta.SetAnnotations(map[string]string{
+			parser.GetAnnotationWithPrefix("request-validation-configmap"): "schema",
+		})
+		s.listers.Ingress.Update(ing)
+		s.updateConfigMapIngressMap(ing)
+
+		if l := s.configMapIngressMap.Len(); !(l == 1 && s.configMapIngressMap.Has("testns/schema")) {
+			t.Errorf("Expected \"testns/schema\" to be the only referenced ConfigMap (got %d)", l)
+		}
+	})
+
+	t.Run("without annotation", func(t *testing.T) {
+		ing := ingTpl.DeepCopy()
+		s.listers.Ingress.Update(ing)
+		s.updateConfigMapIngressMap(ing)
+
+		if l := s.configMapIngressMap.Len(); l != 0 {
+			t.Errorf("Expected 0 referenced ConfigMap (got %d)", l)
+		}
+	})
+}
+
+func TestUpdateSecretStreamMap(t *testing.T) {
+	s := newStore(t)
+
+	cm := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "tcp-services",
+			Namespace: "testns",
+		},
+	}
+
+	t.Run("with TLS options", func(t *testing.T) {
+		cm.Data = map[string]string{
+			"5432": "testns/postgres:5432:tls=postgres-tls",
+			"8883": "testns/mqtt:1883:tls=certs/mqtt-tls:tls-client-ca=mqtt-ca",
+			"9000": "testns/other:9000",
+		}
+		s.updateSecretStreamMap(cm)
+
+		expected := []string{"testns/postgres-tls", "certs/mqtt-tls", "testns/mqtt-ca"}
+		if l := s.secretStreamMap.Len(); l != len(expected) {
+			t.Errorf("Expected %d referenced Secrets (got %d)", len(expected), l)
+		}
+		for _, secret := range expected {
+			if !s.secretStreamMap.Has(secret) {
+				t.Errorf("Expected %q to be referenced", secret)
+			}
+		}
+	})
+
+	t.Run("without TLS options", func(t *testing.T) {
+		cm.Data = map[string]string{
+			"5432": "testns/postgres:5432",
+		}
+		s.updateSecretStreamMap(cm)
+
+		if l := s.secretStreamMap.Len(); l != 0 {
+			t.Errorf("Expected 0 referenced Secrets (got %d)", l)
+		}
+	})
+}
+
 func TestListIngresses(t *testing.T) {
 	s := newStore(t)
 
@@ -0,0 +1,268 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package streams
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/yaml"
+
+	"k8s.io/ingress-nginx/internal/ingress"
+	"k8s.io/ingress-nginx/internal/k8s"
+	"k8s.io/ingress-nginx/internal/net"
+)
+
+// definition is the structured definition of a stream service, in YAML or JSON
+type definition struct {
+	// Service is the namespace/name key of the Service
+	Service string `json:"service"`
+	// Port is the number or the name of the port of the Service
+	Port intstr.IntOrString `json:"port"`
+	// ProxyProtocol enables the PROXY protocol in each side of the proxy
+	ProxyProtocol *ingress.ProxyProtocol `json:"proxyProtocol,omitempty"`
+	// TLS terminates TLS in the Ingress controller
+	TLS *tlsDefinition `json:"tls,omitempty"`
+	// Timeout is the timeout between two successive read or write
+	// operations, as a duration like 10m
+	Timeout string `json:"timeout,omitempty"`
+	// ConnectTimeout is the timeout to establish a connection with the
+	// Service, as a duration like 5s
+	ConnectTimeout string `json:"connectTimeout,omitempty"`
+	// AccessLog enables the access log of the stream service. Enabled by default
+	AccessLog *bool `json:"accessLog,omitempty"`
+	// LoadBalance is the load balancing algorithm used to pick an endpoint
+	LoadBalance string `json:"loadBalance,omitempty"`
+	// HashBy is the NGINX variable used as key by the consistent hashing
+	HashBy string `json:"hashBy,omitempty"`
+	// AllowedSourceRanges are the IP addresses and networks of the clients
+	// allowed to connect. All the clients are allowed when it is empty
+	AllowedSourceRanges []string `json:"allowedSourceRanges,omitempty"`
+	// DeniedSourceRanges are the IP addresses and networks of the clients
+	// not allowed to connect. They take precedence over the allowed ones
+	DeniedSourceRanges []string `json:"deniedSourceRanges,omitempty"`
+	// ConnectionLimit is the number of concurrent connections allowed by
+	// client IP address
+	ConnectionLimit int `json:"connectionLimit,omitempty"`
+}
+
+// loadBalanceAlgorithms are the load balancing algorithms supported by the
+// stream services
+var loadBalanceAlgorithms = map[string]bool{
+	"round_robin": true,
+	"ewma":        true,
+	"least_conn":  true,
+	"chash":       true,
+}
+
+// defaultHashBy is the key of the consistent hashing without hashBy, to keep
+// the connections of a client in the same endpoint
+const defaultHashBy = "$remote_addr"
+
+var hashByRegex = regexp.MustCompile(`^\$[a-zA-Z0-9_]+$`)
+
+type tlsDefinition struct {
+	// Secret is the Secret with the certificate and key
+	Secret string `json:"secret"`
+	// ClientCASecret is the Secret with the certificate authority used to
+	// verify the client certificates
+	ClientCASecret string `json:"clientCASecret,omitempty"`
+}
+
+// isDefinition returns true when the value of an entry of the stream
+// services ConfigMap is a structured definition. The string format does
+// not contain whitespaces after the separators.
+func isDefinition(value string) bool {
+	value = strings.TrimSpace(value)
+	return strings.HasPrefix(value, "{") || strings.Contains(value, ": ") || strings.Contains(value, ":\n")
+}
+
+// parseDefinition parses a structured definition of a stream service.
+// Unknown fields and options not supported by the protocol are errors.
+func parseDefinition(value string, proto apiv1.Protocol) (*Reference, error) {
+	def := &definition{}
+	err := yaml.UnmarshalStrict([]byte(value), def)
+	if err != nil {
+		return nil, fmt.Errorf("invalid stream service definition: %v", err)
+	}
+
+	if def.Service == "" {
+		return nil, fmt.Errorf("field %q is required", "service")
+	}
+	ns, _, err := k8s.ParseNameNS(def.Service)
+	if err != nil {
+		return nil, fmt.Errorf("invalid field %q: %v", "service", err)
+	}
+
+	port := def.Port.String()
+	if port == "" || port == "0" {
+		return nil, fmt.Errorf("field %q is required", "port")
+	}
+	if def.Port.Type == intstr.Int && (def.Port.IntVal < 1 || def.Port.IntVal > 65535) {
+		return nil, fmt.Errorf("invalid field %q: %v is not a valid port number", "port", def.Port.IntVal)
+	}
+
+	ref := &Reference{
+		Service: def.Service,
+		Port:    port,
+	}
+
+	if def.ProxyProtocol != nil {
+		if proto != apiv1.ProtocolTCP {
+			return nil, fmt.Errorf("field %q is only supported by TCP services", "proxyProtocol")
+		}
+		ref.ProxyProtocol = *def.ProxyProtocol
+	}
+
+	if def.TLS != nil {
+		if proto != apiv1.ProtocolTCP {
+			return nil, fmt.Errorf("field %q is only supported by TCP services", "tls")
+		}
+		if def.TLS.Secret == "" {
+			return nil, fmt.Errorf("field %q is required", "tls.secret")
+		}
+		ref.TLSSecret = secretKey(def.TLS.Secret, ns)
+		if def.TLS.ClientCASecret != "" {
+			ref.ClientCASecret = secretKey(def.TLS.ClientCASecret, ns)
+		}
+	}
+
+	ref.ProxyTimeout, err = parseTimeout("timeout", def.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	ref.ProxyConnectTimeout, err = parseTimeout("connectTimeout", def.ConnectTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	if def.AccessLog != nil {
+		ref.DisableAccessLog = !*def.AccessLog
+	}
+
+	ref.LoadBalancing, ref.UpstreamHashBy, err = parseLoadBalance(def.LoadBalance, def.HashBy)
+	if err != nil {
+		return nil, err
+	}
+
+	ref.AllowedSourceRanges, err = parseSourceRanges("allowedSourceRanges", def.AllowedSourceRanges)
+	if err != nil {
+		return nil, err
+	}
+	ref.DeniedSourceRanges, err = parseSourceRanges("deniedSourceRanges", def.DeniedSourceRanges)
+	if err != nil {
+		return nil, err
+	}
+
+	if def.ConnectionLimit < 0 {
+		return nil, fmt.Errorf("invalid field %q: %v is not a positive number", "connectionLimit", def.ConnectionLimit)
+	}
+	ref.ConnectionLimit = def.ConnectionLimit
+
+	// the stream servers do not replace the client address with the one of
+	// the PROXY protocol header, so the access control and the limits would
+	// apply to the address of the load balancer
+	if ref.ProxyProtocol.Decode {
+		if len(ref.AllowedSourceRanges) > 0 || len(ref.DeniedSourceRanges) > 0 {
+			return nil, fmt.Errorf("fields %q and %q are not supported with %q", "allowedSourceRanges", "deniedSourceRanges", "proxyProtocol.decode")
+		}
+		if ref.ConnectionLimit > 0 {
+			return nil, fmt.Errorf("field %q is not supported with %q", "connectionLimit", "proxyProtocol.decode")
+		}
+	}
+
+	return ref, nil
+}
+
+// parseTimeout returns the seconds of a duration. The duration must be
+// a positive number of seconds.
+func parseTimeout(field, value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid field %q: %v", field, err)
+	}
+	if d < time.Second || d%time.Second != 0 {
+		return 0, fmt.Errorf("invalid field %q: %v is not a positive number of seconds", field, value)
+	}
+
+	return int(d / time.Second), nil
+}
+
+// parseLoadBalance returns the load balancing algorithm and the key of the
+// consistent hashing. A hash key selects the consistent hashing.
+func parseLoadBalance(algorithm, hashBy string) (string, string, error) {
+	if algorithm != "" && !loadBalanceAlgorithms[algorithm] {
+		return "", "", fmt.Errorf("invalid field %q: %q is not a supported algorithm", "loadBalance", algorithm)
+	}
+
+	if hashBy == "" {
+		if algorithm == "chash" {
+			hashBy = defaultHashBy
+		}
+		return algorithm, hashBy, nil
+	}
+
+	if algorithm != "" && algorithm != "chash" {
+		return "", "", fmt.Errorf("field %q is only supported by the %q algorithm", "hashBy", "chash")
+	}
+	if !hashByRegex.MatchString(hashBy) {
+		return "", "", fmt.Errorf("invalid field %q: %q is not an NGINX variable", "hashBy", hashBy)
+	}
+
+	return "chash", hashBy, nil
+}
+
+// parseSourceRanges returns the sorted IP addresses and networks of a list
+func parseSourceRanges(field string, values []string) ([]string, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	ipnets, ips, err := net.ParseIPNets(values...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid field %q: %v", field, err)
+	}
+
+	cidrs := []string{}
+	for k := range ipnets {
+		cidrs = append(cidrs, k)
+	}
+	for k := range ips {
+		cidrs = append(cidrs, k)
+	}
+	sort.Strings(cidrs)
+
+	return cidrs, nil
+}
+
+// secretKey returns the namespace/name key of a Secret, using the namespace
+// of the Service when the Secret does not have one
+func secretKey(secret, ns string) string {
+	if strings.Contains(secret, "/") {
+		return secret
+	}
+	return fmt.Sprintf("%v/%v", ns, secret)
+}
@@ -0,0 +1,152 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package streams
+
+import (
+	"reflect"
+	"testing"
+
+	apiv1 "k8s.io/api/core/v1"
+
+	"k8s.io/ingress-nginx/internal/ingress"
+)
+
+func TestParseDefinition(t *testing.T) {
+	testCases := []struct {
+		value    string
+		proto    apiv1.Protocol
+		expected *Reference
+	}{
+		{
+			"service: default/postgres\nport: 5432\n",
+			apiv1.ProtocolTCP,
+			&Reference{Service: "default/postgres", Port: "5432"},
+		},
+		{
+			`{"service": "default/postgres", "port": "postgres", "timeout": "10m", "connectTimeout": "5s"}`,
+			apiv1.ProtocolTCP,
+			&Reference{Service: "default/postgres", Port: "postgres", ProxyTimeout: 600, ProxyConnectTimeout: 5},
+		},
+		{
+			`
+service: default/mqtt
+port: 1883
+proxyProtocol:
+  decode: true
+tls:
+  secret: certs/mqtt-tls
+  clientCASecret: mqtt-ca
+accessLog: false
+`,
+			apiv1.ProtocolTCP,
+			&Reference{
+				Service:          "default/mqtt",
+				Port:             "1883",
+				ProxyProtocol:    ingress.ProxyProtocol{Decode: true},
+				TLSSecret:        "certs/mqtt-tls",
+				ClientCASecret:   "default/mqtt-ca",
+				DisableAccessLog: true,
+			},
+		},
+		{
+			"service: kube-system/kube-dns\nport: 53\ntimeout: 1s\n",
+			apiv1.ProtocolUDP,
+			&Reference{Service: "kube-system/kube-dns", Port: "53", ProxyTimeout: 1},
+		},
+		{
+			"service: kube-system/kube-dns\nport: 53\nloadBalance: chash\n",
+			apiv1.ProtocolUDP,
+			&Reference{Service: "kube-system/kube-dns", Port: "53", LoadBalancing: "chash", UpstreamHashBy: "$remote_addr"},
+		},
+		{
+			"service: default/game\nport: 7777\nhashBy: $binary_remote_addr\n",
+			apiv1.ProtocolUDP,
+			&Reference{Service: "default/game", Port: "7777", LoadBalancing: "chash", UpstreamHashBy: "$binary_remote_addr"},
+		},
+		{
+			"service: default/postgres\nport: 5432\nloadBalance: least_conn\n",
+			apiv1.ProtocolTCP,
+			&Reference{Service: "default/postgres", Port: "5432", LoadBalancing: "least_conn"},
+		},
+		{
+			`
+service: default/postgres
+port: 5432
+allowedSourceRanges: [10.0.0.0/8, 192.168.1.10]
+deniedSourceRanges: [10.0.0.1/32]
+connectionLimit: 5
+`,
+			apiv1.ProtocolTCP,
+			&Reference{
+				Service:             "default/postgres",
+				Port:                "5432",
+				AllowedSourceRanges: []string{"10.0.0.0/8", "192.168.1.10"},
+				DeniedSourceRanges:  []string{"10.0.0.1/32"},
+				ConnectionLimit:     5,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		ref, err := ParseReference(tc.value, tc.proto)
+		if err != nil {
+			t.Errorf("unexpected error parsing %q: %v", tc.value, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(ref, tc.expected) {
+			t.Errorf("expected %+v but got %+v parsing %q", tc.expected, ref, tc.value)
+		}
+	}
+}
+
+func TestParseDefinitionInvalid(t *testing.T) {
+	testCases := []struct {
+		value string
+		proto apiv1.Protocol
+	}{
+		{"port: 5432", apiv1.ProtocolTCP},
+		{"service: postgres\nport: 5432", apiv1.ProtocolTCP},
+		{"service: default/postgres", apiv1.ProtocolTCP},
+		{"service: default/postgres\nport: 70000", apiv1.ProtocolTCP},
+		{"service: default/postgres\nport: 5432\nretries: 3", apiv1.ProtocolTCP},
+		{"service: default/postgres\nport: 5432\ntimeout: 10", apiv1.ProtocolTCP},
+		{"service: default/postgres\nport: 5432\nconnectTimeout: 500ms", apiv1.ProtocolTCP},
+		{"service: default/postgres\nport: 5432\ntls:\n  clientCASecret: ca", apiv1.ProtocolTCP},
+		{"service: default/postgres\nport: 5432\nproxyProtocol:\n  decode: yes please", apiv1.ProtocolTCP},
+		{"service: kube-system/kube-dns\nport: 53\nproxyProtocol:\n  encode: true", apiv1.ProtocolUDP},
+		{"service: kube-system/kube-dns\nport: 53\ntls:\n  secret: dns-tls", apiv1.ProtocolUDP},
+		{`{"service": "default/postgres", "port": 5432`, apiv1.ProtocolTCP},
+		{"service: default/postgres\nport: 5432\nloadBalance: random", apiv1.ProtocolTCP},
+		{"service: default/postgres\nport: 5432\nloadBalance: ewma\nhashBy: $remote_addr", apiv1.ProtocolTCP},
+		{"service: default/postgres\nport: 5432\nhashBy: remote_addr", apiv1.ProtocolTCP},
+		{"service: default/postgres\nport: 5432\nhashBy: $remote_addr$server_port", apiv1.ProtocolTCP},
+		{"service: default/postgres\nport: 5432\nallowedSourceRanges: [10.0.0.0/33]", apiv1.ProtocolTCP},
+		{"service: default/postgres\nport: 5432\ndeniedSourceRanges: [example.com]", apiv1.ProtocolTCP},
+		{"service: default/postgres\nport: 5432\nconnectionLimit: -1", apiv1.ProtocolTCP},
+		{"service: default/postgres\nport: 5432\nproxyProtocol:\n  decode: true\nallowedSourceRanges: [10.0.0.0/8]", apiv1.ProtocolTCP},
+		{"service: default/postgres\nport: 5432\nproxyProtocol:\n  decode: true\ndeniedSourceRanges: [10.0.0.1]", apiv1.ProtocolTCP},
+		{"service: default/postgres\nport: 5432\nproxyProtocol:\n  decode: true\nconnectionLimit: 5", apiv1.ProtocolTCP},
+	}
+
+	for _, tc := range testCases {
+		_, err := ParseReference(tc.value, tc.proto)
+		if err == nil {
+			t.Errorf("expected error parsing %q", tc.value)
+		}
+	}
+}
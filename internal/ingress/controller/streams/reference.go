@@ -0,0 +1,193 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package streams
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"k8s.io/ingress-nginx/internal/ingress"
+	"k8s.io/ingress-nginx/internal/k8s"
+)
+
+// Reference is a stream service defined in the TCP or UDP services ConfigMap
+type Reference struct {
+	// Service is the namespace/name key of the Service
+	Service string
+	// Port is the number or the name of the port of the Service
+	Port          string
+	ProxyProtocol ingress.ProxyProtocol
+	// TLSSecret is the namespace/name key of the Secret with the
+	// certificate used to terminate TLS
+	TLSSecret string
+	// ClientCASecret is the namespace/name key of the Secret with the
+	// certificate authority used to verify the client certificates
+	ClientCASecret string
+	// ProxyTimeout is the timeout between two successive read or write
+	// operations, in seconds
+	ProxyTimeout int
+	// ProxyConnectTimeout is the timeout to establish a connection with
+	// the Service, in seconds
+	ProxyConnectTimeout int
+	// DisableAccessLog disables the access log of the stream service
+	DisableAccessLog bool
+	// LoadBalancing is the load balancing algorithm of the endpoints
+	LoadBalancing string
+	// UpstreamHashBy is the NGINX variable used as key by the consistent
+	// hashing
+	UpstreamHashBy string
+	// AllowedSourceRanges are the IP addresses and networks of the clients
+	// allowed to connect
+	AllowedSourceRanges []string
+	// DeniedSourceRanges are the IP addresses and networks of the clients
+	// not allowed to connect
+	DeniedSourceRanges []string
+	// ConnectionLimit is the number of concurrent connections allowed by
+	// client IP address
+	ConnectionLimit int
+}
+
+// Secrets returns the keys of the Secrets used by the stream service
+func (r *Reference) Secrets() []string {
+	var secrets []string
+	if r.TLSSecret != "" {
+		secrets = append(secrets, r.TLSSecret)
+	}
+	if r.ClientCASecret != "" {
+		secrets = append(secrets, r.ClientCASecret)
+	}
+	return secrets
+}
+
+// HasServerSettings returns true when the stream service uses settings of
+// the NGINX server of its port. Only the Service and the load balancing of
+// the endpoints can be changed without a reload
+func (r *Reference) HasServerSettings() bool {
+	return r.ProxyProtocol != (ingress.ProxyProtocol{}) ||
+		len(r.Secrets()) > 0 ||
+		r.ProxyTimeout > 0 ||
+		r.ProxyConnectTimeout > 0 ||
+		r.DisableAccessLog ||
+		len(r.AllowedSourceRanges) > 0 ||
+		len(r.DeniedSourceRanges) > 0 ||
+		r.ConnectionLimit > 0
+}
+
+// ParseReference parses the value of an entry of the TCP or UDP services
+// ConfigMap, either a structured definition in YAML or JSON or a string with
+// the format:
+//
+// <namespace>/<service>:<port>[:<"PROXY" decode>[:<"PROXY" encode>]][:tls=<secret>[:tls-client-ca=<secret>]]
+//
+// The Secrets without a namespace are in the namespace of the Service.
+func ParseReference(value string, proto apiv1.Protocol) (*Reference, error) {
+	if isDefinition(value) {
+		return parseDefinition(value, proto)
+	}
+
+	fields := strings.Split(value, ":")
+	if len(fields) < 2 {
+		return nil, fmt.Errorf("invalid Service reference %q", value)
+	}
+
+	ns, _, err := k8s.ParseNameNS(fields[0])
+	if err != nil {
+		return nil, err
+	}
+
+	ref := &Reference{
+		Service: fields[0],
+		Port:    fields[1],
+	}
+
+	for i, field := range fields[2:] {
+		option := strings.SplitN(field, "=", 2)
+		if len(option) == 1 {
+			// Proxy Protocol is only compatible with TCP Services
+			if proto != apiv1.ProtocolTCP || strings.ToUpper(field) != "PROXY" {
+				continue
+			}
+
+			switch i {
+			case 0:
+				ref.ProxyProtocol.Decode = true
+			case 1:
+				ref.ProxyProtocol.Encode = true
+			}
+			continue
+		}
+
+		if proto != apiv1.ProtocolTCP {
+			return nil, fmt.Errorf("option %q is only supported by TCP services", option[0])
+		}
+
+		if option[1] == "" {
+			return nil, fmt.Errorf("option %q requires a Secret", option[0])
+		}
+		secret := secretKey(option[1], ns)
+
+		switch option[0] {
+		case "tls":
+			ref.TLSSecret = secret
+		case "tls-client-ca":
+			ref.ClientCASecret = secret
+		default:
+			return nil, fmt.Errorf("unknown option %q", option[0])
+		}
+	}
+
+	if ref.ClientCASecret != "" && ref.TLSSecret == "" {
+		return nil, fmt.Errorf("option %q requires the option %q", "tls-client-ca", "tls")
+	}
+
+	return ref, nil
+}
+
+// ParseKey parses the key of an entry of the TCP or UDP services ConfigMap,
+// with the format:
+//
+// <port>[.<hostname>]
+//
+// The hostname is used to route the TLS connections of a port shared by
+// several TCP services using the Server Name Indication (SNI).
+func ParseKey(key string, proto apiv1.Protocol) (int, string, error) {
+	fields := strings.SplitN(key, ".", 2)
+
+	port, err := strconv.Atoi(fields[0])
+	if err != nil || port <= 0 || port > 65535 {
+		return 0, "", fmt.Errorf("%q is not a valid port number", fields[0])
+	}
+
+	if len(fields) == 1 {
+		return port, "", nil
+	}
+
+	if proto != apiv1.ProtocolTCP {
+		return 0, "", fmt.Errorf("hostname routing is only supported by TCP services")
+	}
+
+	hostname := strings.ToLower(fields[1])
+	if errs := validation.IsDNS1123Subdomain(hostname); len(errs) > 0 {
+		return 0, "", fmt.Errorf("%q is not a valid hostname: %v", fields[1], strings.Join(errs, ", "))
+	}
+
+	return port, hostname, nil
+}
@@ -0,0 +1,187 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package streams
+
+import (
+	"reflect"
+	"testing"
+
+	apiv1 "k8s.io/api/core/v1"
+
+	"k8s.io/ingress-nginx/internal/ingress"
+)
+
+func TestParseReference(t *testing.T) {
+	testCases := []struct {
+		value    string
+		proto    apiv1.Protocol
+		expected *Reference
+	}{
+		{
+			"default/postgres:5432",
+			apiv1.ProtocolTCP,
+			&Reference{Service: "default/postgres", Port: "5432"},
+		},
+		{
+			"default/postgres:postgres:PROXY:proxy",
+			apiv1.ProtocolTCP,
+			&Reference{Service: "default/postgres", Port: "postgres", ProxyProtocol: ingress.ProxyProtocol{Decode: true, Encode: true}},
+		},
+		{
+			"default/postgres:5432::PROXY",
+			apiv1.ProtocolTCP,
+			&Reference{Service: "default/postgres", Port: "5432", ProxyProtocol: ingress.ProxyProtocol{Encode: true}},
+		},
+		{
+			"default/dns:53:PROXY",
+			apiv1.ProtocolUDP,
+			&Reference{Service: "default/dns", Port: "53"},
+		},
+		{
+			"default/mqtt:1883:tls=mqtt-tls",
+			apiv1.ProtocolTCP,
+			&Reference{Service: "default/mqtt", Port: "1883", TLSSecret: "default/mqtt-tls"},
+		},
+		{
+			"default/mqtt:1883:PROXY:tls=certs/mqtt-tls:tls-client-ca=mqtt-ca",
+			apiv1.ProtocolTCP,
+			&Reference{
+				Service:        "default/mqtt",
+				Port:           "1883",
+				ProxyProtocol:  ingress.ProxyProtocol{Decode: true},
+				TLSSecret:      "certs/mqtt-tls",
+				ClientCASecret: "default/mqtt-ca",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		ref, err := ParseReference(tc.value, tc.proto)
+		if err != nil {
+			t.Errorf("unexpected error parsing %q: %v", tc.value, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(ref, tc.expected) {
+			t.Errorf("expected %+v but got %+v parsing %q", tc.expected, ref, tc.value)
+		}
+	}
+}
+
+func TestParseReferenceInvalid(t *testing.T) {
+	testCases := []struct {
+		value string
+		proto apiv1.Protocol
+	}{
+		{"default/postgres", apiv1.ProtocolTCP},
+		{"postgres:5432", apiv1.ProtocolTCP},
+		{"default/postgres:5432:tls=", apiv1.ProtocolTCP},
+		{"default/postgres:5432:cert=postgres-tls", apiv1.ProtocolTCP},
+		{"default/postgres:5432:tls-client-ca=postgres-ca", apiv1.ProtocolTCP},
+		{"default/dns:53:tls=dns-tls", apiv1.ProtocolUDP},
+	}
+
+	for _, tc := range testCases {
+		_, err := ParseReference(tc.value, tc.proto)
+		if err == nil {
+			t.Errorf("expected error parsing %q", tc.value)
+		}
+	}
+}
+
+func TestSecrets(t *testing.T) {
+	ref := &Reference{TLSSecret: "default/tls", ClientCASecret: "default/ca"}
+
+	expected := []string{"default/tls", "default/ca"}
+	if secrets := ref.Secrets(); !reflect.DeepEqual(secrets, expected) {
+		t.Errorf("expected %v but got %v", expected, secrets)
+	}
+}
+
+func TestHasServerSettings(t *testing.T) {
+	testCases := []struct {
+		value    string
+		expected bool
+	}{
+		{"default/echo:8080", false},
+		{"{service: default/echo, port: 8080, loadBalance: ewma}", false},
+		{"{service: default/echo, port: 8080, hashBy: $remote_addr}", false},
+		{"default/echo:8080:PROXY", true},
+		{"default/echo:8080:tls=default/tls", true},
+		{"{service: default/echo, port: 8080, timeout: 30s}", true},
+		{"{service: default/echo, port: 8080, accessLog: false}", true},
+		{"{service: default/echo, port: 8080, allowedSourceRanges: [10.0.0.0/8]}", true},
+		{"{service: default/echo, port: 8080, connectionLimit: 10}", true},
+	}
+
+	for _, tc := range testCases {
+		ref, err := ParseReference(tc.value, apiv1.ProtocolTCP)
+		if err != nil {
+			t.Fatalf("unexpected error parsing %q: %v", tc.value, err)
+		}
+		if ref.HasServerSettings() != tc.expected {
+			t.Errorf("expected HasServerSettings of %q to be %v", tc.value, tc.expected)
+		}
+	}
+}
+
+func TestParseKey(t *testing.T) {
+	testCases := []struct {
+		key      string
+		proto    apiv1.Protocol
+		port     int
+		hostname string
+	}{
+		{"5432", apiv1.ProtocolTCP, 5432, ""},
+		{"53", apiv1.ProtocolUDP, 53, ""},
+		{"5432.tenant-a.db.example.com", apiv1.ProtocolTCP, 5432, "tenant-a.db.example.com"},
+		{"443.Tenant-B.example.com", apiv1.ProtocolTCP, 443, "tenant-b.example.com"},
+	}
+
+	for _, tc := range testCases {
+		port, hostname, err := ParseKey(tc.key, tc.proto)
+		if err != nil {
+			t.Errorf("unexpected error parsing %q: %v", tc.key, err)
+			continue
+		}
+
+		if port != tc.port || hostname != tc.hostname {
+			t.Errorf("expected %v and %q but got %v and %q parsing %q", tc.port, tc.hostname, port, hostname, tc.key)
+		}
+	}
+}
+
+func TestParseKeyInvalid(t *testing.T) {
+	testCases := []struct {
+		key   string
+		proto apiv1.Protocol
+	}{
+		{"postgres", apiv1.ProtocolTCP},
+		{"0", apiv1.ProtocolTCP},
+		{"70000", apiv1.ProtocolTCP},
+		{"5432.", apiv1.ProtocolTCP},
+		{"5432.tenant_a.example.com", apiv1.ProtocolTCP},
+		{"53.dns.example.com", apiv1.ProtocolUDP},
+	}
+
+	for _, tc := range testCases {
+		_, _, err := ParseKey(tc.key, tc.proto)
+		if err == nil {
+			t.Errorf("expected error parsing %q", tc.key)
+		}
+	}
+}
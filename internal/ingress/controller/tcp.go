@@ -46,10 +46,21 @@ func (p *TCPProxy) Get(host string) *TCPServer {
 		return p.Default
 	}
 
+	// exact server names take precedence over the longest wildcard name
+	var wildcard *TCPServer
 	for _, s := range p.ServerList {
 		if s.Hostname == host {
 			return s
 		}
+
+		if isWildcardHost(s.Hostname) && matchServerName(s.Hostname, host) &&
+			(wildcard == nil || len(s.Hostname) > len(wildcard.Hostname)) {
+			wildcard = s
+		}
+	}
+
+	if wildcard != nil {
+		return wildcard
 	}
 
 	return p.Default
@@ -0,0 +1,50 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+)
+
+func TestTCPProxyGet(t *testing.T) {
+	proxy := &TCPProxy{
+		ServerList: []*TCPServer{
+			{Hostname: "*.example.com", Port: 1},
+			{Hostname: "api.example.com", Port: 2},
+			{Hostname: "*.api.example.com", Port: 3},
+		},
+		Default: &TCPServer{Hostname: "localhost", Port: 0},
+	}
+
+	testCases := []struct {
+		host string
+		port int
+	}{
+		{"api.example.com", 2},
+		{"www.example.com", 1},
+		{"foo.bar.example.com", 1},
+		{"v1.api.example.com", 3},
+		{"example.com", 0},
+		{"www.example.org", 0},
+	}
+
+	for _, tc := range testCases {
+		if port := proxy.Get(tc.host).Port; port != tc.port {
+			t.Errorf("expected host %v to use the server with port %v but got %v", tc.host, tc.port, port)
+		}
+	}
+}
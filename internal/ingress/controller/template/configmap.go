@@ -19,6 +19,7 @@ package template
 import (
 	"fmt"
 	"net"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -29,6 +30,7 @@ import (
 	"github.com/mitchellh/mapstructure"
 
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/proxycache"
 	"k8s.io/ingress-nginx/internal/ingress/controller/config"
 	ing_net "k8s.io/ingress-nginx/internal/net"
 	"k8s.io/ingress-nginx/internal/runtime"
@@ -49,11 +51,15 @@ const (
 	nginxStatusIpv4Whitelist = "nginx-status-ipv4-whitelist"
 	nginxStatusIpv6Whitelist = "nginx-status-ipv6-whitelist"
 	proxyHeaderTimeout       = "proxy-protocol-header-timeout"
+	http3AltSvcPort          = "http3-alt-svc-port"
 	workerProcesses          = "worker-processes"
+	proxyCacheZones          = "proxy-cache-zones"
 )
 
 var (
 	validRedirectCodes = sets.NewInt([]int{301, 302, 307, 308}...)
+
+	cacheSizeRegex = regexp.MustCompile(`^[0-9]+[kKmMgG]?$`)
 )
 
 // ReadConfig obtains the configuration defined by the user merged with the defaults.
@@ -161,6 +167,16 @@ func ReadConfig(src map[string]string) config.Configuration {
 		}
 	}
 
+	if val, ok := conf[http3AltSvcPort]; ok {
+		delete(conf, http3AltSvcPort)
+		port, err := strconv.Atoi(val)
+		if err != nil || port < 1 || port > 65535 {
+			klog.Warningf("http3-alt-svc-port of %v is not a valid port. Advertising the HTTP/3 listen port instead.", val)
+		} else {
+			to.HTTP3AltSvcPort = port
+		}
+	}
+
 	streamResponses := 1
 	if val, ok := conf[proxyStreamResponses]; ok {
 		delete(conf, proxyStreamResponses)
@@ -198,6 +214,11 @@ func ReadConfig(src map[string]string) config.Configuration {
 		delete(conf, workerProcesses)
 	}
 
+	if val, ok := conf[proxyCacheZones]; ok {
+		delete(conf, proxyCacheZones)
+		to.ProxyCacheZones = parseProxyCacheZones(val)
+	}
+
 	to.CustomHTTPErrors = filterErrors(errors)
 	to.SkipAccessLogURLs = skipUrls
 	to.WhitelistSourceRange = whiteList
@@ -251,3 +272,52 @@ func filterErrors(codes []int) []int {
 
 	return fa
 }
+
+// parseProxyCacheZones parses a comma separated list of cache zones with the
+// format name:keys_zone_size[:max_size[:inactive]], like "static:10m:1g:60m"
+func parseProxyCacheZones(val string) []config.ProxyCacheZone {
+	var zones []config.ProxyCacheZone
+	names := sets.NewString()
+
+	for _, entry := range strings.Split(val, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		fields := strings.Split(entry, ":")
+		if len(fields) < 2 || len(fields) > 4 {
+			klog.Warningf("%v is not a valid cache zone (name:keys_zone_size[:max_size[:inactive]])", entry)
+			continue
+		}
+
+		zone := config.ProxyCacheZone{
+			Name:         fields[0],
+			KeysZoneSize: fields[1],
+		}
+		if len(fields) > 2 {
+			zone.MaxSize = fields[2]
+		}
+		if len(fields) > 3 {
+			zone.Inactive = fields[3]
+		}
+
+		if !proxycache.ZoneNameRegex.MatchString(zone.Name) || names.Has(zone.Name) {
+			klog.Warningf("%v is not a valid cache zone: invalid or duplicated name", entry)
+			continue
+		}
+		if !cacheSizeRegex.MatchString(zone.KeysZoneSize) || (zone.MaxSize != "" && !cacheSizeRegex.MatchString(zone.MaxSize)) {
+			klog.Warningf("%v is not a valid cache zone: invalid size", entry)
+			continue
+		}
+		if zone.Inactive != "" && !proxycache.TimeRegex.MatchString(zone.Inactive) {
+			klog.Warningf("%v is not a valid cache zone: invalid inactive time", entry)
+			continue
+		}
+
+		names.Insert(zone.Name)
+		zones = append(zones, zone)
+	}
+
+	return zones
+}
@@ -51,6 +51,23 @@ func TestProxyTimeoutParsing(t *testing.T) {
 	}
 }
 
+func TestHTTP3AltSvcPortParsing(t *testing.T) {
+	testCases := map[string]struct {
+		input  string
+		expect int
+	}{
+		"valid port":        {"443", 443},
+		"invalid port":      {"https", 0},
+		"out of range port": {"65536", 0},
+	}
+	for n, tc := range testCases {
+		cfg := ReadConfig(map[string]string{"http3-alt-svc-port": tc.input})
+		if cfg.HTTP3AltSvcPort != tc.expect {
+			t.Errorf("Testing %v. Expected %v but got %v", n, tc.expect, cfg.HTTP3AltSvcPort)
+		}
+	}
+}
+
 func TestMergeConfigMapToStruct(t *testing.T) {
 	conf := map[string]string{
 		"custom-http-errors":            "300,400,demo",
@@ -153,3 +170,18 @@ func TestMergeConfigMapToStruct(t *testing.T) {
 		t.Errorf("unexpected diff: (-got +want)\n%s", diff)
 	}
 }
+
+func TestProxyCacheZonesParsing(t *testing.T) {
+	cfg := ReadConfig(map[string]string{
+		"proxy-cache-zones": "static:10m:1g:60m, api:5m,invalid name:1m,static:1m,bad-size:10x,bad-time:1m:1g:soon,short",
+	})
+
+	expected := []config.ProxyCacheZone{
+		{Name: "static", KeysZoneSize: "10m", MaxSize: "1g", Inactive: "60m"},
+		{Name: "api", KeysZoneSize: "5m"},
+	}
+
+	if !reflect.DeepEqual(cfg.ProxyCacheZones, expected) {
+		t.Errorf("expected cache zones %v but got %v", expected, cfg.ProxyCacheZones)
+	}
+}
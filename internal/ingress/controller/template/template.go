@@ -29,6 +29,7 @@ import (
 	"reflect"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 	text_template "text/template"
 	"time"
@@ -38,8 +39,12 @@ import (
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/ingress-nginx/internal/file"
 	"k8s.io/ingress-nginx/internal/ingress"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/headers"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/influxdb"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/pathtype"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/ratelimit"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/requestvalidation"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/routing"
 	"k8s.io/ingress-nginx/internal/ingress/controller/config"
 	ing_net "k8s.io/ingress-nginx/internal/net"
 	"k8s.io/klog"
@@ -125,6 +130,7 @@ var (
 		"shouldConfigureLuaRestyWAF": shouldConfigureLuaRestyWAF,
 		"buildLuaSharedDictionaries": buildLuaSharedDictionaries,
 		"buildLocation":              buildLocation,
+		"buildLocationPaths":         buildLocationPaths,
 		"buildAuthLocation":          buildAuthLocation,
 		"buildAuthResponseHeaders":   buildAuthResponseHeaders,
 		"buildProxyPass":             buildProxyPass,
@@ -162,6 +168,15 @@ var (
 		"buildCustomErrorDeps":               buildCustomErrorDeps,
 		"opentracingPropagateContext":        opentracingPropagateContext,
 		"buildCustomErrorLocationsPerServer": buildCustomErrorLocationsPerServer,
+		"buildHeaderOperationsForLua":        buildHeaderOperationsForLua,
+		"buildRoutingForLua":                 buildRoutingForLua,
+		"buildProxyCache":                    buildProxyCache,
+		"buildRequestValidationForLua":       buildRequestValidationForLua,
+		"buildRetryPolicyForLua":             buildRetryPolicyForLua,
+		"buildSNIStreamServers":              buildSNIStreamServers,
+		"buildSNIRoutesForLua":               buildSNIRoutesForLua,
+		"hasStreamConnectionLimits":          hasStreamConnectionLimits,
+		"buildStreamServiceForLua":           buildStreamServiceForLua,
 	}
 )
 
@@ -201,7 +216,7 @@ func shouldConfigureLuaRestyWAF(disableLuaRestyWAF bool, mode string) bool {
 	return false
 }
 
-func buildLuaSharedDictionaries(s interface{}, disableLuaRestyWAF bool) string {
+func buildLuaSharedDictionaries(s interface{}, disableLuaRestyWAF, enableZoneMetrics bool) string {
 	servers, ok := s.([]*ingress.Server)
 	if !ok {
 		klog.Errorf("expected an '[]*ingress.Server' type but %T was returned", s)
@@ -211,6 +226,8 @@ func buildLuaSharedDictionaries(s interface{}, disableLuaRestyWAF bool) string {
 	out := []string{
 		"lua_shared_dict configuration_data 5M",
 		"lua_shared_dict certificate_data 16M",
+		"lua_shared_dict balancer_concurrency 1M",
+		"lua_shared_dict monitor_pending 5M",
 	}
 
 	if !disableLuaRestyWAF {
@@ -229,9 +246,268 @@ func buildLuaSharedDictionaries(s interface{}, disableLuaRestyWAF bool) string {
 		}
 	}
 
+	if enableZoneMetrics {
+		out = append(out, "lua_shared_dict zone_status_data 10M")
+	}
+
 	return strings.Join(out, ";\n\r") + ";"
 }
 
+// buildHeaderOperationsForLua returns the changes of the headers as a Lua
+// table, used by the headers Lua module
+func buildHeaderOperationsForLua(input interface{}) string {
+	ops, ok := input.(headers.Operations)
+	if !ok {
+		klog.Errorf("expected a 'headers.Operations' type but %T was returned", input)
+		return "{}"
+	}
+
+	luaHeaders := func(hs []headers.Header) string {
+		out := []string{}
+		for _, h := range hs {
+			out = append(out, fmt.Sprintf("{ %v, %v }", strconv.Quote(h.Name), strconv.Quote(h.Value)))
+		}
+		return strings.Join(out, ", ")
+	}
+
+	remove := []string{}
+	for _, name := range ops.Remove {
+		remove = append(remove, strconv.Quote(name))
+	}
+
+	return fmt.Sprintf("{ remove = { %v }, set = { %v }, add = { %v } }",
+		strings.Join(remove, ", "), luaHeaders(ops.Set), luaHeaders(ops.Add))
+}
+
+// buildRoutingForLua returns the allowed methods and the routes of a
+// location as a Lua table, used by the router Lua module
+func buildRoutingForLua(input interface{}) string {
+	cfg, ok := input.(routing.Config)
+	if !ok {
+		klog.Errorf("expected a 'routing.Config' type but %T was returned", input)
+		return "{}"
+	}
+
+	methods := []string{}
+	for _, method := range cfg.AllowedMethods {
+		methods = append(methods, strconv.Quote(method))
+	}
+
+	routes := []string{}
+	for _, route := range cfg.Routes {
+		fields := []string{}
+		if route.Method != "" {
+			fields = append(fields, fmt.Sprintf("method = %v", strconv.Quote(route.Method)))
+		}
+
+		matches := []string{}
+		for _, h := range route.Headers {
+			matches = append(matches, fmt.Sprintf("{ name = %v, value = %v, type = %v }",
+				strconv.Quote(h.Name), strconv.Quote(h.Value), strconv.Quote(h.Type)))
+		}
+		if route.GRPCService != "" {
+			fields = append(fields, fmt.Sprintf("grpc_service = %v", strconv.Quote(route.GRPCService)))
+		}
+		if route.GRPCMethod != "" {
+			fields = append(fields, fmt.Sprintf("grpc_method = %v", strconv.Quote(route.GRPCMethod)))
+		}
+		fields = append(fields,
+			fmt.Sprintf("headers = { %v }", strings.Join(matches, ", ")),
+			fmt.Sprintf("backend = %v", strconv.Quote(route.Backend)),
+			fmt.Sprintf("service = %v", strconv.Quote(route.ServiceName)))
+
+		routes = append(routes, fmt.Sprintf("{ %v }", strings.Join(fields, ", ")))
+	}
+
+	return fmt.Sprintf("{ methods = { %v }, routes = { %v } }",
+		strings.Join(methods, ", "), strings.Join(routes, ", "))
+}
+
+// buildRequestValidationForLua returns the validation of the requests of a
+// location as a Lua table, used by the request_validation Lua module
+func buildRequestValidationForLua(input interface{}) string {
+	cfg, ok := input.(requestvalidation.Config)
+	if !ok {
+		klog.Errorf("expected a 'requestvalidation.Config' type but %T was returned", input)
+		return "{}"
+	}
+
+	contentTypes := []string{}
+	for _, contentType := range cfg.ContentTypes {
+		contentTypes = append(contentTypes, luaString(contentType))
+	}
+
+	fields := []string{
+		fmt.Sprintf("content_types = { %v }", strings.Join(contentTypes, ", ")),
+		fmt.Sprintf("max_body_size = %v", cfg.MaxBodySize),
+		fmt.Sprintf("status = %v", cfg.Status),
+	}
+	if cfg.Schema != "" {
+		fields = append(fields, fmt.Sprintf("schema = %v", luaString(cfg.Schema)))
+	}
+
+	return fmt.Sprintf("{ %v }", strings.Join(fields, ", "))
+}
+
+// luaString returns a Lua string literal. Unlike strconv.Quote, which uses
+// the \xXX and \uXXXX escapes not supported by Lua 5.1, the bytes that are
+// not printable ASCII characters are written as decimal escapes, so the
+// string keeps its UTF-8 bytes.
+func luaString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < ' ' || c > '~':
+			fmt.Fprintf(&b, "\\%03d", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('"')
+
+	return b.String()
+}
+
+// buildRetryPolicyForLua returns the retry policy of a location as a Lua
+// table, used by the balancer to limit the timeout of each try and the
+// number of retries
+func buildRetryPolicyForLua(loc interface{}) string {
+	location, ok := loc.(*ingress.Location)
+	if !ok {
+		klog.Errorf("expected a '*ingress.Location' type but %T was returned", loc)
+		return "{}"
+	}
+
+	policy := location.RetryPolicy
+
+	fields := []string{}
+	if policy.PerTryTimeout > 0 {
+		// the connect timeout of the location is kept when it is lower
+		connectTimeout := policy.PerTryTimeout
+		if location.Proxy.ConnectTimeout > 0 && location.Proxy.ConnectTimeout < connectTimeout {
+			connectTimeout = location.Proxy.ConnectTimeout
+		}
+
+		fields = append(fields,
+			fmt.Sprintf("connect_timeout = %v", connectTimeout),
+			fmt.Sprintf("per_try_timeout = %v", policy.PerTryTimeout))
+	}
+	if policy.Budget > 0 {
+		fields = append(fields, fmt.Sprintf("budget = %v", policy.Budget))
+	}
+
+	if len(fields) == 0 {
+		return "{}"
+	}
+
+	return fmt.Sprintf("{ %v }", strings.Join(fields, ", "))
+}
+
+// sniStreamServer is a port shared by the TCP services routed by hostname.
+// The settings of the server are the ones of the first service.
+type sniStreamServer struct {
+	ingress.L4Service
+	Services []ingress.L4Service
+}
+
+// buildSNIStreamServers groups the TCP services routed by hostname by port.
+// The services are sorted by port and all the services of a port use the
+// same server settings.
+func buildSNIStreamServers(input interface{}) []sniStreamServer {
+	svcs, ok := input.([]ingress.L4Service)
+	if !ok {
+		klog.Errorf("expected a '[]ingress.L4Service' type but %T was returned", input)
+		return []sniStreamServer{}
+	}
+
+	servers := []sniStreamServer{}
+	for _, svc := range svcs {
+		if svc.Hostname == "" {
+			continue
+		}
+
+		last := len(servers) - 1
+		if last >= 0 && servers[last].Port == svc.Port {
+			servers[last].Services = append(servers[last].Services, svc)
+			continue
+		}
+
+		servers = append(servers, sniStreamServer{
+			L4Service: svc,
+			Services:  []ingress.L4Service{svc},
+		})
+	}
+
+	return servers
+}
+
+// buildSNIRoutesForLua returns the upstream names, namespaces and names of
+// the TCP services of a port indexed by hostname as a Lua table
+func buildSNIRoutesForLua(input interface{}) string {
+	svcs, ok := input.([]ingress.L4Service)
+	if !ok {
+		klog.Errorf("expected a '[]ingress.L4Service' type but %T was returned", input)
+		return "{}"
+	}
+
+	routes := []string{}
+	for _, svc := range svcs {
+		upstreamName := fmt.Sprintf("tcp-%v-%v-%v", svc.Backend.Namespace, svc.Backend.Name, svc.Backend.Port.String())
+		routes = append(routes, fmt.Sprintf("[%v] = { upstream = %v, namespace = %v, service = %v }",
+			strconv.Quote(svc.Hostname), strconv.Quote(upstreamName),
+			strconv.Quote(svc.Backend.Namespace), strconv.Quote(svc.Backend.Name)))
+	}
+
+	return fmt.Sprintf("{ %v }", strings.Join(routes, ", "))
+}
+
+// hasStreamConnectionLimits returns true when a TCP or UDP service limits
+// the connections by client
+func hasStreamConnectionLimits(tcp, udp interface{}) bool {
+	for _, input := range []interface{}{tcp, udp} {
+		svcs, ok := input.([]ingress.L4Service)
+		if !ok {
+			klog.Errorf("expected a '[]ingress.L4Service' type but %T was returned", input)
+			continue
+		}
+
+		for _, svc := range svcs {
+			if svc.ConnectionLimit > 0 {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// buildStreamServiceForLua returns the protocol, namespace, name and external
+// port of a TCP or UDP service as a Lua table, used by the tcp_udp_monitor Lua
+// module. The services routed by hostname only contain the protocol and port
+func buildStreamServiceForLua(input interface{}) string {
+	svc, ok := input.(ingress.L4Service)
+	if !ok {
+		klog.Errorf("expected an 'ingress.L4Service' type but %T was returned", input)
+		return "{}"
+	}
+
+	fields := []string{
+		fmt.Sprintf("protocol = %v", strconv.Quote(strings.ToLower(string(svc.Backend.Protocol)))),
+	}
+	if svc.Hostname == "" {
+		fields = append(fields,
+			fmt.Sprintf("namespace = %v", strconv.Quote(svc.Backend.Namespace)),
+			fmt.Sprintf("service = %v", strconv.Quote(svc.Backend.Name)))
+	}
+	fields = append(fields, fmt.Sprintf("port = %v", svc.Port))
+
+	return fmt.Sprintf("{ %v }", strings.Join(fields, ", "))
+}
+
 func buildResolversForLua(res interface{}, disableIpv6 interface{}) string {
 	nss, ok := res.([]net.IP)
 	if !ok {
@@ -313,7 +589,8 @@ func stripLocationModifer(path string) string {
 }
 
 // enforceRegexModifier checks if the "rewrite-target" or "use-regex" annotation
-// is used on any location path within a server
+// is used on any location path within a server. Paths of type Prefix and
+// Exact are never regular expressions and do not change the other locations.
 func enforceRegexModifier(input interface{}) bool {
 	locations, ok := input.([]*ingress.Location)
 	if !ok {
@@ -330,7 +607,10 @@ func enforceRegexModifier(input interface{}) bool {
 }
 
 // buildLocation produces the location string, if the ingress has redirects
-// (specified through the nginx.ingress.kubernetes.io/rewrite-target annotation)
+// (specified through the nginx.ingress.kubernetes.io/rewrite-target annotation).
+// Exact paths are never regular expressions. A Prefix path is written as
+// several location blocks (see buildLocationPaths), so the regular expression
+// matching its requests is returned to identify the location.
 func buildLocation(input interface{}, enforceRegex bool) string {
 	location, ok := input.(*ingress.Location)
 	if !ok {
@@ -339,12 +619,71 @@ func buildLocation(input interface{}, enforceRegex bool) string {
 	}
 
 	path := location.Path
+	switch location.PathType {
+	case pathtype.Exact:
+		return fmt.Sprintf(`= %s`, path)
+	case pathtype.Prefix:
+		prefix := strings.TrimSuffix(path, slash)
+		if prefix == "" {
+			return fmt.Sprintf(`~ "^%s"`, slash)
+		}
+		return fmt.Sprintf(`~ "^%s(/|$)"`, regexp.QuoteMeta(prefix))
+	}
+
 	if enforceRegex {
 		return fmt.Sprintf(`~* "^%s"`, path)
 	}
 	return path
 }
 
+// buildLocationPaths returns the paths of the location blocks of a location.
+// A Prefix path /foo is written as the prefix location /foo/ and the exact
+// location = /foo, so it follows the longest prefix match of NGINX instead of
+// the order of the regular expressions. The location blocks also written by
+// a location whose path type takes precedence are omitted.
+func buildLocationPaths(input interface{}, all interface{}, enforceRegex bool) []string {
+	location, ok := input.(*ingress.Location)
+	if !ok {
+		klog.Errorf("expected an '*ingress.Location' type but %T was returned", input)
+		return []string{}
+	}
+
+	locations, ok := all.([]*ingress.Location)
+	if !ok {
+		klog.Errorf("expected an '[]*ingress.Location' type but %T was returned", all)
+		return []string{}
+	}
+
+	defined := sets.NewString()
+	for _, l := range locations {
+		if pathtype.Priority(l.PathType) < pathtype.Priority(location.PathType) {
+			defined.Insert(locationPaths(l, enforceRegex)...)
+		}
+	}
+
+	paths := []string{}
+	for _, path := range locationPaths(location, enforceRegex) {
+		if !defined.Has(path) {
+			paths = append(paths, path)
+		}
+	}
+
+	return paths
+}
+
+func locationPaths(location *ingress.Location, enforceRegex bool) []string {
+	if location.PathType != pathtype.Prefix {
+		return []string{buildLocation(location, enforceRegex)}
+	}
+
+	prefix := strings.TrimSuffix(location.Path, slash)
+	if prefix == "" {
+		return []string{slash}
+	}
+
+	return []string{prefix + slash, fmt.Sprintf(`= %s`, prefix)}
+}
+
 func buildAuthLocation(input interface{}) string {
 	location, ok := input.(*ingress.Location)
 	if !ok {
@@ -356,7 +695,13 @@ func buildAuthLocation(input interface{}) string {
 		return ""
 	}
 
-	str := base64.URLEncoding.EncodeToString([]byte(location.Path))
+	path := location.Path
+	if location.PathType == pathtype.Exact || location.PathType == pathtype.Prefix {
+		// the same path can be defined with different types
+		path = fmt.Sprintf("%v-%v", location.PathType, path)
+	}
+
+	str := base64.URLEncoding.EncodeToString([]byte(path))
 	// removes "=" after encoding
 	str = strings.Replace(str, "=", "", -1)
 	return fmt.Sprintf("/_external-auth-%v", str)
@@ -654,7 +999,10 @@ func buildUpstreamName(loc interface{}) string {
 	return upstreamName
 }
 
-func buildNextUpstream(i, r interface{}) string {
+// buildNextUpstream returns the conditions of the proxy_next_upstream
+// directive, adding the status codes of the retry policy of the location
+// unless the retries are disabled with "off"
+func buildNextUpstream(i, r, s interface{}) string {
 	nextUpstream, ok := i.(string)
 	if !ok {
 		klog.Errorf("expected a 'string' type but %T was returned", i)
@@ -663,11 +1011,19 @@ func buildNextUpstream(i, r interface{}) string {
 
 	retryNonIdempotent := r.(bool)
 
+	onStatus, ok := s.([]int)
+	if !ok {
+		klog.Errorf("expected an '[]int' type but %T was returned", s)
+		return ""
+	}
+
 	parts := strings.Split(nextUpstream, " ")
 
-	nextUpstreamCodes := make([]string, 0, len(parts))
+	added := sets.NewString()
+	nextUpstreamCodes := make([]string, 0, len(parts)+len(onStatus))
 	for _, v := range parts {
-		if v != "" && v != nonIdempotent {
+		if v != "" && v != nonIdempotent && !added.Has(v) {
+			added.Insert(v)
 			nextUpstreamCodes = append(nextUpstreamCodes, v)
 		}
 
@@ -676,6 +1032,16 @@ func buildNextUpstream(i, r interface{}) string {
 		}
 	}
 
+	if !added.Has("off") {
+		for _, code := range onStatus {
+			v := fmt.Sprintf("http_%v", code)
+			if !added.Has(v) {
+				added.Insert(v)
+				nextUpstreamCodes = append(nextUpstreamCodes, v)
+			}
+		}
+	}
+
 	if retryNonIdempotent {
 		nextUpstreamCodes = append(nextUpstreamCodes, nonIdempotent)
 	}
@@ -980,3 +1346,54 @@ func opentracingPropagateContext(loc interface{}) string {
 
 	return "opentracing_propagate_context"
 }
+
+// buildProxyCache returns the directives caching the responses of a location
+// in the cache zone of the proxy-cache-zone annotation
+func buildProxyCache(loc interface{}) string {
+	location, ok := loc.(*ingress.Location)
+	if !ok {
+		klog.Errorf("expected a '*ingress.Location' type but %T was returned", loc)
+		return ""
+	}
+
+	cfg := location.ProxyCache
+	if !cfg.IsEnabled() {
+		return ""
+	}
+
+	// the proxy_cache directives are only used by proxy_pass
+	switch location.BackendProtocol {
+	case "GRPC", "GRPCS", "AJP":
+		return ""
+	}
+
+	var out []string
+	out = append(out,
+		fmt.Sprintf("proxy_cache %v;", cfg.Zone),
+		fmt.Sprintf("proxy_cache_key \"%v\";", cfg.Key))
+
+	for _, valid := range cfg.Valid {
+		if len(valid.Codes) == 0 {
+			out = append(out, fmt.Sprintf("proxy_cache_valid %v;", valid.Time))
+			continue
+		}
+
+		out = append(out, fmt.Sprintf("proxy_cache_valid %v %v;", strings.Join(valid.Codes, " "), valid.Time))
+	}
+
+	if len(cfg.Bypass) > 0 {
+		bypass := strings.Join(cfg.Bypass, " ")
+		out = append(out,
+			fmt.Sprintf("proxy_cache_bypass %v;", bypass),
+			fmt.Sprintf("proxy_no_cache %v;", bypass))
+	}
+
+	if cfg.StaleWhileRevalidate {
+		out = append(out,
+			"proxy_cache_use_stale updating error timeout http_500 http_502 http_503 http_504;",
+			"proxy_cache_background_update on;",
+			"proxy_cache_lock on;")
+	}
+
+	return strings.Join(out, "\n")
+}
@@ -29,15 +29,26 @@ import (
 	"fmt"
 
 	jsoniter "github.com/json-iterator/go"
+	apiv1 "k8s.io/api/core/v1"
 	extensions "k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/ingress-nginx/internal/file"
 	"k8s.io/ingress-nginx/internal/ingress"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/authreq"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/headers"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/influxdb"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/ipwhitelist"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/luarestywaf"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/pathtype"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/proxy"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/proxycache"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/ratelimit"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/requestvalidation"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/retrypolicy"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/rewrite"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/routing"
 	"k8s.io/ingress-nginx/internal/ingress/controller/config"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
 )
 
 var (
@@ -167,7 +178,7 @@ proxy_pass http://upstream_balancer;`,
 func TestBuildLuaSharedDictionaries(t *testing.T) {
 	invalidType := &ingress.Ingress{}
 	expected := ""
-	actual := buildLuaSharedDictionaries(invalidType, true)
+	actual := buildLuaSharedDictionaries(invalidType, true, false)
 
 	if !reflect.DeepEqual(expected, actual) {
 		t.Errorf("Expected '%v' but returned '%v'", expected, actual)
@@ -184,16 +195,27 @@ func TestBuildLuaSharedDictionaries(t *testing.T) {
 		},
 	}
 
-	config := buildLuaSharedDictionaries(servers, false)
+	config := buildLuaSharedDictionaries(servers, false, false)
 	if !strings.Contains(config, "lua_shared_dict configuration_data") {
 		t.Errorf("expected to include 'configuration_data' but got %s", config)
 	}
+	if !strings.Contains(config, "lua_shared_dict balancer_concurrency") {
+		t.Errorf("expected to include 'balancer_concurrency' but got %s", config)
+	}
 	if strings.Contains(config, "waf_storage") {
 		t.Errorf("expected to not include 'waf_storage' but got %s", config)
 	}
+	if strings.Contains(config, "zone_status_data") {
+		t.Errorf("expected to not include 'zone_status_data' but got %s", config)
+	}
+
+	config = buildLuaSharedDictionaries(servers, false, true)
+	if !strings.Contains(config, "lua_shared_dict zone_status_data 10M") {
+		t.Errorf("expected to include 'zone_status_data' but got %s", config)
+	}
 
 	servers[1].Locations[0].LuaRestyWAF = luarestywaf.Config{Mode: "ACTIVE"}
-	config = buildLuaSharedDictionaries(servers, false)
+	config = buildLuaSharedDictionaries(servers, false, false)
 	if !strings.Contains(config, "lua_shared_dict waf_storage") {
 		t.Errorf("expected to configure 'waf_storage', but got %s", config)
 	}
@@ -240,6 +262,66 @@ func TestBuildLocation(t *testing.T) {
 	}
 }
 
+func TestBuildLocationWithPathType(t *testing.T) {
+	testCases := []struct {
+		path         string
+		pathType     string
+		enforceRegex bool
+		expected     string
+	}{
+		{"/foo", pathtype.Exact, false, `= /foo`},
+		{"/foo", pathtype.Exact, true, `= /foo`},
+		{"/foo", pathtype.Prefix, true, `~ "^/foo(/|$)"`},
+		{"/foo/", pathtype.Prefix, true, `~ "^/foo(/|$)"`},
+		{"/v1.0", pathtype.Prefix, true, `~ "^/v1\.0(/|$)"`},
+		{"/", pathtype.Prefix, true, `~ "^/"`},
+		{"/foo", pathtype.ImplementationSpecific, false, `/foo`},
+		{"/foo", pathtype.ImplementationSpecific, true, `~* "^/foo"`},
+	}
+
+	for _, tc := range testCases {
+		loc := &ingress.Location{
+			Path:     tc.path,
+			PathType: tc.pathType,
+		}
+
+		actual := buildLocation(loc, tc.enforceRegex)
+		if tc.expected != actual {
+			t.Errorf("%v %v: expected '%v' but returned '%v'", tc.pathType, tc.path, tc.expected, actual)
+		}
+	}
+}
+
+func TestBuildLocationPaths(t *testing.T) {
+	exactFoo := &ingress.Location{Path: "/foo", PathType: pathtype.Exact}
+	prefixFoo := &ingress.Location{Path: "/foo", PathType: pathtype.Prefix}
+	prefixRoot := &ingress.Location{Path: "/", PathType: pathtype.Prefix}
+	specificFoo := &ingress.Location{Path: "/foo/", PathType: pathtype.ImplementationSpecific}
+	specificBar := &ingress.Location{Path: "/bar", PathType: pathtype.ImplementationSpecific}
+
+	testCases := []struct {
+		location     *ingress.Location
+		locations    []*ingress.Location
+		enforceRegex bool
+		expected     []string
+	}{
+		{exactFoo, []*ingress.Location{exactFoo, prefixFoo}, false, []string{"= /foo"}},
+		{prefixFoo, []*ingress.Location{prefixFoo}, true, []string{"/foo/", "= /foo"}},
+		{prefixFoo, []*ingress.Location{exactFoo, prefixFoo}, false, []string{"/foo/"}},
+		{prefixRoot, []*ingress.Location{prefixRoot}, false, []string{"/"}},
+		{specificFoo, []*ingress.Location{prefixFoo, specificFoo}, false, []string{}},
+		{specificFoo, []*ingress.Location{prefixFoo, specificFoo}, true, []string{`~* "^/foo/"`}},
+		{specificBar, []*ingress.Location{prefixFoo, specificBar}, false, []string{"/bar"}},
+	}
+
+	for _, tc := range testCases {
+		actual := buildLocationPaths(tc.location, tc.locations, tc.enforceRegex)
+		if !reflect.DeepEqual(tc.expected, actual) {
+			t.Errorf("%v %v: expected '%v' but returned '%v'", tc.location.PathType, tc.location.Path, tc.expected, actual)
+		}
+	}
+}
+
 func TestBuildProxyPass(t *testing.T) {
 	defaultBackend := "upstream-name"
 	defaultHost := "example.com"
@@ -380,6 +462,333 @@ func TestTemplateWithData(t *testing.T) {
 	}
 }
 
+func TestTemplateWithTLSStreamService(t *testing.T) {
+	pwd, _ := os.Getwd()
+	data, err := ioutil.ReadFile(path.Join(pwd, "../../../../test/data/config.json"))
+	if err != nil {
+		t.Fatalf("unexpected error reading json file: %v", err)
+	}
+	var dat config.TemplateConfig
+	if err := jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal(data, &dat); err != nil {
+		t.Fatalf("unexpected error unmarshalling json: %v", err)
+	}
+	if dat.ListenPorts == nil {
+		dat.ListenPorts = &config.ListenPorts{}
+	}
+
+	dat.Cfg.BindAddressIpv4 = []string{}
+	dat.DynamicCertificatesEnabled = true
+	dat.TCPBackends = []ingress.L4Service{
+		{
+			Port:    5432,
+			Backend: ingress.L4Backend{Name: "postgres", Namespace: "default", Port: intstr.FromString("5432")},
+			SSLCert: &ingress.SSLCert{PemFileName: "/etc/ingress-controller/ssl/default-postgres-tls.pem"},
+			ClientCACert: resolver.AuthSSLCert{
+				CAFileName: "/etc/ingress-controller/ssl/ca-default-postgres-ca.pem",
+			},
+		},
+		{
+			Port:    6379,
+			Backend: ingress.L4Backend{Name: "redis", Namespace: "default", Port: intstr.FromString("6379")},
+		},
+	}
+
+	fs, err := file.NewFakeFS()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ngxTpl, err := NewTemplate("/etc/nginx/template/nginx.tmpl", fs)
+	if err != nil {
+		t.Fatalf("invalid NGINX template: %v", err)
+	}
+
+	rt, err := ngxTpl.Write(dat)
+	if err != nil {
+		t.Fatalf("invalid NGINX template: %v", err)
+	}
+
+	expected := []string{
+		"listen                  5432 ssl;",
+		"listen                  6379;",
+		"ssl_certificate         /etc/ingress-controller/ssl/default-postgres-tls.pem;",
+		"ssl_client_certificate  /etc/ingress-controller/ssl/ca-default-postgres-ca.pem;",
+	}
+	for _, e := range expected {
+		if !strings.Contains(string(rt), e) {
+			t.Errorf("invalid NGINX template, expected %q", e)
+		}
+	}
+}
+
+func TestTemplateWithSNIStreamServices(t *testing.T) {
+	pwd, _ := os.Getwd()
+	data, err := ioutil.ReadFile(path.Join(pwd, "../../../../test/data/config.json"))
+	if err != nil {
+		t.Fatalf("unexpected error reading json file: %v", err)
+	}
+	var dat config.TemplateConfig
+	if err := jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal(data, &dat); err != nil {
+		t.Fatalf("unexpected error unmarshalling json: %v", err)
+	}
+	if dat.ListenPorts == nil {
+		dat.ListenPorts = &config.ListenPorts{}
+	}
+
+	dat.Cfg.BindAddressIpv4 = []string{}
+	dat.TCPBackends = []ingress.L4Service{
+		{
+			Port:     5432,
+			Hostname: "a.db.example.com",
+			Backend:  ingress.L4Backend{Name: "postgres", Namespace: "tenant-a", Port: intstr.FromString("5432")},
+		},
+		{
+			Port:     5432,
+			Hostname: "b.db.example.com",
+			Backend:  ingress.L4Backend{Name: "postgres", Namespace: "tenant-b", Port: intstr.FromString("5432")},
+		},
+	}
+
+	fs, err := file.NewFakeFS()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ngxTpl, err := NewTemplate("/etc/nginx/template/nginx.tmpl", fs)
+	if err != nil {
+		t.Fatalf("invalid NGINX template: %v", err)
+	}
+
+	rt, err := ngxTpl.Write(dat)
+	if err != nil {
+		t.Fatalf("invalid NGINX template: %v", err)
+	}
+
+	if count := strings.Count(string(rt), "listen                  5432;"); count != 1 {
+		t.Errorf("invalid NGINX template, expected one listen directive for port 5432 but got %v", count)
+	}
+
+	expected := []string{
+		"ssl_preread             on;",
+		`tcp_udp_balancer.route_by_sni({ ["a.db.example.com"] = { upstream = "tcp-tenant-a-postgres-5432", namespace = "tenant-a", service = "postgres" }, ` +
+			`["b.db.example.com"] = { upstream = "tcp-tenant-b-postgres-5432", namespace = "tenant-b", service = "postgres" } })`,
+	}
+	for _, e := range expected {
+		if !strings.Contains(string(rt), e) {
+			t.Errorf("invalid NGINX template, expected %q", e)
+		}
+	}
+}
+
+func TestTemplateWithStreamServiceSettings(t *testing.T) {
+	pwd, _ := os.Getwd()
+	data, err := ioutil.ReadFile(path.Join(pwd, "../../../../test/data/config.json"))
+	if err != nil {
+		t.Fatalf("unexpected error reading json file: %v", err)
+	}
+	var dat config.TemplateConfig
+	if err := jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal(data, &dat); err != nil {
+		t.Fatalf("unexpected error unmarshalling json: %v", err)
+	}
+	if dat.ListenPorts == nil {
+		dat.ListenPorts = &config.ListenPorts{}
+	}
+
+	dat.Cfg.BindAddressIpv4 = []string{}
+	dat.Cfg.ProxyStreamTimeout = "600s"
+	dat.EnableMetrics = true
+	dat.TCPBackends = []ingress.L4Service{
+		{
+			Port:                5432,
+			Backend:             ingress.L4Backend{Name: "postgres", Namespace: "default", Port: intstr.FromString("5432"), Protocol: apiv1.ProtocolTCP},
+			ProxyTimeout:        3600,
+			ProxyConnectTimeout: 5,
+			DisableAccessLog:    true,
+			AllowedSourceRange:  ipwhitelist.SourceRange{CIDR: []string{"10.0.0.0/8"}},
+			DeniedSourceRange:   ipwhitelist.SourceRange{CIDR: []string{"10.0.0.1"}},
+			ConnectionLimit:     5,
+		},
+	}
+	dat.UDPBackends = []ingress.L4Service{
+		{
+			Port:    53,
+			Backend: ingress.L4Backend{Name: "kube-dns", Namespace: "kube-system", Port: intstr.FromString("53")},
+		},
+	}
+
+	fs, err := file.NewFakeFS()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ngxTpl, err := NewTemplate("/etc/nginx/template/nginx.tmpl", fs)
+	if err != nil {
+		t.Fatalf("invalid NGINX template: %v", err)
+	}
+
+	rt, err := ngxTpl.Write(dat)
+	if err != nil {
+		t.Fatalf("invalid NGINX template: %v", err)
+	}
+
+	expected := []string{
+		"proxy_connect_timeout   5s;",
+		"proxy_timeout           3600s;",
+		"proxy_timeout           600s;",
+		"tcp_udp_balancer.log()",
+		"deny                    10.0.0.1;",
+		"allow                   10.0.0.0/8;",
+		"deny                    all;",
+		"limit_conn_zone $binary_remote_addr$protocol$server_port zone=stream_conn_limit:10m;",
+		"limit_conn              stream_conn_limit 5;",
+		"log_stream if=$stream_blocked;",
+		`tcp_udp_monitor.call({ protocol = "tcp", namespace = "default", service = "postgres", port = 5432 })`,
+	}
+	for _, e := range expected {
+		if !strings.Contains(string(rt), e) {
+			t.Errorf("invalid NGINX template, expected %q", e)
+		}
+	}
+}
+
+func TestTemplateWithDynamicStreamPorts(t *testing.T) {
+	pwd, _ := os.Getwd()
+	data, err := ioutil.ReadFile(path.Join(pwd, "../../../../test/data/config.json"))
+	if err != nil {
+		t.Fatalf("unexpected error reading json file: %v", err)
+	}
+	var dat config.TemplateConfig
+	if err := jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal(data, &dat); err != nil {
+		t.Fatalf("unexpected error unmarshalling json: %v", err)
+	}
+
+	dat.ListenPorts = &config.ListenPorts{
+		DynamicStream: config.PortRange{First: 10000, Last: 10001},
+	}
+	dat.Cfg.BindAddressIpv4 = []string{}
+	dat.TCPBackends = []ingress.L4Service{
+		{
+			Port:    5432,
+			Backend: ingress.L4Backend{Name: "postgres", Namespace: "default", Port: intstr.FromString("5432"), Protocol: apiv1.ProtocolTCP},
+		},
+		{
+			Port:    10000,
+			Backend: ingress.L4Backend{Name: "redis", Namespace: "default", Port: intstr.FromString("6379"), Protocol: apiv1.ProtocolTCP},
+		},
+	}
+	dat.UDPBackends = []ingress.L4Service{
+		{
+			Port:    10001,
+			Backend: ingress.L4Backend{Name: "kube-dns", Namespace: "kube-system", Port: intstr.FromString("53")},
+		},
+	}
+
+	fs, err := file.NewFakeFS()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ngxTpl, err := NewTemplate("/etc/nginx/template/nginx.tmpl", fs)
+	if err != nil {
+		t.Fatalf("invalid NGINX template: %v", err)
+	}
+
+	rt, err := ngxTpl.Write(dat)
+	if err != nil {
+		t.Fatalf("invalid NGINX template: %v", err)
+	}
+
+	expected := []string{
+		`ngx.var.proxy_upstream_name="tcp-default-postgres-5432";`,
+		`tcp_udp_balancer.route_by_port("tcp")`,
+		`tcp_udp_balancer.route_by_port("udp")`,
+		"listen                  10000;",
+		"listen                  10001;",
+		"listen                  10000 udp;",
+		"listen                  10001 udp;",
+	}
+	for _, e := range expected {
+		if !strings.Contains(string(rt), e) {
+			t.Errorf("invalid NGINX template, expected %q", e)
+		}
+	}
+
+	unexpected := []string{
+		"tcp-default-redis-6379",
+		"udp-kube-system-kube-dns-53",
+	}
+	for _, u := range unexpected {
+		if strings.Contains(string(rt), u) {
+			t.Errorf("invalid NGINX template, unexpected %q", u)
+		}
+	}
+}
+
+func TestTemplateWithHTTP3(t *testing.T) {
+	pwd, _ := os.Getwd()
+	data, err := ioutil.ReadFile(path.Join(pwd, "../../../../test/data/config.json"))
+	if err != nil {
+		t.Fatalf("unexpected error reading json file: %v", err)
+	}
+	var dat config.TemplateConfig
+	if err := jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal(data, &dat); err != nil {
+		t.Fatalf("unexpected error unmarshalling json: %v", err)
+	}
+
+	dat.ListenPorts = &config.ListenPorts{HTTP: 80, HTTPS: 443, HTTP3: 8443}
+	dat.IsIPV6Enabled = true
+	dat.Cfg.BindAddressIpv4 = []string{}
+	dat.Cfg.BindAddressIpv6 = []string{}
+	dat.Cfg.HTTP3AltSvcMaxAge = 3600
+
+	// the catch-all server advertises HTTP/3 but not bar.baz.com
+	dat.Servers = dat.Servers[:2]
+	for _, server := range dat.Servers {
+		server.SSLCert = ingress.SSLCert{PemFileName: "/etc/ingress-controller/ssl/default.pem"}
+	}
+	dat.Servers[0].HTTP3AltSvc = true
+
+	fs, err := file.NewFakeFS()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ngxTpl, err := NewTemplate("/etc/nginx/template/nginx.tmpl", fs)
+	if err != nil {
+		t.Fatalf("invalid NGINX template: %v", err)
+	}
+
+	rt, err := ngxTpl.Write(dat)
+	if err != nil {
+		t.Fatalf("invalid NGINX template: %v", err)
+	}
+
+	expected := map[string]int{
+		"listen 8443 quic default_server reuseport;":           1,
+		"listen [::]:8443 quic default_server reuseport;":      1,
+		"listen 8443 quic;":                                    1,
+		"listen [::]:8443 quic;":                               1,
+		`http3.header_filter({ port = 8443, max_age = 3600 })`: len(dat.Servers[0].Locations),
+	}
+	for e, count := range expected {
+		if actual := strings.Count(string(rt), e); actual != count {
+			t.Errorf("invalid NGINX template, expected %q %v times but found it %v times", e, count, actual)
+		}
+	}
+
+	// the port of the Service exposing the HTTP/3 listener is advertised
+	dat.Cfg.HTTP3AltSvcPort = 443
+	rt, err = ngxTpl.Write(dat)
+	if err != nil {
+		t.Fatalf("invalid NGINX template: %v", err)
+	}
+
+	e := `http3.header_filter({ port = 443, max_age = 3600 })`
+	if actual := strings.Count(string(rt), e); actual != len(dat.Servers[0].Locations) {
+		t.Errorf("invalid NGINX template, expected %q %v times but found it %v times", e, len(dat.Servers[0].Locations), actual)
+	}
+}
+
 func BenchmarkTemplateWithData(b *testing.B) {
 	pwd, _ := os.Getwd()
 	f, err := os.Open(path.Join(pwd, "../../../../test/data/config.json"))
@@ -531,6 +940,238 @@ func TestBuildResolversForLua(t *testing.T) {
 	}
 }
 
+func TestBuildHeaderOperationsForLua(t *testing.T) {
+	expected := "{}"
+	actual := buildHeaderOperationsForLua(&ingress.Ingress{})
+
+	if expected != actual {
+		t.Errorf("Expected '%v' but returned '%v'", expected, actual)
+	}
+
+	ops := headers.Operations{
+		Set:    []headers.Header{{Name: "X-Quote", Value: `say "hi"`}},
+		Add:    []headers.Header{{Name: "X-A", Value: "1"}, {Name: "X-A", Value: "2"}},
+		Remove: []string{"Cookie"},
+	}
+
+	expected = `{ remove = { "Cookie" }, set = { { "X-Quote", "say \"hi\"" } }, add = { { "X-A", "1" }, { "X-A", "2" } } }`
+	actual = buildHeaderOperationsForLua(ops)
+
+	if expected != actual {
+		t.Errorf("Expected '%v' but returned '%v'", expected, actual)
+	}
+}
+
+func TestBuildRoutingForLua(t *testing.T) {
+	expected := "{}"
+	actual := buildRoutingForLua(&ingress.Ingress{})
+
+	if expected != actual {
+		t.Errorf("Expected '%v' but returned '%v'", expected, actual)
+	}
+
+	cfg := routing.Config{
+		AllowedMethods: []string{"GET", "POST"},
+		Routes: []routing.Route{
+			{
+				Headers:     []routing.HeaderMatch{{Name: "X-Api-Version", Value: "2", Type: routing.MatchExact}},
+				ServiceName: "api-v2",
+				Backend:     "default-api-v2-80",
+			},
+			{
+				Method:      "POST",
+				ServiceName: "writer",
+				Backend:     "default-writer-80",
+			},
+			{
+				GRPCService: "helloworld.Greeter",
+				GRPCMethod:  "SayHello",
+				ServiceName: "greeter",
+				Backend:     "default-greeter-50051",
+			},
+		},
+	}
+
+	expected = `{ methods = { "GET", "POST" }, routes = { ` +
+		`{ headers = { { name = "X-Api-Version", value = "2", type = "exact" } }, backend = "default-api-v2-80", service = "api-v2" }, ` +
+		`{ method = "POST", headers = {  }, backend = "default-writer-80", service = "writer" }, ` +
+		`{ grpc_service = "helloworld.Greeter", grpc_method = "SayHello", headers = {  }, backend = "default-greeter-50051", service = "greeter" } } }`
+	actual = buildRoutingForLua(cfg)
+
+	if expected != actual {
+		t.Errorf("Expected '%v' but returned '%v'", expected, actual)
+	}
+}
+
+func TestBuildRequestValidationForLua(t *testing.T) {
+	expected := "{}"
+	actual := buildRequestValidationForLua(&ingress.Ingress{})
+
+	if expected != actual {
+		t.Errorf("Expected '%v' but returned '%v'", expected, actual)
+	}
+
+	cfg := requestvalidation.Config{
+		ConfigMap:    "default/orders",
+		Schema:       `{"type":"object","required":["id"]}`,
+		ContentTypes: []string{"application/json"},
+		MaxBodySize:  1024,
+		Status:       422,
+	}
+
+	expected = `{ content_types = { "application/json" }, max_body_size = 1024, status = 422, schema = "{\"type\":\"object\",\"required\":[\"id\"]}" }`
+	actual = buildRequestValidationForLua(cfg)
+
+	if expected != actual {
+		t.Errorf("Expected '%v' but returned '%v'", expected, actual)
+	}
+}
+
+func TestLuaString(t *testing.T) {
+	testCases := map[string]string{
+		`{"pattern":"^\\d+$"}`:            `"{\"pattern\":\"^\\\\d+$\"}"`,
+		"{\"enum\":[\"café\"]}":           `"{\"enum\":[\"caf\195\169\"]}"`,
+		"{\"enum\":[\"\u2028\", \"\t\"]}": `"{\"enum\":[\"\226\128\168\", \"\009\"]}"`,
+	}
+
+	for s, expected := range testCases {
+		if actual := luaString(s); actual != expected {
+			t.Errorf("expected %v but returned %v", expected, actual)
+		}
+	}
+}
+
+func TestBuildRetryPolicyForLua(t *testing.T) {
+	testCases := []struct {
+		location *ingress.Location
+		expected string
+	}{
+		{&ingress.Location{}, "{}"},
+		{
+			&ingress.Location{
+				Proxy:       proxy.Config{ConnectTimeout: 5},
+				RetryPolicy: retrypolicy.Config{PerTryTimeout: 2, Budget: 20},
+			},
+			"{ connect_timeout = 2, per_try_timeout = 2, budget = 20 }",
+		},
+		{
+			&ingress.Location{
+				Proxy:       proxy.Config{ConnectTimeout: 1},
+				RetryPolicy: retrypolicy.Config{PerTryTimeout: 3},
+			},
+			"{ connect_timeout = 1, per_try_timeout = 3 }",
+		},
+	}
+
+	for _, tc := range testCases {
+		actual := buildRetryPolicyForLua(tc.location)
+		if actual != tc.expected {
+			t.Errorf("Expected '%v' but returned '%v'", tc.expected, actual)
+		}
+	}
+}
+
+func TestBuildSNIStreamServers(t *testing.T) {
+	proxy := ingress.ProxyProtocol{Encode: true}
+	svcs := []ingress.L4Service{
+		{Port: 3306},
+		{Port: 5432, Hostname: "a.db.example.com"},
+		{Port: 5432, Hostname: "b.db.example.com"},
+		{Port: 6379, Hostname: "a.redis.example.com", Backend: ingress.L4Backend{ProxyProtocol: proxy}},
+	}
+
+	servers := buildSNIStreamServers(svcs)
+	if len(servers) != 2 {
+		t.Fatalf("expected 2 servers but returned %v", len(servers))
+	}
+
+	if servers[0].Port != 5432 || len(servers[0].Services) != 2 || servers[0].Backend.ProxyProtocol.Encode {
+		t.Errorf("unexpected server %+v", servers[0])
+	}
+
+	if servers[1].Port != 6379 || len(servers[1].Services) != 1 || !servers[1].Backend.ProxyProtocol.Encode {
+		t.Errorf("unexpected server %+v", servers[1])
+	}
+
+	if servers := buildSNIStreamServers(&ingress.Ingress{}); len(servers) != 0 {
+		t.Errorf("expected no servers but returned %v", len(servers))
+	}
+}
+
+func TestBuildSNIRoutesForLua(t *testing.T) {
+	svcs := []ingress.L4Service{
+		{
+			Port:     5432,
+			Hostname: "a.db.example.com",
+			Backend:  ingress.L4Backend{Namespace: "tenant-a", Name: "postgres", Port: intstr.FromString("5432")},
+		},
+		{
+			Port:     5432,
+			Hostname: "b.db.example.com",
+			Backend:  ingress.L4Backend{Namespace: "tenant-b", Name: "postgres", Port: intstr.FromString("postgres")},
+		},
+	}
+
+	expected := `{ ["a.db.example.com"] = { upstream = "tcp-tenant-a-postgres-5432", namespace = "tenant-a", service = "postgres" }, ` +
+		`["b.db.example.com"] = { upstream = "tcp-tenant-b-postgres-postgres", namespace = "tenant-b", service = "postgres" } }`
+	if actual := buildSNIRoutesForLua(svcs); actual != expected {
+		t.Errorf("Expected '%v' but returned '%v'", expected, actual)
+	}
+
+	if actual := buildSNIRoutesForLua(&ingress.Ingress{}); actual != "{}" {
+		t.Errorf("Expected '{}' but returned '%v'", actual)
+	}
+}
+
+func TestHasStreamConnectionLimits(t *testing.T) {
+	tcp := []ingress.L4Service{{Port: 5432}}
+	udp := []ingress.L4Service{{Port: 53}}
+
+	if hasStreamConnectionLimits(tcp, udp) {
+		t.Errorf("expected no connection limits")
+	}
+
+	udp[0].ConnectionLimit = 10
+	if !hasStreamConnectionLimits(tcp, udp) {
+		t.Errorf("expected connection limits")
+	}
+
+	if hasStreamConnectionLimits(&ingress.Ingress{}, nil) {
+		t.Errorf("expected no connection limits with invalid types")
+	}
+}
+
+func TestBuildStreamServiceForLua(t *testing.T) {
+	testCases := []struct {
+		svc      interface{}
+		expected string
+	}{
+		{
+			ingress.L4Service{
+				Port:    53,
+				Backend: ingress.L4Backend{Namespace: "kube-system", Name: "kube-dns", Protocol: apiv1.ProtocolUDP},
+			},
+			`{ protocol = "udp", namespace = "kube-system", service = "kube-dns", port = 53 }`,
+		},
+		{
+			ingress.L4Service{
+				Port:     443,
+				Hostname: "a.db.example.com",
+				Backend:  ingress.L4Backend{Namespace: "tenant-a", Name: "postgres", Protocol: apiv1.ProtocolTCP},
+			},
+			`{ protocol = "tcp", port = 443 }`,
+		},
+		{&ingress.Ingress{}, "{}"},
+	}
+
+	for _, tc := range testCases {
+		actual := buildStreamServiceForLua(tc.svc)
+		if actual != tc.expected {
+			t.Errorf("Expected '%v' but returned '%v'", tc.expected, actual)
+		}
+	}
+}
+
 func TestBuildResolvers(t *testing.T) {
 	ipOne := net.ParseIP("192.0.0.1")
 	ipTwo := net.ParseIP("2001:db8:1234:0000:0000:0000:0000:0000")
@@ -570,7 +1211,7 @@ func TestBuildResolvers(t *testing.T) {
 func TestBuildNextUpstream(t *testing.T) {
 	invalidType := &ingress.Ingress{}
 	expected := ""
-	actual := buildNextUpstream(invalidType, "")
+	actual := buildNextUpstream(invalidType, "", []int{})
 
 	if expected != actual {
 		t.Errorf("Expected '%v' but returned '%v'", expected, actual)
@@ -579,33 +1220,50 @@ func TestBuildNextUpstream(t *testing.T) {
 	cases := map[string]struct {
 		NextUpstream  string
 		NonIdempotent bool
+		OnStatus      []int
 		Output        string
 	}{
 		"default": {
 			"timeout http_500 http_502",
 			false,
+			nil,
 			"timeout http_500 http_502",
 		},
 		"global": {
 			"timeout http_500 http_502",
 			true,
+			nil,
 			"timeout http_500 http_502 non_idempotent",
 		},
 		"local": {
 			"timeout http_500 http_502 non_idempotent",
 			false,
+			nil,
 			"timeout http_500 http_502 non_idempotent",
 		},
+		"retry policy": {
+			"error timeout http_502 http_502",
+			true,
+			[]int{502, 503},
+			"error timeout http_502 http_503 non_idempotent",
+		},
+		"retries disabled": {
+			"off",
+			false,
+			[]int{502, 503},
+			"off",
+		},
 	}
 
 	for k, tc := range cases {
-		nextUpstream := buildNextUpstream(tc.NextUpstream, tc.NonIdempotent)
+		nextUpstream := buildNextUpstream(tc.NextUpstream, tc.NonIdempotent, tc.OnStatus)
 		if nextUpstream != tc.Output {
 			t.Errorf(
-				"%s: called buildNextUpstream('%s', %v); expected '%v' but returned '%v'",
+				"%s: called buildNextUpstream('%s', %v, %v); expected '%v' but returned '%v'",
 				k,
 				tc.NextUpstream,
 				tc.NonIdempotent,
+				tc.OnStatus,
 				tc.Output,
 				nextUpstream,
 			)
@@ -1147,6 +1805,22 @@ func TestEnforceRegexModifier(t *testing.T) {
 	if expected != actual {
 		t.Errorf("Expected '%v' but returned '%v'", expected, actual)
 	}
+
+	locs = []*ingress.Location{
+		{
+			Path:     "/ok",
+			PathType: pathtype.Prefix,
+		},
+		{
+			Path: "/other",
+		},
+	}
+	expected = false
+	actual = enforceRegexModifier(locs)
+
+	if expected != actual {
+		t.Errorf("Expected '%v' but returned '%v'", expected, actual)
+	}
 }
 
 func TestStripLocationModifer(t *testing.T) {
@@ -1157,3 +1831,47 @@ func TestStripLocationModifer(t *testing.T) {
 		t.Errorf("Expected '%v' but returned '%v'", expected, actual)
 	}
 }
+
+func TestBuildProxyCache(t *testing.T) {
+	cfg := proxycache.Config{
+		Zone: "static",
+		Key:  proxycache.DefaultKey,
+		Valid: []proxycache.Valid{
+			{Codes: []string{"200", "302"}, Time: "10m"},
+			{Time: "1m"},
+		},
+		Bypass:               []string{"$http_pragma", "$cookie_nocache"},
+		StaleWhileRevalidate: true,
+	}
+
+	expected := `proxy_cache static;
+proxy_cache_key "$scheme$host$request_uri";
+proxy_cache_valid 200 302 10m;
+proxy_cache_valid 1m;
+proxy_cache_bypass $http_pragma $cookie_nocache;
+proxy_no_cache $http_pragma $cookie_nocache;
+proxy_cache_use_stale updating error timeout http_500 http_502 http_503 http_504;
+proxy_cache_background_update on;
+proxy_cache_lock on;`
+
+	actual := buildProxyCache(&ingress.Location{ProxyCache: cfg})
+	if actual != expected {
+		t.Errorf("Expected '%v' but returned '%v'", expected, actual)
+	}
+
+	actual = buildProxyCache(&ingress.Location{ProxyCache: proxycache.Config{Zone: "static", Key: "$host$uri"}})
+	expected = "proxy_cache static;\nproxy_cache_key \"$host$uri\";"
+	if actual != expected {
+		t.Errorf("Expected '%v' but returned '%v'", expected, actual)
+	}
+
+	for _, loc := range []interface{}{
+		&ingress.Location{},
+		&ingress.Location{BackendProtocol: "GRPC", ProxyCache: cfg},
+		"not a location",
+	} {
+		if actual := buildProxyCache(loc); actual != "" {
+			t.Errorf("Expected no directives for %v but returned '%v'", loc, actual)
+		}
+	}
+}
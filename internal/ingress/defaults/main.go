@@ -107,6 +107,11 @@ type Backend struct {
 	// This is useful if doing SSL offloading outside of cluster eg AWS ELB
 	ForceSSLRedirect bool `json:"force-ssl-redirect"`
 
+	// Enables or disables the Alt-Svc header advertising the HTTP/3 (QUIC)
+	// listener of the controller (--enable-http3) in the HTTPS responses
+	// Default: true
+	HTTP3AltSvc bool `json:"http3-alt-svc"`
+
 	// Enables or disables the specification of port in redirects
 	// Default: false
 	UsePortInRedirects bool `json:"use-port-in-redirects"`
@@ -48,6 +48,11 @@ type Controller struct {
 	labels      prometheus.Labels
 
 	leaderElection *prometheus.GaugeVec
+
+	syncPhaseDuration *prometheus.HistogramVec
+	configUpdates     *prometheus.CounterVec
+
+	ingressConflicts *prometheus.GaugeVec
 }
 
 // NewController creates a new prometheus collector for the
@@ -123,6 +128,34 @@ func NewController(pod, namespace, class string) *Controller {
 			},
 			[]string{"name"},
 		),
+		syncPhaseDuration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace:   PrometheusNamespace,
+				Name:        "sync_phase_duration_seconds",
+				Help:        `Time spent in each phase of the synchronization of the Ingress controller configuration`,
+				Buckets:     prometheus.ExponentialBuckets(0.005, 2, 14),
+				ConstLabels: constLabels,
+			},
+			[]string{"phase"},
+		),
+		configUpdates: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace:   PrometheusNamespace,
+				Name:        "config_updates_total",
+				Help:        `Cumulative number of configuration updates applied dynamically or with a reload, by reason. A reload required for several reasons is counted once per reason`,
+				ConstLabels: constLabels,
+			},
+			[]string{"type", "reason"},
+		),
+		ingressConflicts: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace:   PrometheusNamespace,
+				Name:        "ingress_conflicts",
+				Help:        `Number of active conflicts between Ingresses defining the same configuration of a host`,
+				ConstLabels: constLabels,
+			},
+			[]string{"type"},
+		),
 	}
 
 	return cm
@@ -138,6 +171,35 @@ func (cm *Controller) IncReloadErrorCount() {
 	cm.reloadOperationErrors.With(cm.constLabels).Inc()
 }
 
+// ObserveSyncPhase records the time spent in a phase of the synchronization
+func (cm *Controller) ObserveSyncPhase(phase string, duration time.Duration) {
+	cm.syncPhaseDuration.WithLabelValues(phase).Observe(duration.Seconds())
+}
+
+// IncConfigUpdateCount increments the counter of configuration updates.
+// A reload is counted once for each of the reasons it was required, so the
+// number of series does not depend on the combinations of reasons. The
+// updates applied dynamically have an empty reason.
+func (cm *Controller) IncConfigUpdateCount(dynamic bool, reasons []string) {
+	if dynamic {
+		cm.configUpdates.WithLabelValues("dynamic", "").Inc()
+		return
+	}
+
+	for _, reason := range reasons {
+		cm.configUpdates.WithLabelValues("reload", reason).Inc()
+	}
+}
+
+// SetIngressConflicts sets the number of active conflicts between Ingresses
+// by type of conflict
+func (cm *Controller) SetIngressConflicts(conflicts map[string]int) {
+	cm.ingressConflicts.Reset()
+	for conflictType, count := range conflicts {
+		cm.ingressConflicts.WithLabelValues(conflictType).Set(float64(count))
+	}
+}
+
 // OnStartedLeading indicates the pod was elected as the leader
 func (cm *Controller) OnStartedLeading(electionID string) {
 	cm.leaderElection.WithLabelValues(electionID).Set(1.0)
@@ -172,6 +234,9 @@ func (cm Controller) Describe(ch chan<- *prometheus.Desc) {
 	cm.reloadOperationErrors.Describe(ch)
 	cm.sslExpireTime.Describe(ch)
 	cm.leaderElection.Describe(ch)
+	cm.syncPhaseDuration.Describe(ch)
+	cm.configUpdates.Describe(ch)
+	cm.ingressConflicts.Describe(ch)
 }
 
 // Collect implements the prometheus.Collector interface.
@@ -183,6 +248,9 @@ func (cm Controller) Collect(ch chan<- prometheus.Metric) {
 	cm.reloadOperationErrors.Collect(ch)
 	cm.sslExpireTime.Collect(ch)
 	cm.leaderElection.Collect(ch)
+	cm.syncPhaseDuration.Collect(ch)
+	cm.configUpdates.Collect(ch)
+	cm.ingressConflicts.Collect(ch)
 }
 
 // SetSSLExpireTime sets the expiration time of SSL Certificates
@@ -100,6 +100,63 @@ func TestControllerCounters(t *testing.T) {
 			`,
 			metrics: []string{"nginx_ingress_controller_ssl_expire_time_seconds"},
 		},
+		{
+			name: "should count dynamic updates and reloads by reason",
+			test: func(cm *Controller) {
+				cm.IncConfigUpdateCount(true, nil)
+				cm.IncConfigUpdateCount(false, []string{"servers"})
+				cm.IncConfigUpdateCount(false, []string{"servers", "configmap"})
+			},
+			want: `
+				# HELP nginx_ingress_controller_config_updates_total Cumulative number of configuration updates applied dynamically or with a reload, by reason. A reload required for several reasons is counted once per reason
+				# TYPE nginx_ingress_controller_config_updates_total counter
+				nginx_ingress_controller_config_updates_total{controller_class="nginx",controller_namespace="default",controller_pod="pod",reason="",type="dynamic"} 1
+				nginx_ingress_controller_config_updates_total{controller_class="nginx",controller_namespace="default",controller_pod="pod",reason="configmap",type="reload"} 1
+				nginx_ingress_controller_config_updates_total{controller_class="nginx",controller_namespace="default",controller_pod="pod",reason="servers",type="reload"} 2
+			`,
+			metrics: []string{"nginx_ingress_controller_config_updates_total"},
+		},
+		{
+			name: "should set the active Ingress conflicts by type",
+			test: func(cm *Controller) {
+				cm.SetIngressConflicts(map[string]int{"path": 3, "alias": 1})
+				cm.SetIngressConflicts(map[string]int{"path": 2})
+			},
+			want: `
+				# HELP nginx_ingress_controller_ingress_conflicts Number of active conflicts between Ingresses defining the same configuration of a host
+				# TYPE nginx_ingress_controller_ingress_conflicts gauge
+				nginx_ingress_controller_ingress_conflicts{controller_class="nginx",controller_namespace="default",controller_pod="pod",type="path"} 2
+			`,
+			metrics: []string{"nginx_ingress_controller_ingress_conflicts"},
+		},
+		{
+			name: "should observe the duration of a sync phase",
+			test: func(cm *Controller) {
+				cm.ObserveSyncPhase("reload", 30*time.Millisecond)
+			},
+			want: `
+				# HELP nginx_ingress_controller_sync_phase_duration_seconds Time spent in each phase of the synchronization of the Ingress controller configuration
+				# TYPE nginx_ingress_controller_sync_phase_duration_seconds histogram
+				nginx_ingress_controller_sync_phase_duration_seconds_bucket{controller_class="nginx",controller_namespace="default",controller_pod="pod",phase="reload",le="0.005"} 0
+				nginx_ingress_controller_sync_phase_duration_seconds_bucket{controller_class="nginx",controller_namespace="default",controller_pod="pod",phase="reload",le="0.01"} 0
+				nginx_ingress_controller_sync_phase_duration_seconds_bucket{controller_class="nginx",controller_namespace="default",controller_pod="pod",phase="reload",le="0.02"} 0
+				nginx_ingress_controller_sync_phase_duration_seconds_bucket{controller_class="nginx",controller_namespace="default",controller_pod="pod",phase="reload",le="0.04"} 1
+				nginx_ingress_controller_sync_phase_duration_seconds_bucket{controller_class="nginx",controller_namespace="default",controller_pod="pod",phase="reload",le="0.08"} 1
+				nginx_ingress_controller_sync_phase_duration_seconds_bucket{controller_class="nginx",controller_namespace="default",controller_pod="pod",phase="reload",le="0.16"} 1
+				nginx_ingress_controller_sync_phase_duration_seconds_bucket{controller_class="nginx",controller_namespace="default",controller_pod="pod",phase="reload",le="0.32"} 1
+				nginx_ingress_controller_sync_phase_duration_seconds_bucket{controller_class="nginx",controller_namespace="default",controller_pod="pod",phase="reload",le="0.64"} 1
+				nginx_ingress_controller_sync_phase_duration_seconds_bucket{controller_class="nginx",controller_namespace="default",controller_pod="pod",phase="reload",le="1.28"} 1
+				nginx_ingress_controller_sync_phase_duration_seconds_bucket{controller_class="nginx",controller_namespace="default",controller_pod="pod",phase="reload",le="2.56"} 1
+				nginx_ingress_controller_sync_phase_duration_seconds_bucket{controller_class="nginx",controller_namespace="default",controller_pod="pod",phase="reload",le="5.12"} 1
+				nginx_ingress_controller_sync_phase_duration_seconds_bucket{controller_class="nginx",controller_namespace="default",controller_pod="pod",phase="reload",le="10.24"} 1
+				nginx_ingress_controller_sync_phase_duration_seconds_bucket{controller_class="nginx",controller_namespace="default",controller_pod="pod",phase="reload",le="20.48"} 1
+				nginx_ingress_controller_sync_phase_duration_seconds_bucket{controller_class="nginx",controller_namespace="default",controller_pod="pod",phase="reload",le="40.96"} 1
+				nginx_ingress_controller_sync_phase_duration_seconds_bucket{controller_class="nginx",controller_namespace="default",controller_pod="pod",phase="reload",le="+Inf"} 1
+				nginx_ingress_controller_sync_phase_duration_seconds_sum{controller_class="nginx",controller_namespace="default",controller_pod="pod",phase="reload"} 0.03
+				nginx_ingress_controller_sync_phase_duration_seconds_count{controller_class="nginx",controller_namespace="default",controller_pod="pod",phase="reload"} 1
+			`,
+			metrics: []string{"nginx_ingress_controller_sync_phase_duration_seconds"},
+		},
 	}
 
 	for _, c := range cases {
@@ -17,6 +17,7 @@ limitations under the License.
 package collectors
 
 import (
+	"encoding/json"
 	"log"
 	"regexp"
 	"strconv"
@@ -38,6 +39,8 @@ type (
 	nginxStatusCollector struct {
 		scrapeChan chan scrapeRequest
 
+		zoneMetrics bool
+
 		data *nginxStatusData
 	}
 
@@ -45,6 +48,27 @@ type (
 		connectionsTotal *prometheus.Desc
 		requestsTotal    *prometheus.Desc
 		connections      *prometheus.Desc
+
+		serverZoneRequests    *prometheus.Desc
+		serverZoneBytes       *prometheus.Desc
+		serverZoneResponses   *prometheus.Desc
+		upstreamZoneRequests  *prometheus.Desc
+		upstreamZoneBytes     *prometheus.Desc
+		upstreamZoneResponses *prometheus.Desc
+	}
+
+	// zoneStatus contains the counters of the server and upstream zones
+	// returned by the zone_status Lua module
+	zoneStatus struct {
+		ServerZones   map[string]zoneCounters `json:"serverZones"`
+		UpstreamZones map[string]zoneCounters `json:"upstreamZones"`
+	}
+
+	zoneCounters struct {
+		Requests  float64            `json:"requests"`
+		BytesIn   float64            `json:"bytesIn"`
+		BytesOut  float64            `json:"bytesOut"`
+		Responses map[string]float64 `json:"responses"`
 	}
 
 	basicStatus struct {
@@ -73,11 +97,13 @@ type NGINXStatusCollector interface {
 	Stop()
 }
 
-// NewNGINXStatus returns a new prometheus collector the default nginx status module
-func NewNGINXStatus(podName, namespace, ingressClass string) (NGINXStatusCollector, error) {
+// NewNGINXStatus returns a new prometheus collector the default nginx status module.
+// If zoneMetrics is true, the counters of each server and upstream zone are also collected.
+func NewNGINXStatus(podName, namespace, ingressClass string, zoneMetrics bool) (NGINXStatusCollector, error) {
 
 	p := nginxStatusCollector{
-		scrapeChan: make(chan scrapeRequest),
+		scrapeChan:  make(chan scrapeRequest),
+		zoneMetrics: zoneMetrics,
 	}
 
 	constLabels := prometheus.Labels{
@@ -101,6 +127,36 @@ func NewNGINXStatus(podName, namespace, ingressClass string) (NGINXStatusCollect
 			prometheus.BuildFQName(PrometheusNamespace, subSystem, "connections"),
 			"current number of client connections with state {active, reading, writing, waiting}",
 			[]string{"state"}, constLabels),
+
+		serverZoneRequests: prometheus.NewDesc(
+			prometheus.BuildFQName(PrometheusNamespace, subSystem, "server_zone_requests_total"),
+			"total number of client requests per server zone",
+			[]string{"host"}, constLabels),
+
+		serverZoneBytes: prometheus.NewDesc(
+			prometheus.BuildFQName(PrometheusNamespace, subSystem, "server_zone_bytes_total"),
+			"total number of bytes per server zone with direction {in, out}",
+			[]string{"host", "direction"}, constLabels),
+
+		serverZoneResponses: prometheus.NewDesc(
+			prometheus.BuildFQName(PrometheusNamespace, subSystem, "server_zone_responses_total"),
+			"total number of responses per server zone and status code class",
+			[]string{"host", "code"}, constLabels),
+
+		upstreamZoneRequests: prometheus.NewDesc(
+			prometheus.BuildFQName(PrometheusNamespace, subSystem, "upstream_zone_requests_total"),
+			"total number of client requests per upstream zone",
+			[]string{"upstream"}, constLabels),
+
+		upstreamZoneBytes: prometheus.NewDesc(
+			prometheus.BuildFQName(PrometheusNamespace, subSystem, "upstream_zone_bytes_total"),
+			"total number of bytes per upstream zone with direction {in, out}",
+			[]string{"upstream", "direction"}, constLabels),
+
+		upstreamZoneResponses: prometheus.NewDesc(
+			prometheus.BuildFQName(PrometheusNamespace, subSystem, "upstream_zone_responses_total"),
+			"total number of responses per upstream zone and status code class",
+			[]string{"upstream", "code"}, constLabels),
 	}
 
 	return p, nil
@@ -111,6 +167,15 @@ func (p nginxStatusCollector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- p.data.connectionsTotal
 	ch <- p.data.requestsTotal
 	ch <- p.data.connections
+
+	if p.zoneMetrics {
+		ch <- p.data.serverZoneRequests
+		ch <- p.data.serverZoneBytes
+		ch <- p.data.serverZoneResponses
+		ch <- p.data.upstreamZoneRequests
+		ch <- p.data.upstreamZoneBytes
+		ch <- p.data.upstreamZoneResponses
+	}
 }
 
 // Collect implements prometheus.Collector.
@@ -124,6 +189,9 @@ func (p nginxStatusCollector) Start() {
 	for req := range p.scrapeChan {
 		ch := req.results
 		p.scrape(ch)
+		if p.zoneMetrics {
+			p.scrapeZones(ch)
+		}
 		req.done <- struct{}{}
 	}
 }
@@ -195,3 +263,51 @@ func (p nginxStatusCollector) scrape(ch chan<- prometheus.Metric) {
 	ch <- prometheus.MustNewConstMetric(p.data.connections,
 		prometheus.GaugeValue, float64(s.Waiting), "waiting")
 }
+
+// scrapeZones scrapes the counters of the server and upstream zones
+func (p nginxStatusCollector) scrapeZones(ch chan<- prometheus.Metric) {
+	klog.V(3).Infof("start scraping socket: %v", nginx.ZoneStatusPath)
+	status, data, err := nginx.NewGetStatusRequest(nginx.ZoneStatusPath)
+	if err != nil {
+		klog.Warningf("unexpected error obtaining nginx zone status info: %v", err)
+		return
+	}
+
+	if status < 200 || status >= 400 {
+		klog.Warningf("unexpected error obtaining nginx zone status info (status %v)", status)
+		return
+	}
+
+	var zs zoneStatus
+	err = json.Unmarshal(data, &zs)
+	if err != nil {
+		klog.Warningf("unexpected error decoding nginx zone status info: %v", err)
+		return
+	}
+
+	for host, z := range zs.ServerZones {
+		ch <- prometheus.MustNewConstMetric(p.data.serverZoneRequests,
+			prometheus.CounterValue, z.Requests, host)
+		ch <- prometheus.MustNewConstMetric(p.data.serverZoneBytes,
+			prometheus.CounterValue, z.BytesIn, host, "in")
+		ch <- prometheus.MustNewConstMetric(p.data.serverZoneBytes,
+			prometheus.CounterValue, z.BytesOut, host, "out")
+		for code, v := range z.Responses {
+			ch <- prometheus.MustNewConstMetric(p.data.serverZoneResponses,
+				prometheus.CounterValue, v, host, code)
+		}
+	}
+
+	for upstream, z := range zs.UpstreamZones {
+		ch <- prometheus.MustNewConstMetric(p.data.upstreamZoneRequests,
+			prometheus.CounterValue, z.Requests, upstream)
+		ch <- prometheus.MustNewConstMetric(p.data.upstreamZoneBytes,
+			prometheus.CounterValue, z.BytesIn, upstream, "in")
+		ch <- prometheus.MustNewConstMetric(p.data.upstreamZoneBytes,
+			prometheus.CounterValue, z.BytesOut, upstream, "out")
+		for code, v := range z.Responses {
+			ch <- prometheus.MustNewConstMetric(p.data.upstreamZoneResponses,
+				prometheus.CounterValue, v, upstream, code)
+		}
+	}
+}
@@ -31,10 +31,12 @@ import (
 
 func TestStatusCollector(t *testing.T) {
 	cases := []struct {
-		name    string
-		mock    string
-		metrics []string
-		want    string
+		name        string
+		mock        string
+		zoneMetrics bool
+		zoneMock    string
+		metrics     []string
+		want        string
 	}{
 		{
 			name: "should return empty metrics",
@@ -93,6 +95,46 @@ func TestStatusCollector(t *testing.T) {
 				"nginx_ingress_controller_nginx_process_connections",
 			},
 		},
+		{
+			name: "should return server and upstream zone metrics",
+			mock: `
+				Active connections: 15
+				server accepts handled requests
+				1 2 3
+				Reading: 4 Writing: 5 Waiting: 6
+			`,
+			zoneMetrics: true,
+			zoneMock: `{
+				"serverZones": {"example.com": {"requests": 3, "bytesIn": 300, "bytesOut": 3000, "responses": {"2xx": 2, "5xx": 1}}},
+				"upstreamZones": {"default-echo-80": {"requests": 2, "bytesIn": 200, "bytesOut": 2000, "responses": {"2xx": 2}}}
+			}`,
+			want: `
+				# HELP nginx_ingress_controller_nginx_process_server_zone_bytes_total total number of bytes per server zone with direction {in, out}
+				# TYPE nginx_ingress_controller_nginx_process_server_zone_bytes_total counter
+				nginx_ingress_controller_nginx_process_server_zone_bytes_total{controller_class="nginx",controller_namespace="default",controller_pod="pod",direction="in",host="example.com"} 300
+				nginx_ingress_controller_nginx_process_server_zone_bytes_total{controller_class="nginx",controller_namespace="default",controller_pod="pod",direction="out",host="example.com"} 3000
+				# HELP nginx_ingress_controller_nginx_process_server_zone_requests_total total number of client requests per server zone
+				# TYPE nginx_ingress_controller_nginx_process_server_zone_requests_total counter
+				nginx_ingress_controller_nginx_process_server_zone_requests_total{controller_class="nginx",controller_namespace="default",controller_pod="pod",host="example.com"} 3
+				# HELP nginx_ingress_controller_nginx_process_server_zone_responses_total total number of responses per server zone and status code class
+				# TYPE nginx_ingress_controller_nginx_process_server_zone_responses_total counter
+				nginx_ingress_controller_nginx_process_server_zone_responses_total{code="2xx",controller_class="nginx",controller_namespace="default",controller_pod="pod",host="example.com"} 2
+				nginx_ingress_controller_nginx_process_server_zone_responses_total{code="5xx",controller_class="nginx",controller_namespace="default",controller_pod="pod",host="example.com"} 1
+				# HELP nginx_ingress_controller_nginx_process_upstream_zone_requests_total total number of client requests per upstream zone
+				# TYPE nginx_ingress_controller_nginx_process_upstream_zone_requests_total counter
+				nginx_ingress_controller_nginx_process_upstream_zone_requests_total{controller_class="nginx",controller_namespace="default",controller_pod="pod",upstream="default-echo-80"} 2
+				# HELP nginx_ingress_controller_nginx_process_upstream_zone_responses_total total number of responses per upstream zone and status code class
+				# TYPE nginx_ingress_controller_nginx_process_upstream_zone_responses_total counter
+				nginx_ingress_controller_nginx_process_upstream_zone_responses_total{code="2xx",controller_class="nginx",controller_namespace="default",controller_pod="pod",upstream="default-echo-80"} 2
+			`,
+			metrics: []string{
+				"nginx_ingress_controller_nginx_process_server_zone_requests_total",
+				"nginx_ingress_controller_nginx_process_server_zone_bytes_total",
+				"nginx_ingress_controller_nginx_process_server_zone_responses_total",
+				"nginx_ingress_controller_nginx_process_upstream_zone_requests_total",
+				"nginx_ingress_controller_nginx_process_upstream_zone_responses_total",
+			},
+		},
 	}
 
 	for _, c := range cases {
@@ -116,6 +158,15 @@ func TestStatusCollector(t *testing.T) {
 						return
 					}
 
+					if r.URL.Path == nginx.ZoneStatusPath {
+						_, err := fmt.Fprint(w, c.zoneMock)
+						if err != nil {
+							t.Fatal(err)
+						}
+
+						return
+					}
+
 					fmt.Fprintf(w, "OK")
 				})},
 			}
@@ -123,7 +174,7 @@ func TestStatusCollector(t *testing.T) {
 
 			time.Sleep(1 * time.Second)
 
-			cm, err := NewNGINXStatus("pod", "default", "nginx")
+			cm, err := NewNGINXStatus("pod", "default", "nginx", c.zoneMetrics)
 			if err != nil {
 				t.Errorf("unexpected error creating nginx status collector: %v", err)
 			}
@@ -27,6 +27,8 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/klog"
+
+	"k8s.io/ingress-nginx/internal/ingress/metric/traffic"
 )
 
 type upstream struct {
@@ -53,6 +55,60 @@ type socketData struct {
 	Ingress   string `json:"ingress"`
 	Service   string `json:"service"`
 	Path      string `json:"path"`
+
+	// CacheStatus contains the value of $upstream_cache_status in the
+	// locations using a cache zone
+	CacheStatus string `json:"cacheStatus"`
+
+	// ValidationError contains the reason of the rejection of requests not
+	// passing the request validation of the location
+	ValidationError string `json:"validationError"`
+
+	// UpstreamRetries is the number of times the request was retried in
+	// another upstream server
+	UpstreamRetries float64 `json:"upstreamRetries"`
+	// RetryBudgetExhausted is true when a retry of the request was rejected
+	// by the retry budget of the location
+	RetryBudgetExhausted bool `json:"retryBudgetExhausted"`
+
+	// ConcurrencyLimit is "queued" or "rejected" when the request reached
+	// the limit of concurrent requests of the backend
+	ConcurrencyLimit string `json:"concurrencyLimit"`
+
+	// GRPCService and GRPCMethod are extracted from the path of the gRPC
+	// requests, and GRPCStatus is the grpc-status of the response
+	GRPCService string `json:"grpcService"`
+	GRPCMethod  string `json:"grpcMethod"`
+	GRPCStatus  string `json:"grpcStatus"`
+
+	// UpgradeOutcome is how a WebSocket or other upgraded connection ended:
+	// "closed" while NGINX was running, "drained" during a reload or shutdown
+	// or "killed" by NGINX at the end of the drain budget
+	UpgradeOutcome string `json:"upgradeOutcome"`
+
+	// Stream contains the information of a session of a TCP or UDP
+	// service instead of a request
+	Stream *streamData `json:"stream"`
+}
+
+// streamData is the information of a session of a TCP or UDP service
+type streamData struct {
+	Protocol  string `json:"protocol"`
+	Namespace string `json:"namespace"`
+	Service   string `json:"service"`
+	Port      string `json:"port"`
+
+	// Status is the value of $status, the result of the session
+	Status string `json:"status"`
+
+	BytesSent           float64 `json:"bytesSent"`
+	BytesReceived       float64 `json:"bytesReceived"`
+	SessionTime         float64 `json:"sessionTime"`
+	UpstreamConnectTime float64 `json:"upstreamConnectTime"`
+
+	// Blocked is "denied" or "limited" when the session was closed by the
+	// access rules or the connection limit of the service
+	Blocked string `json:"blocked"`
 }
 
 // SocketCollector stores prometheus metrics and ingress meta-data
@@ -71,13 +127,38 @@ type SocketCollector struct {
 
 	requests *prometheus.CounterVec
 
+	cacheResponses *prometheus.CounterVec
+
+	validationFailures *prometheus.CounterVec
+
+	upstreamRetries      *prometheus.CounterVec
+	retryBudgetExhausted *prometheus.CounterVec
+
+	concurrencyLimited *prometheus.CounterVec
+
+	grpcRequests    *prometheus.CounterVec
+	grpcRequestTime *prometheus.HistogramVec
+
+	upgradedConnections *prometheus.CounterVec
+
+	streamSessions        *prometheus.CounterVec
+	streamSessionsBlocked *prometheus.CounterVec
+	streamBytesSent       *prometheus.CounterVec
+	streamBytesReceived   *prometheus.CounterVec
+	streamSessionTime     *prometheus.HistogramVec
+	streamConnectTime     *prometheus.HistogramVec
+
 	listener net.Listener
 
 	metricMapping map[string]interface{}
 
+	streamMetricMapping map[string]interface{}
+
 	hosts sets.String
 
 	metricsPerHost bool
+
+	traffic *trafficTracker
 }
 
 var (
@@ -91,6 +172,13 @@ var (
 		"ingress",
 		"service",
 	}
+
+	streamTags = []string{
+		"protocol",
+		"namespace",
+		"service",
+		"port",
+	}
 )
 
 // NewSocketCollector creates a new SocketCollector instance using
@@ -123,6 +211,8 @@ func NewSocketCollector(pod, namespace, class string, metricsPerHost bool) (*Soc
 
 		metricsPerHost: metricsPerHost,
 
+		traffic: newTrafficTracker(),
+
 		responseTime: prometheus.NewHistogramVec(
 			prometheus.HistogramOpts{
 				Name:        "response_duration_seconds",
@@ -172,6 +262,147 @@ func NewSocketCollector(pod, namespace, class string, metricsPerHost bool) (*Soc
 			[]string{"ingress", "namespace", "status"},
 		),
 
+		cacheResponses: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name:        "cache_responses_total",
+				Help:        "The total number of responses of locations using a cache zone by cache status",
+				Namespace:   PrometheusNamespace,
+				ConstLabels: constLabels,
+			},
+			[]string{"ingress", "namespace", "service", "cache_status"},
+		),
+
+		validationFailures: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name:        "request_validation_failures_total",
+				Help:        "The total number of requests rejected by the request validation by reason",
+				Namespace:   PrometheusNamespace,
+				ConstLabels: constLabels,
+			},
+			[]string{"ingress", "namespace", "service", "reason"},
+		),
+
+		upstreamRetries: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name:        "upstream_retries_total",
+				Help:        "The total number of retries in another upstream server",
+				Namespace:   PrometheusNamespace,
+				ConstLabels: constLabels,
+			},
+			[]string{"ingress", "namespace", "service"},
+		),
+
+		retryBudgetExhausted: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name:        "retry_budget_exhausted_total",
+				Help:        "The total number of retries rejected by the retry budget",
+				Namespace:   PrometheusNamespace,
+				ConstLabels: constLabels,
+			},
+			[]string{"ingress", "namespace", "service"},
+		),
+
+		concurrencyLimited: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name:        "upstream_concurrency_limited_total",
+				Help:        "The total number of requests queued or rejected by the limit of concurrent requests of the backend",
+				Namespace:   PrometheusNamespace,
+				ConstLabels: constLabels,
+			},
+			[]string{"ingress", "namespace", "service", "result"},
+		),
+
+		grpcRequests: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name:        "grpc_requests_total",
+				Help:        "The total number of gRPC requests by gRPC service, method and status",
+				Namespace:   PrometheusNamespace,
+				ConstLabels: constLabels,
+			},
+			[]string{"namespace", "ingress", "service", "grpc_service", "grpc_method", "grpc_status"},
+		),
+
+		grpcRequestTime: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:        "grpc_request_duration_seconds",
+				Help:        "The duration of the gRPC requests by gRPC service and method",
+				Namespace:   PrometheusNamespace,
+				ConstLabels: constLabels,
+			},
+			[]string{"namespace", "ingress", "service", "grpc_service", "grpc_method"},
+		),
+
+		upgradedConnections: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name:        "upgraded_connections_total",
+				Help:        "The total number of WebSocket and other upgraded connections by the way they ended: closed, drained or killed on reload or shutdown",
+				Namespace:   PrometheusNamespace,
+				ConstLabels: constLabels,
+			},
+			[]string{"namespace", "ingress", "service", "outcome"},
+		),
+
+		streamSessions: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name:        "stream_sessions_total",
+				Help:        "The total number of TCP and UDP sessions",
+				Namespace:   PrometheusNamespace,
+				ConstLabels: constLabels,
+			},
+			append(streamTags, "status"),
+		),
+
+		streamBytesSent: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name:        "stream_bytes_sent_total",
+				Help:        "The total number of bytes sent to the clients of TCP and UDP services",
+				Namespace:   PrometheusNamespace,
+				ConstLabels: constLabels,
+			},
+			streamTags,
+		),
+
+		streamBytesReceived: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name:        "stream_bytes_received_total",
+				Help:        "The total number of bytes received from the clients of TCP and UDP services",
+				Namespace:   PrometheusNamespace,
+				ConstLabels: constLabels,
+			},
+			streamTags,
+		),
+
+		streamSessionTime: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:        "stream_session_duration_seconds",
+				Help:        "The duration of the TCP and UDP sessions",
+				Namespace:   PrometheusNamespace,
+				Buckets:     prometheus.ExponentialBuckets(0.1, 4, 10), // 10 buckets from 100ms to about 7h.
+				ConstLabels: constLabels,
+			},
+			streamTags,
+		),
+
+		streamConnectTime: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:        "stream_upstream_connect_duration_seconds",
+				Help:        "The time spent on establishing the connections to the upstream servers of TCP and UDP services",
+				Namespace:   PrometheusNamespace,
+				ConstLabels: constLabels,
+			},
+			streamTags,
+		),
+
+		streamSessionsBlocked: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name:        "stream_sessions_blocked_total",
+				Help:        "The total number of TCP and UDP sessions closed by the access rules or the connection limit of the service",
+				Namespace:   PrometheusNamespace,
+				ConstLabels: constLabels,
+			},
+			append(streamTags, "reason"),
+		),
+
 		bytesSent: prometheus.NewHistogramVec(
 			prometheus.HistogramOpts{
 				Name:        "bytes_sent",
@@ -204,6 +435,25 @@ func NewSocketCollector(pod, namespace, class string, metricsPerHost bool) (*Soc
 		prometheus.BuildFQName(PrometheusNamespace, "", "bytes_sent"): sc.bytesSent,
 
 		prometheus.BuildFQName(PrometheusNamespace, "", "ingress_upstream_latency_seconds"): sc.upstreamLatency,
+
+		prometheus.BuildFQName(PrometheusNamespace, "", "cache_responses_total"):              sc.cacheResponses,
+		prometheus.BuildFQName(PrometheusNamespace, "", "request_validation_failures_total"):  sc.validationFailures,
+		prometheus.BuildFQName(PrometheusNamespace, "", "upstream_retries_total"):             sc.upstreamRetries,
+		prometheus.BuildFQName(PrometheusNamespace, "", "retry_budget_exhausted_total"):       sc.retryBudgetExhausted,
+		prometheus.BuildFQName(PrometheusNamespace, "", "upstream_concurrency_limited_total"): sc.concurrencyLimited,
+
+		prometheus.BuildFQName(PrometheusNamespace, "", "grpc_requests_total"):           sc.grpcRequests,
+		prometheus.BuildFQName(PrometheusNamespace, "", "grpc_request_duration_seconds"): sc.grpcRequestTime,
+		prometheus.BuildFQName(PrometheusNamespace, "", "upgraded_connections_total"):    sc.upgradedConnections,
+	}
+
+	sc.streamMetricMapping = map[string]interface{}{
+		prometheus.BuildFQName(PrometheusNamespace, "", "stream_sessions_total"):                    sc.streamSessions,
+		prometheus.BuildFQName(PrometheusNamespace, "", "stream_sessions_blocked_total"):            sc.streamSessionsBlocked,
+		prometheus.BuildFQName(PrometheusNamespace, "", "stream_bytes_sent_total"):                  sc.streamBytesSent,
+		prometheus.BuildFQName(PrometheusNamespace, "", "stream_bytes_received_total"):              sc.streamBytesReceived,
+		prometheus.BuildFQName(PrometheusNamespace, "", "stream_session_duration_seconds"):          sc.streamSessionTime,
+		prometheus.BuildFQName(PrometheusNamespace, "", "stream_upstream_connect_duration_seconds"): sc.streamConnectTime,
 	}
 
 	return sc, nil
@@ -221,6 +471,11 @@ func (sc *SocketCollector) handleMessage(msg []byte) {
 	}
 
 	for _, stats := range statsBatch {
+		if stats.Stream != nil {
+			sc.handleStreamData(stats.Stream)
+			continue
+		}
+
 		if !sc.hosts.Has(stats.Host) {
 			klog.V(3).Infof("skiping metric for host %v that is not being served", stats.Host)
 			continue
@@ -251,6 +506,13 @@ func (sc *SocketCollector) handleMessage(msg []byte) {
 			"service":   stats.Service,
 		}
 
+		sc.traffic.Record(trafficKey{
+			namespace: stats.Namespace,
+			ingress:   stats.Ingress,
+			host:      stats.Host,
+			service:   stats.Service,
+		}, stats.Status, stats.RequestTime)
+
 		requestsMetric, err := sc.requests.GetMetricWith(collectorLabels)
 		if err != nil {
 			klog.Errorf("Error fetching requests metric: %v", err)
@@ -258,6 +520,107 @@ func (sc *SocketCollector) handleMessage(msg []byte) {
 			requestsMetric.Inc()
 		}
 
+		if stats.CacheStatus != "" {
+			cacheMetric, err := sc.cacheResponses.GetMetricWith(prometheus.Labels{
+				"namespace":    stats.Namespace,
+				"ingress":      stats.Ingress,
+				"service":      stats.Service,
+				"cache_status": stats.CacheStatus,
+			})
+			if err != nil {
+				klog.Errorf("Error fetching cache responses metric: %v", err)
+			} else {
+				cacheMetric.Inc()
+			}
+		}
+
+		if stats.ValidationError != "" {
+			validationMetric, err := sc.validationFailures.GetMetricWith(prometheus.Labels{
+				"namespace": stats.Namespace,
+				"ingress":   stats.Ingress,
+				"service":   stats.Service,
+				"reason":    stats.ValidationError,
+			})
+			if err != nil {
+				klog.Errorf("Error fetching request validation failures metric: %v", err)
+			} else {
+				validationMetric.Inc()
+			}
+		}
+
+		if stats.UpstreamRetries > 0 {
+			retriesMetric, err := sc.upstreamRetries.GetMetricWith(latencyLabels)
+			if err != nil {
+				klog.Errorf("Error fetching upstream retries metric: %v", err)
+			} else {
+				retriesMetric.Add(stats.UpstreamRetries)
+			}
+		}
+
+		if stats.RetryBudgetExhausted {
+			budgetMetric, err := sc.retryBudgetExhausted.GetMetricWith(latencyLabels)
+			if err != nil {
+				klog.Errorf("Error fetching retry budget exhausted metric: %v", err)
+			} else {
+				budgetMetric.Inc()
+			}
+		}
+
+		if stats.ConcurrencyLimit != "" {
+			concurrencyMetric, err := sc.concurrencyLimited.GetMetricWith(prometheus.Labels{
+				"namespace": stats.Namespace,
+				"ingress":   stats.Ingress,
+				"service":   stats.Service,
+				"result":    stats.ConcurrencyLimit,
+			})
+			if err != nil {
+				klog.Errorf("Error fetching concurrency limit metric: %v", err)
+			} else {
+				concurrencyMetric.Inc()
+			}
+		}
+
+		if stats.GRPCStatus != "" {
+			grpcLabels := prometheus.Labels{
+				"namespace":    stats.Namespace,
+				"ingress":      stats.Ingress,
+				"service":      stats.Service,
+				"grpc_service": stats.GRPCService,
+				"grpc_method":  stats.GRPCMethod,
+			}
+
+			if stats.RequestTime != -1 {
+				grpcTimeMetric, err := sc.grpcRequestTime.GetMetricWith(grpcLabels)
+				if err != nil {
+					klog.Errorf("Error fetching gRPC request duration metric: %v", err)
+				} else {
+					grpcTimeMetric.Observe(stats.RequestTime)
+				}
+			}
+
+			grpcLabels["grpc_status"] = stats.GRPCStatus
+			grpcMetric, err := sc.grpcRequests.GetMetricWith(grpcLabels)
+			if err != nil {
+				klog.Errorf("Error fetching gRPC requests metric: %v", err)
+			} else {
+				grpcMetric.Inc()
+			}
+		}
+
+		if stats.UpgradeOutcome != "" {
+			upgradedMetric, err := sc.upgradedConnections.GetMetricWith(prometheus.Labels{
+				"namespace": stats.Namespace,
+				"ingress":   stats.Ingress,
+				"service":   stats.Service,
+				"outcome":   stats.UpgradeOutcome,
+			})
+			if err != nil {
+				klog.Errorf("Error fetching upgraded connections metric: %v", err)
+			} else {
+				upgradedMetric.Inc()
+			}
+		}
+
 		if stats.Latency != -1 {
 			latencyMetric, err := sc.upstreamLatency.GetMetricWith(latencyLabels)
 			if err != nil {
@@ -312,6 +675,75 @@ func (sc *SocketCollector) handleMessage(msg []byte) {
 	}
 }
 
+func (sc *SocketCollector) handleStreamData(stats *streamData) {
+	labels := prometheus.Labels{
+		"protocol":  stats.Protocol,
+		"namespace": stats.Namespace,
+		"service":   stats.Service,
+		"port":      stats.Port,
+	}
+
+	sessionLabels := prometheus.Labels{"status": stats.Status}
+	for k, v := range labels {
+		sessionLabels[k] = v
+	}
+	sessionsMetric, err := sc.streamSessions.GetMetricWith(sessionLabels)
+	if err != nil {
+		klog.Errorf("Error fetching stream sessions metric: %v", err)
+	} else {
+		sessionsMetric.Inc()
+	}
+
+	if stats.Blocked != "" {
+		blockedLabels := prometheus.Labels{"reason": stats.Blocked}
+		for k, v := range labels {
+			blockedLabels[k] = v
+		}
+		blockedMetric, err := sc.streamSessionsBlocked.GetMetricWith(blockedLabels)
+		if err != nil {
+			klog.Errorf("Error fetching stream sessions blocked metric: %v", err)
+		} else {
+			blockedMetric.Inc()
+		}
+	}
+
+	if stats.BytesSent != -1 {
+		bytesSentMetric, err := sc.streamBytesSent.GetMetricWith(labels)
+		if err != nil {
+			klog.Errorf("Error fetching stream bytes sent metric: %v", err)
+		} else {
+			bytesSentMetric.Add(stats.BytesSent)
+		}
+	}
+
+	if stats.BytesReceived != -1 {
+		bytesReceivedMetric, err := sc.streamBytesReceived.GetMetricWith(labels)
+		if err != nil {
+			klog.Errorf("Error fetching stream bytes received metric: %v", err)
+		} else {
+			bytesReceivedMetric.Add(stats.BytesReceived)
+		}
+	}
+
+	if stats.SessionTime != -1 {
+		sessionTimeMetric, err := sc.streamSessionTime.GetMetricWith(labels)
+		if err != nil {
+			klog.Errorf("Error fetching stream session time metric: %v", err)
+		} else {
+			sessionTimeMetric.Observe(stats.SessionTime)
+		}
+	}
+
+	if stats.UpstreamConnectTime != -1 {
+		connectTimeMetric, err := sc.streamConnectTime.GetMetricWith(labels)
+		if err != nil {
+			klog.Errorf("Error fetching stream upstream connect time metric: %v", err)
+		} else {
+			connectTimeMetric.Observe(stats.UpstreamConnectTime)
+		}
+	}
+}
+
 // Start listen for connections in the unix socket and spawns a goroutine to process the content
 func (sc *SocketCollector) Start() {
 	for {
@@ -389,17 +821,87 @@ func (sc *SocketCollector) RemoveMetrics(ingresses []string, registry prometheus
 					klog.V(2).Infof("metric %v for ingress %v with labels not removed: %v", metricName, ingKey, labels)
 				}
 			}
+
+			c, ok := metric.(*prometheus.CounterVec)
+			if ok {
+				removed := c.Delete(labels)
+				if !removed {
+					klog.V(2).Infof("metric %v for ingress %v with labels not removed: %v", metricName, ingKey, labels)
+				}
+			}
 		}
 	}
 
 }
 
+// RemoveStreamMetrics deletes the metrics of the TCP and UDP services that are
+// not available anymore. Services are identified by a key with the format
+// <protocol>/<namespace>/<service>/<port>, where port is the listening port.
+func (sc *SocketCollector) RemoveStreamMetrics(services []string, registry prometheus.Gatherer) {
+	mfs, err := registry.Gather()
+	if err != nil {
+		klog.Errorf("Error gathering metrics: %v", err)
+		return
+	}
+
+	klog.V(2).Infof("removing stream services %v from metrics", services)
+	toRemove := sets.NewString(services...)
+	for _, mf := range mfs {
+		metricName := mf.GetName()
+		metric, ok := sc.streamMetricMapping[metricName]
+		if !ok {
+			continue
+		}
+
+		for _, m := range mf.GetMetric() {
+			labels := make(map[string]string, len(m.GetLabel()))
+			for _, labelPair := range m.GetLabel() {
+				labels[*labelPair.Name] = *labelPair.Value
+			}
+
+			// remove labels that are constant
+			deleteConstants(labels)
+
+			svcKey := fmt.Sprintf("%v/%v/%v/%v", labels["protocol"], labels["namespace"], labels["service"], labels["port"])
+			if !toRemove.Has(svcKey) {
+				continue
+			}
+
+			var removed bool
+			switch v := metric.(type) {
+			case *prometheus.CounterVec:
+				removed = v.Delete(labels)
+			case *prometheus.HistogramVec:
+				removed = v.Delete(labels)
+			}
+
+			if !removed {
+				klog.V(2).Infof("metric %v for stream service %v with labels not removed: %v", metricName, svcKey, labels)
+			}
+		}
+	}
+}
+
 // Describe implements prometheus.Collector
 func (sc SocketCollector) Describe(ch chan<- *prometheus.Desc) {
 	sc.requestTime.Describe(ch)
 	sc.requestLength.Describe(ch)
 
 	sc.requests.Describe(ch)
+	sc.cacheResponses.Describe(ch)
+	sc.validationFailures.Describe(ch)
+	sc.upstreamRetries.Describe(ch)
+	sc.retryBudgetExhausted.Describe(ch)
+	sc.concurrencyLimited.Describe(ch)
+	sc.grpcRequests.Describe(ch)
+	sc.grpcRequestTime.Describe(ch)
+	sc.upgradedConnections.Describe(ch)
+	sc.streamSessions.Describe(ch)
+	sc.streamSessionsBlocked.Describe(ch)
+	sc.streamBytesSent.Describe(ch)
+	sc.streamBytesReceived.Describe(ch)
+	sc.streamSessionTime.Describe(ch)
+	sc.streamConnectTime.Describe(ch)
 
 	sc.upstreamLatency.Describe(ch)
 
@@ -415,6 +917,20 @@ func (sc SocketCollector) Collect(ch chan<- prometheus.Metric) {
 	sc.requestLength.Collect(ch)
 
 	sc.requests.Collect(ch)
+	sc.cacheResponses.Collect(ch)
+	sc.validationFailures.Collect(ch)
+	sc.upstreamRetries.Collect(ch)
+	sc.retryBudgetExhausted.Collect(ch)
+	sc.concurrencyLimited.Collect(ch)
+	sc.grpcRequests.Collect(ch)
+	sc.grpcRequestTime.Collect(ch)
+	sc.upgradedConnections.Collect(ch)
+	sc.streamSessions.Collect(ch)
+	sc.streamSessionsBlocked.Collect(ch)
+	sc.streamBytesSent.Collect(ch)
+	sc.streamBytesReceived.Collect(ch)
+	sc.streamSessionTime.Collect(ch)
+	sc.streamConnectTime.Collect(ch)
 
 	sc.upstreamLatency.Collect(ch)
 
@@ -430,6 +946,11 @@ func (sc *SocketCollector) SetHosts(hosts sets.String) {
 	sc.hosts = hosts
 }
 
+// TrafficStats returns the rolling traffic statistics per Ingress, host and service
+func (sc *SocketCollector) TrafficStats() []traffic.Stats {
+	return sc.traffic.Stats()
+}
+
 // handleMessages process the content received in a network connection
 func handleMessages(conn io.ReadCloser, fn func([]byte)) {
 	defer conn.Close()
@@ -69,12 +69,13 @@ func TestNewUDPLogListener(t *testing.T) {
 
 func TestCollector(t *testing.T) {
 	cases := []struct {
-		name            string
-		data            []string
-		metrics         []string
-		wantBefore      string
-		removeIngresses []string
-		wantAfter       string
+		name                 string
+		data                 []string
+		metrics              []string
+		wantBefore           string
+		removeIngresses      []string
+		removeStreamServices []string
+		wantAfter            string
 	}{
 		{
 			name: "invalid metric object should not increase prometheus metrics",
@@ -282,6 +283,416 @@ func TestCollector(t *testing.T) {
 			wantAfter: `
 			`,
 		},
+		{
+			name: "responses of locations using a cache zone should update the cache metric",
+			data: []string{`[{
+				"host":"testshop.com",
+				"status":"200",
+				"method":"GET",
+				"path":"/static",
+				"requestLength":-1,
+				"requestTime":-1,
+				"upstreamLatency":-1,
+				"upstreamResponseTime":-1,
+				"responseLength":-1,
+				"namespace":"test-app-production",
+				"ingress":"web-yml",
+				"service":"test-app",
+				"cacheStatus":"HIT"
+			},{
+				"host":"testshop.com",
+				"status":"200",
+				"method":"GET",
+				"path":"/static",
+				"requestLength":-1,
+				"requestTime":-1,
+				"upstreamLatency":-1,
+				"upstreamResponseTime":-1,
+				"responseLength":-1,
+				"namespace":"test-app-production",
+				"ingress":"web-yml",
+				"service":"test-app",
+				"cacheStatus":"HIT"
+			},{
+				"host":"testshop.com",
+				"status":"200",
+				"method":"GET",
+				"path":"/static",
+				"requestLength":-1,
+				"requestTime":-1,
+				"upstreamLatency":-1,
+				"upstreamResponseTime":-1,
+				"responseLength":-1,
+				"namespace":"test-app-production",
+				"ingress":"web-yml",
+				"service":"test-app",
+				"cacheStatus":"MISS"
+			}]`},
+			metrics: []string{"nginx_ingress_controller_cache_responses_total"},
+			wantBefore: `
+				# HELP nginx_ingress_controller_cache_responses_total The total number of responses of locations using a cache zone by cache status
+				# TYPE nginx_ingress_controller_cache_responses_total counter
+				nginx_ingress_controller_cache_responses_total{cache_status="HIT",controller_class="ingress",controller_namespace="default",controller_pod="pod",ingress="web-yml",namespace="test-app-production",service="test-app"} 2
+				nginx_ingress_controller_cache_responses_total{cache_status="MISS",controller_class="ingress",controller_namespace="default",controller_pod="pod",ingress="web-yml",namespace="test-app-production",service="test-app"} 1
+			`,
+			removeIngresses: []string{"test-app-production/web-yml"},
+			wantAfter: `
+			`,
+		},
+		{
+			name: "requests rejected by the request validation should update the validation metric",
+			data: []string{`[{
+				"host":"testshop.com",
+				"status":"400",
+				"method":"POST",
+				"path":"/orders",
+				"requestLength":-1,
+				"requestTime":-1,
+				"upstreamLatency":-1,
+				"upstreamResponseTime":-1,
+				"responseLength":-1,
+				"namespace":"test-app-production",
+				"ingress":"web-yml",
+				"service":"test-app",
+				"validationError":"schema"
+			}]`},
+			metrics: []string{"nginx_ingress_controller_request_validation_failures_total"},
+			wantBefore: `
+				# HELP nginx_ingress_controller_request_validation_failures_total The total number of requests rejected by the request validation by reason
+				# TYPE nginx_ingress_controller_request_validation_failures_total counter
+				nginx_ingress_controller_request_validation_failures_total{controller_class="ingress",controller_namespace="default",controller_pod="pod",ingress="web-yml",namespace="test-app-production",reason="schema",service="test-app"} 1
+			`,
+			removeIngresses: []string{"test-app-production/web-yml"},
+			wantAfter: `
+			`,
+		},
+		{
+			name: "retried requests should update the retry metrics",
+			data: []string{`[{
+				"host":"testshop.com",
+				"status":"503",
+				"method":"GET",
+				"path":"/admin",
+				"requestLength":-1,
+				"requestTime":-1,
+				"upstreamLatency":-1,
+				"upstreamResponseTime":-1,
+				"responseLength":-1,
+				"namespace":"test-app-production",
+				"ingress":"web-yml",
+				"service":"test-app",
+				"upstreamRetries":2,
+				"retryBudgetExhausted":true
+			}]`},
+			metrics: []string{"nginx_ingress_controller_upstream_retries_total", "nginx_ingress_controller_retry_budget_exhausted_total"},
+			wantBefore: `
+				# HELP nginx_ingress_controller_retry_budget_exhausted_total The total number of retries rejected by the retry budget
+				# TYPE nginx_ingress_controller_retry_budget_exhausted_total counter
+				nginx_ingress_controller_retry_budget_exhausted_total{controller_class="ingress",controller_namespace="default",controller_pod="pod",ingress="web-yml",namespace="test-app-production",service="test-app"} 1
+				# HELP nginx_ingress_controller_upstream_retries_total The total number of retries in another upstream server
+				# TYPE nginx_ingress_controller_upstream_retries_total counter
+				nginx_ingress_controller_upstream_retries_total{controller_class="ingress",controller_namespace="default",controller_pod="pod",ingress="web-yml",namespace="test-app-production",service="test-app"} 2
+			`,
+			removeIngresses: []string{"test-app-production/web-yml"},
+			wantAfter: `
+			`,
+		},
+		{
+			name: "requests limited by the concurrency limit should update the concurrency metric",
+			data: []string{`[{
+				"host":"testshop.com",
+				"status":"503",
+				"method":"GET",
+				"path":"/admin",
+				"requestLength":-1,
+				"requestTime":-1,
+				"upstreamLatency":-1,
+				"upstreamResponseTime":-1,
+				"responseLength":-1,
+				"namespace":"test-app-production",
+				"ingress":"web-yml",
+				"service":"test-app",
+				"concurrencyLimit":"rejected"
+			}]`},
+			metrics: []string{"nginx_ingress_controller_upstream_concurrency_limited_total"},
+			wantBefore: `
+				# HELP nginx_ingress_controller_upstream_concurrency_limited_total The total number of requests queued or rejected by the limit of concurrent requests of the backend
+				# TYPE nginx_ingress_controller_upstream_concurrency_limited_total counter
+				nginx_ingress_controller_upstream_concurrency_limited_total{controller_class="ingress",controller_namespace="default",controller_pod="pod",ingress="web-yml",namespace="test-app-production",result="rejected",service="test-app"} 1
+			`,
+			removeIngresses: []string{"test-app-production/web-yml"},
+			wantAfter: `
+			`,
+		},
+		{
+			name: "gRPC requests should update the gRPC metrics",
+			data: []string{`[{
+				"host":"testshop.com",
+				"status":"200",
+				"method":"POST",
+				"path":"/",
+				"requestLength":-1,
+				"requestTime":0.05,
+				"upstreamLatency":-1,
+				"upstreamResponseTime":-1,
+				"responseLength":-1,
+				"namespace":"test-app-production",
+				"ingress":"web-yml",
+				"service":"greeter",
+				"grpcService":"helloworld.Greeter",
+				"grpcMethod":"SayHello",
+				"grpcStatus":"0"
+			},{
+				"host":"testshop.com",
+				"status":"502",
+				"method":"POST",
+				"path":"/",
+				"requestLength":-1,
+				"requestTime":-1,
+				"upstreamLatency":-1,
+				"upstreamResponseTime":-1,
+				"responseLength":-1,
+				"namespace":"test-app-production",
+				"ingress":"web-yml",
+				"service":"greeter",
+				"grpcService":"helloworld.Greeter",
+				"grpcMethod":"SayHello",
+				"grpcStatus":"14"
+			}]`},
+			metrics: []string{"nginx_ingress_controller_grpc_requests_total"},
+			wantBefore: `
+				# HELP nginx_ingress_controller_grpc_requests_total The total number of gRPC requests by gRPC service, method and status
+				# TYPE nginx_ingress_controller_grpc_requests_total counter
+				nginx_ingress_controller_grpc_requests_total{controller_class="ingress",controller_namespace="default",controller_pod="pod",grpc_method="SayHello",grpc_service="helloworld.Greeter",grpc_status="0",ingress="web-yml",namespace="test-app-production",service="greeter"} 1
+				nginx_ingress_controller_grpc_requests_total{controller_class="ingress",controller_namespace="default",controller_pod="pod",grpc_method="SayHello",grpc_service="helloworld.Greeter",grpc_status="14",ingress="web-yml",namespace="test-app-production",service="greeter"} 1
+			`,
+			removeIngresses: []string{"test-app-production/web-yml"},
+			wantAfter: `
+			`,
+		},
+		{
+			name: "upgraded connections should update the upgraded connections metric",
+			data: []string{`[{
+				"host":"testshop.com",
+				"status":"101",
+				"method":"GET",
+				"path":"/ws",
+				"requestLength":-1,
+				"requestTime":-1,
+				"upstreamLatency":-1,
+				"upstreamResponseTime":-1,
+				"responseLength":-1,
+				"namespace":"test-app-production",
+				"ingress":"web-yml",
+				"service":"chat",
+				"upgradeOutcome":"drained"
+			},{
+				"host":"testshop.com",
+				"status":"101",
+				"method":"GET",
+				"path":"/ws",
+				"requestLength":-1,
+				"requestTime":-1,
+				"upstreamLatency":-1,
+				"upstreamResponseTime":-1,
+				"responseLength":-1,
+				"namespace":"test-app-production",
+				"ingress":"web-yml",
+				"service":"chat",
+				"upgradeOutcome":"killed"
+			},{
+				"host":"testshop.com",
+				"status":"101",
+				"method":"GET",
+				"path":"/ws",
+				"requestLength":-1,
+				"requestTime":-1,
+				"upstreamLatency":-1,
+				"upstreamResponseTime":-1,
+				"responseLength":-1,
+				"namespace":"test-app-production",
+				"ingress":"web-yml",
+				"service":"chat",
+				"upgradeOutcome":"killed"
+			}]`},
+			metrics: []string{"nginx_ingress_controller_upgraded_connections_total"},
+			wantBefore: `
+				# HELP nginx_ingress_controller_upgraded_connections_total The total number of WebSocket and other upgraded connections by the way they ended: closed, drained or killed on reload or shutdown
+				# TYPE nginx_ingress_controller_upgraded_connections_total counter
+				nginx_ingress_controller_upgraded_connections_total{controller_class="ingress",controller_namespace="default",controller_pod="pod",ingress="web-yml",namespace="test-app-production",outcome="drained",service="chat"} 1
+				nginx_ingress_controller_upgraded_connections_total{controller_class="ingress",controller_namespace="default",controller_pod="pod",ingress="web-yml",namespace="test-app-production",outcome="killed",service="chat"} 2
+			`,
+			removeIngresses: []string{"test-app-production/web-yml"},
+			wantAfter: `
+			`,
+		},
+		{
+			name: "blocked stream sessions should update the stream sessions blocked metric",
+			data: []string{`[{
+				"stream":{
+					"protocol":"tcp",
+					"namespace":"default",
+					"service":"postgres",
+					"port":"5432",
+					"status":"403",
+					"bytesSent":0,
+					"bytesReceived":0,
+					"sessionTime":0,
+					"upstreamConnectTime":-1,
+					"blocked":"denied"
+				}
+			},{
+				"stream":{
+					"protocol":"tcp",
+					"namespace":"-",
+					"service":"-",
+					"port":"443",
+					"status":"503",
+					"bytesSent":0,
+					"bytesReceived":0,
+					"sessionTime":0,
+					"upstreamConnectTime":-1,
+					"blocked":"limited"
+				}
+			}]`},
+			metrics: []string{"nginx_ingress_controller_stream_sessions_blocked_total"},
+			wantBefore: `
+				# HELP nginx_ingress_controller_stream_sessions_blocked_total The total number of TCP and UDP sessions closed by the access rules or the connection limit of the service
+				# TYPE nginx_ingress_controller_stream_sessions_blocked_total counter
+				nginx_ingress_controller_stream_sessions_blocked_total{controller_class="ingress",controller_namespace="default",controller_pod="pod",namespace="-",port="443",protocol="tcp",reason="limited",service="-"} 1
+				nginx_ingress_controller_stream_sessions_blocked_total{controller_class="ingress",controller_namespace="default",controller_pod="pod",namespace="default",port="5432",protocol="tcp",reason="denied",service="postgres"} 1
+			`,
+			removeIngresses: []string{"test-app-production/web-yml"},
+			wantAfter: `
+				# HELP nginx_ingress_controller_stream_sessions_blocked_total The total number of TCP and UDP sessions closed by the access rules or the connection limit of the service
+				# TYPE nginx_ingress_controller_stream_sessions_blocked_total counter
+				nginx_ingress_controller_stream_sessions_blocked_total{controller_class="ingress",controller_namespace="default",controller_pod="pod",namespace="-",port="443",protocol="tcp",reason="limited",service="-"} 1
+				nginx_ingress_controller_stream_sessions_blocked_total{controller_class="ingress",controller_namespace="default",controller_pod="pod",namespace="default",port="5432",protocol="tcp",reason="denied",service="postgres"} 1
+			`,
+		},
+		{
+			name: "stream sessions should update the stream session metrics",
+			data: []string{`[{
+				"stream":{
+					"protocol":"tcp",
+					"namespace":"default",
+					"service":"postgres",
+					"port":"5432",
+					"status":"200",
+					"bytesSent":512,
+					"bytesReceived":256,
+					"sessionTime":1.25,
+					"upstreamConnectTime":0.002
+				}
+			},{
+				"stream":{
+					"protocol":"tcp",
+					"namespace":"default",
+					"service":"postgres",
+					"port":"5432",
+					"status":"502",
+					"bytesSent":0,
+					"bytesReceived":128,
+					"sessionTime":0.5,
+					"upstreamConnectTime":-1
+				}
+			}]`},
+			metrics: []string{
+				"nginx_ingress_controller_stream_sessions_total",
+				"nginx_ingress_controller_stream_bytes_sent_total",
+				"nginx_ingress_controller_stream_bytes_received_total",
+				"nginx_ingress_controller_stream_upstream_connect_duration_seconds",
+			},
+			wantBefore: `
+				# HELP nginx_ingress_controller_stream_bytes_received_total The total number of bytes received from the clients of TCP and UDP services
+				# TYPE nginx_ingress_controller_stream_bytes_received_total counter
+				nginx_ingress_controller_stream_bytes_received_total{controller_class="ingress",controller_namespace="default",controller_pod="pod",namespace="default",port="5432",protocol="tcp",service="postgres"} 384
+				# HELP nginx_ingress_controller_stream_bytes_sent_total The total number of bytes sent to the clients of TCP and UDP services
+				# TYPE nginx_ingress_controller_stream_bytes_sent_total counter
+				nginx_ingress_controller_stream_bytes_sent_total{controller_class="ingress",controller_namespace="default",controller_pod="pod",namespace="default",port="5432",protocol="tcp",service="postgres"} 512
+				# HELP nginx_ingress_controller_stream_sessions_total The total number of TCP and UDP sessions
+				# TYPE nginx_ingress_controller_stream_sessions_total counter
+				nginx_ingress_controller_stream_sessions_total{controller_class="ingress",controller_namespace="default",controller_pod="pod",namespace="default",port="5432",protocol="tcp",service="postgres",status="200"} 1
+				nginx_ingress_controller_stream_sessions_total{controller_class="ingress",controller_namespace="default",controller_pod="pod",namespace="default",port="5432",protocol="tcp",service="postgres",status="502"} 1
+				# HELP nginx_ingress_controller_stream_upstream_connect_duration_seconds The time spent on establishing the connections to the upstream servers of TCP and UDP services
+				# TYPE nginx_ingress_controller_stream_upstream_connect_duration_seconds histogram
+				nginx_ingress_controller_stream_upstream_connect_duration_seconds_bucket{controller_class="ingress",controller_namespace="default",controller_pod="pod",namespace="default",port="5432",protocol="tcp",service="postgres",le="0.005"} 1
+				nginx_ingress_controller_stream_upstream_connect_duration_seconds_bucket{controller_class="ingress",controller_namespace="default",controller_pod="pod",namespace="default",port="5432",protocol="tcp",service="postgres",le="0.01"} 1
+				nginx_ingress_controller_stream_upstream_connect_duration_seconds_bucket{controller_class="ingress",controller_namespace="default",controller_pod="pod",namespace="default",port="5432",protocol="tcp",service="postgres",le="0.025"} 1
+				nginx_ingress_controller_stream_upstream_connect_duration_seconds_bucket{controller_class="ingress",controller_namespace="default",controller_pod="pod",namespace="default",port="5432",protocol="tcp",service="postgres",le="0.05"} 1
+				nginx_ingress_controller_stream_upstream_connect_duration_seconds_bucket{controller_class="ingress",controller_namespace="default",controller_pod="pod",namespace="default",port="5432",protocol="tcp",service="postgres",le="0.1"} 1
+				nginx_ingress_controller_stream_upstream_connect_duration_seconds_bucket{controller_class="ingress",controller_namespace="default",controller_pod="pod",namespace="default",port="5432",protocol="tcp",service="postgres",le="0.25"} 1
+				nginx_ingress_controller_stream_upstream_connect_duration_seconds_bucket{controller_class="ingress",controller_namespace="default",controller_pod="pod",namespace="default",port="5432",protocol="tcp",service="postgres",le="0.5"} 1
+				nginx_ingress_controller_stream_upstream_connect_duration_seconds_bucket{controller_class="ingress",controller_namespace="default",controller_pod="pod",namespace="default",port="5432",protocol="tcp",service="postgres",le="1"} 1
+				nginx_ingress_controller_stream_upstream_connect_duration_seconds_bucket{controller_class="ingress",controller_namespace="default",controller_pod="pod",namespace="default",port="5432",protocol="tcp",service="postgres",le="2.5"} 1
+				nginx_ingress_controller_stream_upstream_connect_duration_seconds_bucket{controller_class="ingress",controller_namespace="default",controller_pod="pod",namespace="default",port="5432",protocol="tcp",service="postgres",le="5"} 1
+				nginx_ingress_controller_stream_upstream_connect_duration_seconds_bucket{controller_class="ingress",controller_namespace="default",controller_pod="pod",namespace="default",port="5432",protocol="tcp",service="postgres",le="10"} 1
+				nginx_ingress_controller_stream_upstream_connect_duration_seconds_bucket{controller_class="ingress",controller_namespace="default",controller_pod="pod",namespace="default",port="5432",protocol="tcp",service="postgres",le="+Inf"} 1
+				nginx_ingress_controller_stream_upstream_connect_duration_seconds_sum{controller_class="ingress",controller_namespace="default",controller_pod="pod",namespace="default",port="5432",protocol="tcp",service="postgres"} 0.002
+				nginx_ingress_controller_stream_upstream_connect_duration_seconds_count{controller_class="ingress",controller_namespace="default",controller_pod="pod",namespace="default",port="5432",protocol="tcp",service="postgres"} 1
+			`,
+		},
+		{
+			name: "removed stream services should remove the stream metrics",
+			data: []string{`[{
+				"stream":{
+					"protocol":"tcp",
+					"namespace":"default",
+					"service":"postgres",
+					"port":"5432",
+					"status":"200",
+					"bytesSent":512,
+					"bytesReceived":256,
+					"sessionTime":1.25,
+					"upstreamConnectTime":0.002
+				}
+			},{
+				"stream":{
+					"protocol":"udp",
+					"namespace":"kube-system",
+					"service":"kube-dns",
+					"port":"53",
+					"status":"200",
+					"bytesSent":64,
+					"bytesReceived":32,
+					"sessionTime":0.01,
+					"upstreamConnectTime":-1
+				}
+			},{
+				"stream":{
+					"protocol":"tcp",
+					"namespace":"default",
+					"service":"postgres",
+					"port":"5432",
+					"status":"403",
+					"bytesSent":0,
+					"bytesReceived":0,
+					"sessionTime":0,
+					"upstreamConnectTime":-1,
+					"blocked":"denied"
+				}
+			}]`},
+			metrics: []string{
+				"nginx_ingress_controller_stream_sessions_total",
+				"nginx_ingress_controller_stream_sessions_blocked_total",
+			},
+			wantBefore: `
+				# HELP nginx_ingress_controller_stream_sessions_blocked_total The total number of TCP and UDP sessions closed by the access rules or the connection limit of the service
+				# TYPE nginx_ingress_controller_stream_sessions_blocked_total counter
+				nginx_ingress_controller_stream_sessions_blocked_total{controller_class="ingress",controller_namespace="default",controller_pod="pod",namespace="default",port="5432",protocol="tcp",reason="denied",service="postgres"} 1
+				# HELP nginx_ingress_controller_stream_sessions_total The total number of TCP and UDP sessions
+				# TYPE nginx_ingress_controller_stream_sessions_total counter
+				nginx_ingress_controller_stream_sessions_total{controller_class="ingress",controller_namespace="default",controller_pod="pod",namespace="default",port="5432",protocol="tcp",service="postgres",status="200"} 1
+				nginx_ingress_controller_stream_sessions_total{controller_class="ingress",controller_namespace="default",controller_pod="pod",namespace="default",port="5432",protocol="tcp",service="postgres",status="403"} 1
+				nginx_ingress_controller_stream_sessions_total{controller_class="ingress",controller_namespace="default",controller_pod="pod",namespace="kube-system",port="53",protocol="udp",service="kube-dns",status="200"} 1
+			`,
+			removeStreamServices: []string{"tcp/default/postgres/5432"},
+			wantAfter: `
+				# HELP nginx_ingress_controller_stream_sessions_total The total number of TCP and UDP sessions
+				# TYPE nginx_ingress_controller_stream_sessions_total counter
+				nginx_ingress_controller_stream_sessions_total{controller_class="ingress",controller_namespace="default",controller_pod="pod",namespace="kube-system",port="53",protocol="udp",service="kube-dns",status="200"} 1
+			`,
+		},
 	}
 
 	for _, c := range cases {
@@ -316,6 +727,14 @@ func TestCollector(t *testing.T) {
 				}
 			}
 
+			if len(c.removeStreamServices) > 0 {
+				sc.RemoveStreamMetrics(c.removeStreamServices, registry)
+
+				if err := GatherAndCompare(sc, c.wantAfter, c.metrics, registry); err != nil {
+					t.Errorf("unexpected collecting result:\n%s", err)
+				}
+			}
+
 			sc.Stop()
 
 			registry.Unregister(sc)
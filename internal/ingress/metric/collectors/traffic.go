@@ -0,0 +1,186 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collectors
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"k8s.io/ingress-nginx/internal/ingress/metric/traffic"
+)
+
+const (
+	// trafficWindow is the number of seconds used to compute the rolling
+	// traffic statistics
+	trafficWindow = 60
+	// maxLatencySamples is the maximum number of latencies kept per second
+	// to estimate the percentiles
+	maxLatencySamples = 100
+)
+
+type trafficKey struct {
+	namespace string
+	ingress   string
+	host      string
+	service   string
+}
+
+type trafficBucket struct {
+	second   int64
+	requests uint64
+	errors   uint64
+
+	latencies []float64
+}
+
+// trafficTracker keeps per second buckets of the requests received by each
+// Ingress, host and service in the last trafficWindow seconds
+type trafficTracker struct {
+	mu sync.Mutex
+
+	series map[trafficKey]*[trafficWindow]trafficBucket
+
+	// started is used to compute the rates over the time elapsed since
+	// the tracker was created while it is shorter than the window
+	started time.Time
+	now     func() time.Time
+}
+
+func newTrafficTracker() *trafficTracker {
+	return &trafficTracker{
+		series:  make(map[trafficKey]*[trafficWindow]trafficBucket),
+		started: time.Now(),
+		now:     time.Now,
+	}
+}
+
+// Record adds a request to the statistics. A negative latency means the
+// request time is unknown.
+func (t *trafficTracker) Record(key trafficKey, status string, latency float64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	buckets, ok := t.series[key]
+	if !ok {
+		buckets = &[trafficWindow]trafficBucket{}
+		t.series[key] = buckets
+	}
+
+	second := t.now().Unix()
+	b := &buckets[second%trafficWindow]
+	if b.second != second {
+		*b = trafficBucket{second: second}
+	}
+
+	b.requests++
+	if code, err := strconv.Atoi(status); err == nil && code >= 500 {
+		b.errors++
+	}
+
+	if latency < 0 {
+		return
+	}
+
+	// reservoir sampling keeps an uniform sample of the latencies
+	if len(b.latencies) < maxLatencySamples {
+		b.latencies = append(b.latencies, latency)
+	} else if i := rand.Int63n(int64(b.requests)); i < maxLatencySamples {
+		b.latencies[i] = latency
+	}
+}
+
+// Stats returns the statistics of the series with requests in the window,
+// removing the series without requests
+func (t *trafficTracker) Stats() []traffic.Stats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	oldest := now.Unix() - trafficWindow
+
+	window := now.Sub(t.started).Seconds()
+	if window > trafficWindow {
+		window = trafficWindow
+	}
+	if window < 1 {
+		window = 1
+	}
+
+	stats := []traffic.Stats{}
+	for key, buckets := range t.series {
+		var requests, errors uint64
+		var latencies []float64
+
+		for _, b := range buckets {
+			if b.second <= oldest {
+				continue
+			}
+
+			requests += b.requests
+			errors += b.errors
+			latencies = append(latencies, b.latencies...)
+		}
+
+		if requests == 0 {
+			delete(t.series, key)
+			continue
+		}
+
+		sort.Float64s(latencies)
+
+		stats = append(stats, traffic.Stats{
+			Namespace:         key.namespace,
+			Ingress:           key.ingress,
+			Host:              key.host,
+			Service:           key.service,
+			Requests:          requests,
+			RequestsPerSecond: float64(requests) / window,
+			ErrorRate:         float64(errors) / float64(requests),
+			LatencyP50:        percentile(latencies, 0.5),
+			LatencyP99:        percentile(latencies, 0.99),
+		})
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Requests != stats[j].Requests {
+			return stats[i].Requests > stats[j].Requests
+		}
+
+		return stats[i].Namespace+stats[i].Ingress+stats[i].Host+stats[i].Service <
+			stats[j].Namespace+stats[j].Ingress+stats[j].Host+stats[j].Service
+	})
+
+	return stats
+}
+
+// percentile returns the q-th quantile of sorted values
+func percentile(sorted []float64, q float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	i := int(math.Ceil(q*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	}
+
+	return sorted[i]
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collectors
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTrafficTracker(t *testing.T) {
+	now := time.Unix(1000, 0)
+
+	tt := newTrafficTracker()
+	tt.started = now.Add(-time.Hour)
+	tt.now = func() time.Time { return now }
+
+	demo := trafficKey{namespace: "default", ingress: "demo", host: "demo.com", service: "demo"}
+	other := trafficKey{namespace: "default", ingress: "other", host: "other.com", service: "other"}
+
+	for i := 1; i <= 100; i++ {
+		status := "200"
+		if i%10 == 0 {
+			status = "503"
+		}
+		tt.Record(demo, status, float64(i)/1000)
+	}
+
+	now = now.Add(30 * time.Second)
+	tt.Record(other, "404", -1)
+
+	stats := tt.Stats()
+	if len(stats) != 2 {
+		t.Fatalf("expected 2 series but got %v", len(stats))
+	}
+
+	s := stats[0]
+	if s.Ingress != "demo" || s.Requests != 100 {
+		t.Errorf("expected 100 requests for ingress demo but got %+v", s)
+	}
+	if s.RequestsPerSecond != 100.0/trafficWindow {
+		t.Errorf("expected %v requests per second but got %v", 100.0/trafficWindow, s.RequestsPerSecond)
+	}
+	if s.ErrorRate != 0.1 {
+		t.Errorf("expected an error rate of 0.1 but got %v", s.ErrorRate)
+	}
+	if s.LatencyP50 != 0.05 || s.LatencyP99 != 0.099 {
+		t.Errorf("expected latencies p50=0.05 and p99=0.099 but got p50=%v p99=%v", s.LatencyP50, s.LatencyP99)
+	}
+	if stats[1].ErrorRate != 0 || stats[1].LatencyP99 != 0 {
+		t.Errorf("expected no errors and no latency for ingress other but got %+v", stats[1])
+	}
+
+	now = now.Add(45 * time.Second)
+	stats = tt.Stats()
+	if len(stats) != 1 || stats[0].Ingress != "other" {
+		t.Errorf("expected only the requests of ingress other in the window but got %+v", stats)
+	}
+
+	now = now.Add(time.Minute)
+	stats = tt.Stats()
+	if len(stats) != 0 {
+		t.Errorf("expected no series without requests in the window but got %+v", stats)
+	}
+	if len(tt.series) != 0 {
+		t.Errorf("expected series without requests to be removed")
+	}
+}
+
+func TestTrafficTrackerRateSinceStart(t *testing.T) {
+	now := time.Unix(1000, 0)
+
+	tt := newTrafficTracker()
+	tt.started = now
+	tt.now = func() time.Time { return now }
+
+	key := trafficKey{namespace: "default", ingress: "demo"}
+	for i := 0; i < 20; i++ {
+		tt.Record(key, "200", 0.1)
+	}
+
+	// the first requests are counted over one second instead of nothing
+	if rps := tt.Stats()[0].RequestsPerSecond; rps != 20 {
+		t.Errorf("expected 20 requests per second right after the start but got %v", rps)
+	}
+
+	now = now.Add(10 * time.Second)
+	if rps := tt.Stats()[0].RequestsPerSecond; rps != 2 {
+		t.Errorf("expected 2 requests per second 10 seconds after the start but got %v", rps)
+	}
+}
+
+func TestTrafficTrackerSamplesLatencies(t *testing.T) {
+	tt := newTrafficTracker()
+
+	key := trafficKey{namespace: "default", ingress: "demo"}
+	for i := 0; i < 10*maxLatencySamples; i++ {
+		tt.Record(key, "200", 0.1)
+	}
+
+	for _, buckets := range tt.series {
+		for _, b := range buckets {
+			if len(b.latencies) > maxLatencySamples {
+				t.Errorf("expected at most %v latency samples but got %v", maxLatencySamples, len(b.latencies))
+			}
+		}
+	}
+}
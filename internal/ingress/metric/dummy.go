@@ -17,8 +17,11 @@ limitations under the License.
 package metric
 
 import (
+	"time"
+
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/ingress-nginx/internal/ingress"
+	"k8s.io/ingress-nginx/internal/ingress/metric/traffic"
 )
 
 // NewDummyCollector returns a dummy metric collector
@@ -38,9 +41,21 @@ func (dc DummyCollector) IncReloadCount() {}
 // IncReloadErrorCount ...
 func (dc DummyCollector) IncReloadErrorCount() {}
 
+// ObserveSyncPhase ...
+func (dc DummyCollector) ObserveSyncPhase(string, time.Duration) {}
+
+// IncConfigUpdateCount ...
+func (dc DummyCollector) IncConfigUpdateCount(bool, []string) {}
+
+// SetIngressConflicts ...
+func (dc DummyCollector) SetIngressConflicts(map[string]int) {}
+
 // RemoveMetrics ...
 func (dc DummyCollector) RemoveMetrics(ingresses, endpoints []string) {}
 
+// RemoveStreamMetrics ...
+func (dc DummyCollector) RemoveStreamMetrics(services []string) {}
+
 // Start ...
 func (dc DummyCollector) Start() {}
 
@@ -53,6 +68,11 @@ func (dc DummyCollector) SetSSLExpireTime([]*ingress.Server) {}
 // SetHosts ...
 func (dc DummyCollector) SetHosts(hosts sets.String) {}
 
+// TrafficStats ...
+func (dc DummyCollector) TrafficStats() []traffic.Stats {
+	return []traffic.Stats{}
+}
+
 // OnStartedLeading indicates the pod is not the current leader
 func (dc DummyCollector) OnStartedLeading(electionID string) {}
 
@@ -23,10 +23,12 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog"
 
 	"k8s.io/ingress-nginx/internal/ingress"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/class"
 	"k8s.io/ingress-nginx/internal/ingress/metric/collectors"
+	"k8s.io/ingress-nginx/internal/ingress/metric/traffic"
 )
 
 // Collector defines the interface for a metric collector
@@ -36,16 +38,31 @@ type Collector interface {
 	IncReloadCount()
 	IncReloadErrorCount()
 
+	// ObserveSyncPhase records the time spent in a phase of the synchronization loop
+	ObserveSyncPhase(string, time.Duration)
+	// IncConfigUpdateCount counts configuration updates applied dynamically or
+	// with a reload, including the reasons a reload was required
+	IncConfigUpdateCount(bool, []string)
+	// SetIngressConflicts sets the number of active conflicts between
+	// Ingresses by type of conflict
+	SetIngressConflicts(map[string]int)
+
 	OnStartedLeading(string)
 	OnStoppedLeading(string)
 
 	RemoveMetrics(ingresses, endpoints []string)
+	// RemoveStreamMetrics removes the metrics of TCP and UDP services, with
+	// the format <protocol>/<namespace>/<service>/<port>
+	RemoveStreamMetrics(services []string)
 
 	SetSSLExpireTime([]*ingress.Server)
 
 	// SetHosts sets the hostnames that are being served by the ingress controller
 	SetHosts(sets.String)
 
+	// TrafficStats returns the rolling traffic statistics per Ingress, host and service
+	TrafficStats() []traffic.Stats
+
 	Start()
 	Stop()
 }
@@ -59,10 +76,14 @@ type collector struct {
 	socket *collectors.SocketCollector
 
 	registry *prometheus.Registry
+
+	sinks  []Sink
+	stopCh chan struct{}
 }
 
-// NewCollector creates a new metric collector the for ingress controller
-func NewCollector(metricsPerHost bool, registry *prometheus.Registry) (Collector, error) {
+// NewCollector creates a new metric collector the for ingress controller.
+// Besides the Prometheus registry, the metrics are pushed to the optional sinks.
+func NewCollector(metricsPerHost, zoneMetrics bool, registry *prometheus.Registry, sinks ...Sink) (Collector, error) {
 	podNamespace := os.Getenv("POD_NAMESPACE")
 	if podNamespace == "" {
 		podNamespace = "default"
@@ -70,7 +91,7 @@ func NewCollector(metricsPerHost bool, registry *prometheus.Registry) (Collector
 
 	podName := os.Getenv("POD_NAME")
 
-	nc, err := collectors.NewNGINXStatus(podName, podNamespace, class.IngressClass)
+	nc, err := collectors.NewNGINXStatus(podName, podNamespace, class.IngressClass, zoneMetrics)
 	if err != nil {
 		return nil, err
 	}
@@ -96,6 +117,9 @@ func NewCollector(metricsPerHost bool, registry *prometheus.Registry) (Collector
 		socket: s,
 
 		registry: registry,
+
+		sinks:  sinks,
+		stopCh: make(chan struct{}),
 	}), nil
 }
 
@@ -111,11 +135,27 @@ func (c *collector) IncReloadErrorCount() {
 	c.ingressController.IncReloadErrorCount()
 }
 
+func (c *collector) ObserveSyncPhase(phase string, duration time.Duration) {
+	c.ingressController.ObserveSyncPhase(phase, duration)
+}
+
+func (c *collector) IncConfigUpdateCount(dynamic bool, reasons []string) {
+	c.ingressController.IncConfigUpdateCount(dynamic, reasons)
+}
+
+func (c *collector) SetIngressConflicts(conflicts map[string]int) {
+	c.ingressController.SetIngressConflicts(conflicts)
+}
+
 func (c *collector) RemoveMetrics(ingresses, hosts []string) {
 	c.socket.RemoveMetrics(ingresses, c.registry)
 	c.ingressController.RemoveMetrics(hosts, c.registry)
 }
 
+func (c *collector) RemoveStreamMetrics(services []string) {
+	c.socket.RemoveStreamMetrics(services, c.registry)
+}
+
 func (c *collector) Start() {
 	c.registry.MustRegister(c.nginxStatus)
 	c.registry.MustRegister(c.nginxProcess)
@@ -130,6 +170,10 @@ func (c *collector) Start() {
 	}()
 	go c.nginxProcess.Start()
 	go c.socket.Start()
+
+	for _, sink := range c.sinks {
+		go runSink(sink, c.registry, c.stopCh)
+	}
 }
 
 func (c *collector) Stop() {
@@ -141,6 +185,13 @@ func (c *collector) Stop() {
 	c.nginxStatus.Stop()
 	c.nginxProcess.Stop()
 	c.socket.Stop()
+
+	close(c.stopCh)
+	for _, sink := range c.sinks {
+		if err := sink.Close(); err != nil {
+			klog.Warningf("Error closing metrics sink: %v", err)
+		}
+	}
 }
 
 func (c *collector) SetSSLExpireTime(servers []*ingress.Server) {
@@ -151,6 +202,10 @@ func (c *collector) SetHosts(hosts sets.String) {
 	c.socket.SetHosts(hosts)
 }
 
+func (c *collector) TrafficStats() []traffic.Stats {
+	return c.socket.TrafficStats()
+}
+
 // OnStartedLeading indicates the pod was elected as the leader
 func (c *collector) OnStartedLeading(electionID string) {
 	c.ingressController.OnStartedLeading(electionID)
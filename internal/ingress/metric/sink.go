@@ -0,0 +1,80 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metric
+
+import (
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"k8s.io/klog"
+
+	"k8s.io/ingress-nginx/internal/ingress/metric/collectors"
+)
+
+// Sink defines a destination, other than the Prometheus /metrics endpoint,
+// the ingress controller metrics are pushed to
+type Sink interface {
+	// Push sends a snapshot of the gathered metric families
+	Push([]*dto.MetricFamily) error
+
+	// FlushInterval returns the time between two consecutive pushes
+	FlushInterval() time.Duration
+
+	// Close releases the resources used by the sink
+	Close() error
+}
+
+// runSink periodically gathers the metrics exposed by the ingress controller
+// collectors and pushes them to a sink until stopCh is closed
+func runSink(sink Sink, gatherer prometheus.Gatherer, stopCh <-chan struct{}) {
+	ticker := time.NewTicker(sink.FlushInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			mfs, err := gatherer.Gather()
+			if err != nil {
+				klog.Warningf("Error gathering metrics: %v", err)
+			}
+
+			err = sink.Push(filterControllerMetrics(mfs))
+			if err != nil {
+				klog.Warningf("Error pushing metrics to sink: %v", err)
+			}
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+// filterControllerMetrics removes the metric families not created by the
+// ingress controller collectors, like the Go runtime metrics
+func filterControllerMetrics(mfs []*dto.MetricFamily) []*dto.MetricFamily {
+	prefix := collectors.PrometheusNamespace + "_"
+
+	var filtered []*dto.MetricFamily
+	for _, mf := range mfs {
+		if strings.HasPrefix(mf.GetName(), prefix) {
+			filtered = append(filtered, mf)
+		}
+	}
+
+	return filtered
+}
@@ -0,0 +1,231 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metric
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+// maxStatsDPacketSize is the maximum size of a UDP payload sent to the StatsD
+// server. It is chosen to avoid IP fragmentation on a standard Ethernet MTU.
+const maxStatsDPacketSize = 1432
+
+// StatsDConfig contains the settings of a StatsD sink
+type StatsDConfig struct {
+	// Address of the StatsD server in the form host:port
+	Address string
+	// Prefix prepended to the name of all the metrics
+	Prefix string
+	// Tags added to all the metrics, in the form key:value. Only sent
+	// when the DogStatsD extension is enabled.
+	Tags []string
+	// FlushInterval defines how often the metrics are pushed
+	FlushInterval time.Duration
+	// DogStatsD enables the DogStatsD extension and sends the Prometheus
+	// labels as tags. Otherwise the label values are appended to the name.
+	DogStatsD bool
+}
+
+// StatsDSink pushes metrics to a StatsD (or DogStatsD) server using UDP.
+// Prometheus gauges are sent as StatsD gauges and counters as the difference
+// since the previous push. Histograms and summaries are sent as the _sum and
+// _count counters.
+type StatsDSink struct {
+	cfg StatsDConfig
+
+	conn net.Conn
+
+	mu       sync.Mutex
+	counters map[string]float64
+}
+
+// NewStatsDSink creates a new StatsD sink
+func NewStatsDSink(cfg StatsDConfig) (*StatsDSink, error) {
+	if cfg.FlushInterval <= 0 {
+		return nil, fmt.Errorf("invalid StatsD flush interval %v", cfg.FlushInterval)
+	}
+
+	for _, tag := range cfg.Tags {
+		if !strings.Contains(tag, ":") {
+			return nil, fmt.Errorf("invalid StatsD tag %q (expected key:value)", tag)
+		}
+	}
+
+	conn, err := net.Dial("udp", cfg.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	return &StatsDSink{
+		cfg:      cfg,
+		conn:     conn,
+		counters: make(map[string]float64),
+	}, nil
+}
+
+// FlushInterval returns the time between two consecutive pushes
+func (s *StatsDSink) FlushInterval() time.Duration {
+	return s.cfg.FlushInterval
+}
+
+// Close closes the UDP connection to the StatsD server
+func (s *StatsDSink) Close() error {
+	return s.conn.Close()
+}
+
+// Push sends the metric families to the StatsD server
+func (s *StatsDSink) Push(mfs []*dto.MetricFamily) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var lines []string
+	for _, mf := range mfs {
+		for _, m := range mf.GetMetric() {
+			lines = append(lines, s.format(mf.GetName(), mf.GetType(), m)...)
+		}
+	}
+
+	return s.send(lines)
+}
+
+func (s *StatsDSink) format(name string, t dto.MetricType, m *dto.Metric) []string {
+	switch t {
+	case dto.MetricType_GAUGE:
+		return []string{s.line(name, m.GetLabel(), m.GetGauge().GetValue(), "g")}
+	case dto.MetricType_UNTYPED:
+		return []string{s.line(name, m.GetLabel(), m.GetUntyped().GetValue(), "g")}
+	case dto.MetricType_COUNTER:
+		return s.counter(name, m.GetLabel(), m.GetCounter().GetValue())
+	case dto.MetricType_HISTOGRAM:
+		h := m.GetHistogram()
+		return append(s.counter(name+"_sum", m.GetLabel(), h.GetSampleSum()),
+			s.counter(name+"_count", m.GetLabel(), float64(h.GetSampleCount()))...)
+	case dto.MetricType_SUMMARY:
+		sm := m.GetSummary()
+		return append(s.counter(name+"_sum", m.GetLabel(), sm.GetSampleSum()),
+			s.counter(name+"_count", m.GetLabel(), float64(sm.GetSampleCount()))...)
+	}
+
+	return nil
+}
+
+// counter returns the increment of a cumulative value since the last push.
+// A value lower than the previous one means the counter was reset.
+func (s *StatsDSink) counter(name string, labels []*dto.LabelPair, value float64) []string {
+	key := seriesKey(name, labels)
+
+	delta := value
+	if last, ok := s.counters[key]; ok && value >= last {
+		delta = value - last
+	}
+	s.counters[key] = value
+
+	if delta == 0 {
+		return nil
+	}
+
+	return []string{s.line(name, labels, delta, "c")}
+}
+
+func (s *StatsDSink) line(name string, labels []*dto.LabelPair, value float64, kind string) string {
+	buf := bytes.NewBufferString(s.cfg.Prefix)
+	if s.cfg.Prefix != "" && !strings.HasSuffix(s.cfg.Prefix, ".") {
+		buf.WriteString(".")
+	}
+	buf.WriteString(name)
+
+	if !s.cfg.DogStatsD {
+		for _, l := range labels {
+			buf.WriteString(".")
+			buf.WriteString(strings.Replace(sanitizeStatsD(l.GetValue()), ".", "_", -1))
+		}
+	}
+
+	buf.WriteString(":")
+	buf.WriteString(strconv.FormatFloat(value, 'f', -1, 64))
+	buf.WriteString("|")
+	buf.WriteString(kind)
+
+	if s.cfg.DogStatsD {
+		tags := append([]string{}, s.cfg.Tags...)
+		for _, l := range labels {
+			tags = append(tags, fmt.Sprintf("%v:%v", l.GetName(), sanitizeStatsD(l.GetValue())))
+		}
+
+		if len(tags) > 0 {
+			buf.WriteString("|#")
+			buf.WriteString(strings.Join(tags, ","))
+		}
+	}
+
+	return buf.String()
+}
+
+// send writes the lines to the StatsD server, grouping as many lines as
+// possible in each UDP packet
+func (s *StatsDSink) send(lines []string) error {
+	var packet bytes.Buffer
+	for _, line := range lines {
+		if packet.Len() > 0 && packet.Len()+len(line)+1 > maxStatsDPacketSize {
+			_, err := s.conn.Write(packet.Bytes())
+			if err != nil {
+				return err
+			}
+			packet.Reset()
+		}
+
+		if packet.Len() > 0 {
+			packet.WriteString("\n")
+		}
+		packet.WriteString(line)
+	}
+
+	if packet.Len() == 0 {
+		return nil
+	}
+
+	_, err := s.conn.Write(packet.Bytes())
+	return err
+}
+
+// seriesKey returns a unique identifier for a metric and its labels
+func seriesKey(name string, labels []*dto.LabelPair) string {
+	pairs := make([]string, 0, len(labels))
+	for _, l := range labels {
+		pairs = append(pairs, l.GetName()+"="+l.GetValue())
+	}
+	sort.Strings(pairs)
+
+	return name + "{" + strings.Join(pairs, ",") + "}"
+}
+
+var statsDReplacer = strings.NewReplacer(":", "_", "|", "_", "@", "_", "#", "_", ",", "_", "\n", "_", " ", "_")
+
+// sanitizeStatsD replaces the characters with a special meaning in the
+// StatsD line protocol
+func sanitizeStatsD(s string) string {
+	return statsDReplacer.Replace(s)
+}
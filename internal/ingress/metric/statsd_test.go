@@ -0,0 +1,170 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metric
+
+import (
+	"net"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// newUDPServer starts a local UDP listener standing in for a StatsD server
+func newUDPServer(t *testing.T) *net.UDPConn {
+	addr, err := net.ResolveUDPAddr("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error resolving address: %v", err)
+	}
+
+	conn, err := net.ListenUDP("udp", addr)
+	if err != nil {
+		t.Fatalf("unexpected error creating UDP listener: %v", err)
+	}
+
+	return conn
+}
+
+func readLines(t *testing.T, conn *net.UDPConn) []string {
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+
+	buf := make([]byte, maxStatsDPacketSize)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatalf("unexpected error reading from UDP listener: %v", err)
+	}
+
+	lines := strings.Split(string(buf[:n]), "\n")
+	sort.Strings(lines)
+	return lines
+}
+
+func TestStatsDSink(t *testing.T) {
+	cases := []struct {
+		name       string
+		dogStatsD  bool
+		wantFirst  []string
+		wantSecond []string
+	}{
+		{
+			name:      "dogstatsd sends labels as tags",
+			dogStatsD: true,
+			wantFirst: []string{
+				"ingress.nginx_ingress_controller_config_hash:1.5|g|#env:test",
+				"ingress.nginx_ingress_controller_success:3|c|#env:test,controller_pod:pod-1.default",
+			},
+			wantSecond: []string{
+				"ingress.nginx_ingress_controller_config_hash:1.5|g|#env:test",
+				"ingress.nginx_ingress_controller_success:2|c|#env:test,controller_pod:pod-1.default",
+			},
+		},
+		{
+			name: "statsd appends label values to the name",
+			wantFirst: []string{
+				"ingress.nginx_ingress_controller_config_hash:1.5|g",
+				"ingress.nginx_ingress_controller_success.pod-1_default:3|c",
+			},
+			wantSecond: []string{
+				"ingress.nginx_ingress_controller_config_hash:1.5|g",
+				"ingress.nginx_ingress_controller_success.pod-1_default:2|c",
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			server := newUDPServer(t)
+			defer server.Close()
+
+			sink, err := NewStatsDSink(StatsDConfig{
+				Address:       server.LocalAddr().String(),
+				Prefix:        "ingress",
+				Tags:          []string{"env:test"},
+				FlushInterval: time.Second,
+				DogStatsD:     c.dogStatsD,
+			})
+			if err != nil {
+				t.Fatalf("unexpected error creating StatsD sink: %v", err)
+			}
+			defer sink.Close()
+
+			gauge := prometheus.NewGauge(prometheus.GaugeOpts{
+				Name: "nginx_ingress_controller_config_hash",
+				Help: "test",
+			})
+			counter := prometheus.NewCounterVec(prometheus.CounterOpts{
+				Name: "nginx_ingress_controller_success",
+				Help: "test",
+			}, []string{"controller_pod"})
+			goCounter := prometheus.NewCounter(prometheus.CounterOpts{
+				Name: "go_test_total",
+				Help: "test",
+			})
+
+			reg := prometheus.NewRegistry()
+			reg.MustRegister(gauge, counter, goCounter)
+
+			gauge.Set(1.5)
+			counter.WithLabelValues("pod-1.default").Add(3)
+			goCounter.Inc()
+
+			push := func() {
+				mfs, err := reg.Gather()
+				if err != nil {
+					t.Fatalf("unexpected error gathering metrics: %v", err)
+				}
+
+				err = sink.Push(filterControllerMetrics(mfs))
+				if err != nil {
+					t.Fatalf("unexpected error pushing metrics: %v", err)
+				}
+			}
+
+			push()
+			got := readLines(t, server)
+			if strings.Join(got, "\n") != strings.Join(c.wantFirst, "\n") {
+				t.Errorf("expected %v but got %v", c.wantFirst, got)
+			}
+
+			counter.WithLabelValues("pod-1.default").Add(2)
+
+			push()
+			got = readLines(t, server)
+			if strings.Join(got, "\n") != strings.Join(c.wantSecond, "\n") {
+				t.Errorf("expected %v but got %v", c.wantSecond, got)
+			}
+		})
+	}
+}
+
+func TestNewStatsDSinkInvalidConfig(t *testing.T) {
+	_, err := NewStatsDSink(StatsDConfig{Address: "127.0.0.1:8125"})
+	if err == nil {
+		t.Errorf("expected an error with an empty flush interval")
+	}
+
+	_, err = NewStatsDSink(StatsDConfig{
+		Address:       "127.0.0.1:8125",
+		FlushInterval: time.Second,
+		Tags:          []string{"invalid"},
+	})
+	if err == nil {
+		t.Errorf("expected an error with an invalid tag")
+	}
+}
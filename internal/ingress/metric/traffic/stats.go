@@ -0,0 +1,38 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package traffic
+
+// Stats contains the rolling traffic statistics of an Ingress,
+// host and service, computed over the last minute
+type Stats struct {
+	Namespace string `json:"namespace"`
+	Ingress   string `json:"ingress"`
+	Host      string `json:"host"`
+	Service   string `json:"service"`
+
+	// Requests is the number of requests in the window
+	Requests uint64 `json:"requests"`
+	// RequestsPerSecond is the average rate of requests in the window, or
+	// since the controller started when it is shorter
+	RequestsPerSecond float64 `json:"requestsPerSecond"`
+	// ErrorRate is the fraction of requests with a 5xx status code
+	ErrorRate float64 `json:"errorRate"`
+	// LatencyP50 is the median request time in seconds
+	LatencyP50 float64 `json:"latencyP50"`
+	// LatencyP99 is the 99th percentile of the request time in seconds
+	LatencyP99 float64 `json:"latencyP99"`
+}
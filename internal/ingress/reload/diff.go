@@ -0,0 +1,63 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reload
+
+import (
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+)
+
+// ConfigurationDiff describes the changes between the running configuration
+// and a new one that required a reload of NGINX
+type ConfigurationDiff struct {
+	Timestamp time.Time `json:"timestamp"`
+	// Reasons contains the parts of the configuration that changed
+	Reasons []string `json:"reasons"`
+	// Error contains the reason why the reload failed, if it did
+	Error string `json:"error,omitempty"`
+
+	Servers        []ServerDiff        `json:"servers,omitempty"`
+	StreamServices []StreamServiceDiff `json:"streamServices,omitempty"`
+}
+
+// ServerDiff describes the changes in a server
+type ServerDiff struct {
+	Hostname string `json:"hostname"`
+	Change   string `json:"change"`
+	// Fields contains the names of the modified fields of the server
+	Fields    []string       `json:"fields,omitempty"`
+	Locations []LocationDiff `json:"locations,omitempty"`
+}
+
+// LocationDiff describes the changes in a location of a server
+type LocationDiff struct {
+	Path string `json:"path"`
+	// Ingress contains the namespace and name of the Ingress that
+	// defines the location
+	Ingress string `json:"ingress,omitempty"`
+	Change  string `json:"change"`
+	// Fields contains the names of the modified fields of the location
+	Fields []string `json:"fields,omitempty"`
+}
+
+// StreamServiceDiff describes the changes in a TCP or UDP service
+type StreamServiceDiff struct {
+	Protocol apiv1.Protocol `json:"protocol"`
+	Port     int            `json:"port"`
+	Change   string         `json:"change"`
+}
@@ -47,3 +47,8 @@ func (m Mock) GetAuthCertificate(string) (*AuthSSLCert, error) {
 func (m Mock) GetService(string) (*apiv1.Service, error) {
 	return nil, nil
 }
+
+// GetConfigMap searches for configmaps contenating the namespace and name using a the character /
+func (m Mock) GetConfigMap(string) (*apiv1.ConfigMap, error) {
+	return nil, nil
+}
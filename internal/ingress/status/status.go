@@ -55,6 +55,12 @@ type ingressLister interface {
 	ListIngresses() []*ingress.Ingress
 }
 
+type conflictChecker interface {
+	// IsRejected returns true if the Ingress is not configured because of
+	// a conflict with other Ingresses
+	IsRejected(*ingress.Ingress) bool
+}
+
 // Config ...
 type Config struct {
 	Client clientset.Interface
@@ -68,6 +74,9 @@ type Config struct {
 	UseNodeInternalIP bool
 
 	IngressLister ingressLister
+
+	// +optional
+	ConflictChecker conflictChecker
 }
 
 // statusSync keeps the status IP in each Ingress rule updated executing a periodic check
@@ -264,14 +273,20 @@ func (s *statusSync) updateStatus(newIngressPoint []apiv1.LoadBalancerIngress) {
 	sort.SliceStable(newIngressPoint, lessLoadBalancerIngress(newIngressPoint))
 
 	for _, ing := range ings {
+		ingressPoint := newIngressPoint
+		// rejected Ingresses are not served by the controller
+		if s.ConflictChecker != nil && s.ConflictChecker.IsRejected(ing) {
+			ingressPoint = []apiv1.LoadBalancerIngress{}
+		}
+
 		curIPs := ing.Status.LoadBalancer.Ingress
 		sort.SliceStable(curIPs, lessLoadBalancerIngress(curIPs))
-		if ingressSliceEqual(curIPs, newIngressPoint) {
+		if ingressSliceEqual(curIPs, ingressPoint) {
 			klog.V(3).Infof("skipping update of Ingress %v/%v (no change)", ing.Namespace, ing.Name)
 			continue
 		}
 
-		batch.Queue(runUpdate(ing, newIngressPoint, s.Client))
+		batch.Queue(runUpdate(ing, ingressPoint, s.Client))
 	}
 
 	batch.QueueComplete()
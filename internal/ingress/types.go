@@ -26,16 +26,22 @@ import (
 	"k8s.io/ingress-nginx/internal/ingress/annotations/auth"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/authreq"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/authtls"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/concurrencylimit"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/connection"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/cors"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/headers"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/influxdb"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/ipwhitelist"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/log"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/luarestywaf"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/proxy"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/proxycache"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/ratelimit"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/redirect"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/requestvalidation"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/retrypolicy"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/rewrite"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/routing"
 	"k8s.io/ingress-nginx/internal/ingress/resolver"
 )
 
@@ -96,6 +102,9 @@ type Backend struct {
 	UpstreamHashBy UpstreamHashByConfig `json:"upstreamHashByConfig,omitempty"`
 	// LB algorithm configuration per ingress
 	LoadBalancing string `json:"load-balance,omitempty"`
+	// ConcurrencyLimit limits the requests in flight to the backend
+	// +optional
+	ConcurrencyLimit concurrencylimit.Config `json:"concurrencyLimit,omitempty"`
 	// Denotes if a backend has no server. The backend instead shares a server with another backend and acts as an
 	// alternative backend.
 	// This can be used to share multiple upstreams in the sam nginx server block.
@@ -192,6 +201,9 @@ type Server struct {
 	ServerSnippet string `json:"serverSnippet"`
 	// SSLCiphers returns list of ciphers to be enabled
 	SSLCiphers string `json:"sslCiphers,omitempty"`
+	// HTTP3AltSvc indicates if the HTTPS responses advertise the HTTP/3 listener
+	// in the Alt-Svc header
+	HTTP3AltSvc bool `json:"http3AltSvc,omitempty"`
 	// AuthTLSError contains the reason why the access to a server should be denied
 	AuthTLSError string `json:"authTLSError,omitempty"`
 }
@@ -216,6 +228,10 @@ type Location struct {
 	// a '/'. If unspecified, the path defaults to a catch all sending
 	// traffic to the backend.
 	Path string `json:"path"`
+	// PathType defines how the path is matched: Exact, Prefix or
+	// ImplementationSpecific (default)
+	// +optional
+	PathType string `json:"pathType,omitempty"`
 	// IsDefBackend indicates if service specified in the Ingress
 	// contains active endpoints or not. Returning true means the location
 	// uses the default backend.
@@ -304,6 +320,10 @@ type Location struct {
 	// BackendProtocol indicates which protocol should be used to communicate with the service
 	// By default this is HTTP
 	BackendProtocol string `json:"backend-protocol"`
+	// GRPCWeb translates the gRPC-Web requests of browsers to gRPC requests
+	// to the backend. Only used with the GRPC and GRPCS backend protocols.
+	// +optional
+	GRPCWeb bool `json:"grpcWeb,omitempty"`
 	// CustomHTTPErrors specifies the error codes that should be intercepted.
 	// +optional
 	CustomHTTPErrors []int `json:"custom-http-errors"`
@@ -312,6 +332,27 @@ type Location struct {
 	ModSecurity modsecurity.Config `json:"modsecurity"`
 	// Satisfy dictates allow access if any or all is set
 	Satisfy string `json:"satisfy"`
+	// Headers contains the changes applied to the headers of the requests
+	// sent to the upstream and the responses sent to the client
+	// +optional
+	Headers headers.Config `json:"headers,omitempty"`
+	// Routing contains the methods allowed in the location and the routes
+	// that send requests to other backends depending on the method and
+	// the headers
+	// +optional
+	Routing routing.Config `json:"routing,omitempty"`
+	// ProxyCache contains the cache zone and the rules used to cache the
+	// responses of the location
+	// +optional
+	ProxyCache proxycache.Config `json:"proxyCache,omitempty"`
+	// RequestValidation contains the JSON schema, the content types and the
+	// maximum body size used to validate the requests before proxying them
+	// +optional
+	RequestValidation requestvalidation.Config `json:"requestValidation,omitempty"`
+	// RetryPolicy contains the status codes retried, the timeout of each try
+	// and the retry budget of the location
+	// +optional
+	RetryPolicy retrypolicy.Config `json:"retryPolicy,omitempty"`
 }
 
 // SSLPassthroughBackend describes a SSL upstream server configured
@@ -331,12 +372,54 @@ type SSLPassthroughBackend struct {
 type L4Service struct {
 	// Port external port to expose
 	Port int `json:"port"`
+	// Hostname is the name used to route the TLS connections of a port
+	// shared by several services using the Server Name Indication (SNI)
+	// +optional
+	Hostname string `json:"hostname,omitempty"`
 	// Backend of the service
 	Backend L4Backend `json:"backend"`
 	// Endpoints active endpoints of the service
 	Endpoints []Endpoint `json:"endpoints,omitempty"`
 	// k8s Service
 	Service *apiv1.Service `json:"service,omitempty"`
+	// SSLCert is the certificate used to terminate TLS in the port
+	// +optional
+	SSLCert *SSLCert `json:"sslCert,omitempty"`
+	// ClientCACert is the certificate authority used to verify the
+	// certificates of the clients
+	// +optional
+	ClientCACert resolver.AuthSSLCert `json:"clientCACert,omitempty"`
+	// ProxyTimeout is the timeout between two successive read or write
+	// operations, in seconds. The global timeout is used when it is 0
+	// +optional
+	ProxyTimeout int `json:"proxyTimeout,omitempty"`
+	// ProxyConnectTimeout is the timeout to establish a connection with
+	// the service, in seconds. The global timeout is used when it is 0
+	// +optional
+	ProxyConnectTimeout int `json:"proxyConnectTimeout,omitempty"`
+	// DisableAccessLog disables the access log of the service
+	// +optional
+	DisableAccessLog bool `json:"disableAccessLog,omitempty"`
+	// LoadBalancing is the load balancing algorithm of the endpoints.
+	// round_robin is used when it is empty
+	// +optional
+	LoadBalancing string `json:"loadBalancing,omitempty"`
+	// UpstreamHashBy is the NGINX variable used as key by the consistent
+	// hashing of the endpoints
+	// +optional
+	UpstreamHashBy string `json:"upstreamHashBy,omitempty"`
+	// AllowedSourceRange are the IP addresses and networks of the clients
+	// allowed to connect. All the clients are allowed when it is empty
+	// +optional
+	AllowedSourceRange ipwhitelist.SourceRange `json:"allowedSourceRange,omitempty"`
+	// DeniedSourceRange are the IP addresses and networks of the clients
+	// not allowed to connect
+	// +optional
+	DeniedSourceRange ipwhitelist.SourceRange `json:"deniedSourceRange,omitempty"`
+	// ConnectionLimit is the number of concurrent connections allowed by
+	// client IP address. There is no limit when it is 0
+	// +optional
+	ConnectionLimit int `json:"connectionLimit,omitempty"`
 }
 
 // L4Backend describes the kubernetes service behind L4 Ingress service
@@ -157,6 +157,9 @@ func (b1 *Backend) Equal(b2 *Backend) bool {
 	if b1.LoadBalancing != b2.LoadBalancing {
 		return false
 	}
+	if !(&b1.ConcurrencyLimit).Equal(&b2.ConcurrencyLimit) {
+		return false
+	}
 
 	if len(b1.Endpoints) != len(b2.Endpoints) {
 		return false
@@ -338,6 +341,9 @@ func (s1 *Server) Equal(s2 *Server) bool {
 	if s1.SSLCiphers != s2.SSLCiphers {
 		return false
 	}
+	if s1.HTTP3AltSvc != s2.HTTP3AltSvc {
+		return false
+	}
 	if s1.AuthTLSError != s2.AuthTLSError {
 		return false
 	}
@@ -367,6 +373,9 @@ func (l1 *Location) Equal(l2 *Location) bool {
 	if l1.Path != l2.Path {
 		return false
 	}
+	if l1.PathType != l2.PathType {
+		return false
+	}
 	if l1.IsDefBackend != l2.IsDefBackend {
 		return false
 	}
@@ -452,6 +461,10 @@ func (l1 *Location) Equal(l2 *Location) bool {
 		return false
 	}
 
+	if l1.GRPCWeb != l2.GRPCWeb {
+		return false
+	}
+
 	if len(l1.CustomHTTPErrors) != len(l2.CustomHTTPErrors) {
 		return false
 	}
@@ -476,6 +489,26 @@ func (l1 *Location) Equal(l2 *Location) bool {
 		return false
 	}
 
+	if !(&l1.Headers).Equal(&l2.Headers) {
+		return false
+	}
+
+	if !(&l1.Routing).Equal(&l2.Routing) {
+		return false
+	}
+
+	if !(&l1.ProxyCache).Equal(&l2.ProxyCache) {
+		return false
+	}
+
+	if !(&l1.RequestValidation).Equal(&l2.RequestValidation) {
+		return false
+	}
+
+	if !(&l1.RetryPolicy).Equal(&l2.RetryPolicy) {
+		return false
+	}
+
 	if l1.DefaultBackendUpstreamName != l2.DefaultBackendUpstreamName {
 		return false
 	}
@@ -527,9 +560,42 @@ func (e1 *L4Service) Equal(e2 *L4Service) bool {
 	if e1.Port != e2.Port {
 		return false
 	}
+	if e1.Hostname != e2.Hostname {
+		return false
+	}
 	if !(&e1.Backend).Equal(&e2.Backend) {
 		return false
 	}
+	if !e1.SSLCert.Equal(e2.SSLCert) {
+		return false
+	}
+	if !(&e1.ClientCACert).Equal(&e2.ClientCACert) {
+		return false
+	}
+	if e1.ProxyTimeout != e2.ProxyTimeout {
+		return false
+	}
+	if e1.ProxyConnectTimeout != e2.ProxyConnectTimeout {
+		return false
+	}
+	if e1.DisableAccessLog != e2.DisableAccessLog {
+		return false
+	}
+	if e1.LoadBalancing != e2.LoadBalancing {
+		return false
+	}
+	if e1.UpstreamHashBy != e2.UpstreamHashBy {
+		return false
+	}
+	if !(&e1.AllowedSourceRange).Equal(&e2.AllowedSourceRange) {
+		return false
+	}
+	if !(&e1.DeniedSourceRange).Equal(&e2.DeniedSourceRange) {
+		return false
+	}
+	if e1.ConnectionLimit != e2.ConnectionLimit {
+		return false
+	}
 	if len(e1.Endpoints) != len(e2.Endpoints) {
 		return false
 	}
@@ -42,9 +42,17 @@ var HealthPath = "/healthz"
 // http://nginx.org/en/docs/http/ngx_http_stub_status_module.html
 var StatusPath = "/nginx_status"
 
+// ZoneStatusPath defines the path used to expose the requests and bytes
+// counters of each server and upstream zone
+var ZoneStatusPath = "/nginx_zone_status"
+
 // StreamSocket defines the location of the unix socket used by NGINX for the NGINX stream configuration socket
 var StreamSocket = "/tmp/ingress-stream.sock"
 
+// ProxyCachePath defines the directory containing the cached responses of
+// each cache zone
+var ProxyCachePath = "/tmp/nginx-cache"
+
 var statusLocation = "nginx-status"
 
 var socketClient = buildUnixSocketClient()
@@ -23,9 +23,37 @@ local IMPLEMENTATIONS = {
   ewma = ewma,
 }
 
+-- the retries allowed at the same time per backend regardless of the retry
+-- budget, to allow retries in backends with few active requests
+local MIN_RETRY_CONCURRENCY = 3
+
+-- measured in seconds
+-- interval between the attempts of a queued request to get a slot in a
+-- backend limiting its concurrent requests
+local CONCURRENCY_QUEUE_INTERVAL = 0.01
+-- the requests are released in the log phase. The ones still tracked after
+-- STALE_REQUEST_AGE, because the log phase of their last location does not
+-- release them, are released by a timer running every STALE_REQUESTS_INTERVAL
+local STALE_REQUEST_AGE = 3600
+local STALE_REQUESTS_INTERVAL = 60
+
 local _M = {}
 local balancers = {}
 
+-- active requests and retries per backend, used to enforce the retry budgets.
+-- The counters are local to each NGINX worker.
+local retry_budgets = {}
+-- budget tracked by each active request and the time it started,
+-- indexed by $request_id which is kept after internal redirects, unlike
+-- ngx.ctx
+local active_requests = {}
+
+-- limit of concurrent requests per backend
+local concurrency_limits = {}
+-- backend of each request holding a slot in the balancer_concurrency shared
+-- dictionary and the time it was taken, indexed by $request_id
+local concurrency_slots = {}
+
 local function get_implementation(backend)
   local name = backend["load-balance"] or DEFAULT_LB_ALG
 
@@ -74,6 +102,8 @@ local function format_ipv6_endpoints(endpoints)
 end
 
 local function sync_backend(backend)
+  concurrency_limits[backend.name] = backend.concurrencyLimit
+
   if not backend.endpoints or #backend.endpoints == 0 then
     ngx.log(ngx.INFO, string.format("there is no endpoint for backend %s. Removing...", backend.name))
     balancers[backend.name] = nil
@@ -122,9 +152,11 @@ local function sync_backends()
   end
 
   local balancers_to_keep = {}
+  local concurrency_limits_to_keep = {}
   for _, new_backend in ipairs(new_backends) do
     sync_backend(new_backend)
     balancers_to_keep[new_backend.name] = balancers[new_backend.name]
+    concurrency_limits_to_keep[new_backend.name] = concurrency_limits[new_backend.name]
   end
 
   for backend_name, _ in pairs(balancers) do
@@ -132,6 +164,8 @@ local function sync_backends()
       balancers[backend_name] = nil
     end
   end
+
+  concurrency_limits = concurrency_limits_to_keep
 end
 
 local function route_to_alternative_balancer(balancer)
@@ -205,12 +239,90 @@ local function get_balancer()
   return balancer
 end
 
+local release_stale_requests
+
 function _M.init_worker()
   sync_backends() -- when worker starts, sync backends without delay
   local _, err = ngx.timer.every(BACKENDS_SYNC_INTERVAL, sync_backends)
   if err then
     ngx.log(ngx.ERR, string.format("error when setting up timer.every for sync_backends: %s", tostring(err)))
   end
+
+  _, err = ngx.timer.every(STALE_REQUESTS_INTERVAL, release_stale_requests)
+  if err then
+    ngx.log(ngx.ERR, string.format("error when setting up timer.every for release_stale_requests: %s", tostring(err)))
+  end
+end
+
+local function acquire_slot(backend_name, max_requests)
+  local concurrency = ngx.shared.balancer_concurrency
+
+  local count, err = concurrency:incr(backend_name, 1, 0)
+  if not count then
+    -- the request is not limited when the shared dictionary is full
+    ngx.log(ngx.ERR, "error counting the requests of backend " .. backend_name .. ": ", err)
+    return true
+  end
+
+  if count <= max_requests then
+    return true
+  end
+
+  concurrency:incr(backend_name, -1, 0)
+  return false
+end
+
+-- limit_concurrency takes a slot of the backend for the request, waiting up
+-- to the queue timeout of the backend when all the slots are taken. It
+-- returns false when the request must be rejected.
+local function limit_concurrency(backend_name)
+  local limit = concurrency_limits[backend_name]
+  if not limit or not limit.maxRequests then
+    return true
+  end
+
+  local request_id = ngx.var.request_id
+  if concurrency_slots[request_id] then
+    -- the slot was taken before an internal redirect
+    return true
+  end
+
+  local acquired = acquire_slot(backend_name, limit.maxRequests)
+
+  if not acquired and limit.queueTimeout then
+    ngx.ctx.concurrency_limit = "queued"
+
+    local deadline = ngx.now() + limit.queueTimeout / 1000
+    repeat
+      ngx.sleep(CONCURRENCY_QUEUE_INTERVAL)
+      ngx.update_time()
+      acquired = acquire_slot(backend_name, limit.maxRequests)
+    until acquired or ngx.now() >= deadline
+  end
+
+  if not acquired then
+    ngx.ctx.concurrency_limit = "rejected"
+    return false
+  end
+
+  concurrency_slots[request_id] = { backend = backend_name, since = ngx.now() }
+  return true
+end
+
+local function release_slot(request_id, slot)
+  concurrency_slots[request_id] = nil
+  ngx.shared.balancer_concurrency:incr(slot.backend, -1, 0)
+end
+
+local function release_concurrency()
+  local request_id = ngx.var.request_id
+
+  local slot = concurrency_slots[request_id]
+  if not slot then
+    return
+  end
+
+  release_slot(request_id, slot)
 end
 
 function _M.rewrite()
@@ -219,6 +331,111 @@ function _M.rewrite()
     ngx.status = ngx.HTTP_SERVICE_UNAVAILABLE
     return ngx.exit(ngx.status)
   end
+
+  if not limit_concurrency(ngx.var.proxy_upstream_name) then
+    ngx.log(ngx.WARN, "maximum of concurrent requests of backend " .. ngx.var.proxy_upstream_name .. " reached")
+    return ngx.exit(ngx.HTTP_SERVICE_UNAVAILABLE)
+  end
+end
+
+local function get_retry_budget(backend_name)
+  local budget = retry_budgets[backend_name]
+  if not budget then
+    budget = { requests = 0, retries = 0 }
+    retry_budgets[backend_name] = budget
+  end
+  return budget
+end
+
+local function retry_allowed(policy, budget)
+  if not policy or not policy.budget then
+    return true
+  end
+
+  local max_retries = math.max(MIN_RETRY_CONCURRENCY, budget.requests * policy.budget / 100)
+  return budget.retries < max_retries
+end
+
+-- track_try counts the tries of the request and the active requests and
+-- retries of the backend. It returns false when the request is a retry
+-- exceeding the retry budget of the location.
+local function track_try()
+  local ctx = ngx.ctx
+  local request_id = ngx.var.request_id
+
+  local active = active_requests[request_id]
+  if not active then
+    active = { budget = get_retry_budget(ngx.var.proxy_upstream_name), retrying = false, since = ngx.now() }
+    active.budget.requests = active.budget.requests + 1
+    active_requests[request_id] = active
+  end
+
+  ctx.balancer_tries = (ctx.balancer_tries or 0) + 1
+  if ctx.balancer_tries == 1 then
+    return true
+  end
+
+  if not active.retrying then
+    if not retry_allowed(ctx.retry_policy, active.budget) then
+      ctx.retry_budget_exhausted = true
+      return false
+    end
+
+    active.retrying = true
+    active.budget.retries = active.budget.retries + 1
+  end
+
+  ctx.upstream_retries = ctx.balancer_tries - 1
+  return true
+end
+
+local function release_active_request(request_id, active)
+  active.budget.requests = active.budget.requests - 1
+  if active.retrying then
+    active.budget.retries = active.budget.retries - 1
+  end
+  active_requests[request_id] = nil
+end
+
+local function release_try()
+  local request_id = ngx.var.request_id
+
+  local active = active_requests[request_id]
+  if not active then
+    return
+  end
+
+  release_active_request(request_id, active)
+end
+
+release_stale_requests = function(premature)
+  if premature then
+    return
+  end
+
+  local oldest = ngx.now() - STALE_REQUEST_AGE
+
+  for request_id, active in pairs(active_requests) do
+    if active.since < oldest then
+      ngx.log(ngx.WARN, "releasing the retry budget of stale request " .. request_id)
+      release_active_request(request_id, active)
+    end
+  end
+
+  -- the slots are counted by all the workers in the shared dictionary, so
+  -- each worker releases the stale slots it took
+  for request_id, slot in pairs(concurrency_slots) do
+    if slot.since < oldest then
+      ngx.log(ngx.WARN, "releasing the slot of backend " .. slot.backend .. " taken by stale request " .. request_id)
+      release_slot(request_id, slot)
+    end
+  end
+end
+
+-- set_retry_policy defines the retry policy of the location, with the
+-- fields per_try_timeout, connect_timeout and budget
+function _M.set_retry_policy(policy)
+  ngx.ctx.retry_policy = policy
 end
 
 function _M.balance()
@@ -227,6 +444,12 @@ function _M.balance()
     return
   end
 
+  if not track_try() then
+    local _, status = ngx_balancer.get_last_failure()
+    ngx.log(ngx.WARN, "retry budget of backend " .. ngx.var.proxy_upstream_name .. " exhausted")
+    return ngx.exit(status or ngx.HTTP_BAD_GATEWAY)
+  end
+
   local peer = balancer:balance()
   if not peer then
     ngx.log(ngx.WARN, "no peer was returned, balancer: " .. balancer.name)
@@ -235,6 +458,14 @@ function _M.balance()
 
   ngx_balancer.set_more_tries(1)
 
+  local policy = ngx.ctx.retry_policy
+  if policy and policy.per_try_timeout then
+    local ok, err = ngx_balancer.set_timeouts(policy.connect_timeout, policy.per_try_timeout, policy.per_try_timeout)
+    if not ok then
+      ngx.log(ngx.ERR, "error while setting the timeouts of the try: ", err)
+    end
+  end
+
   local ok, err = ngx_balancer.set_current_peer(peer)
   if not ok then
     ngx.log(ngx.ERR, string.format("error while setting current upstream peer %s: %s", peer, err))
@@ -242,6 +473,9 @@ function _M.balance()
 end
 
 function _M.log()
+  release_try()
+  release_concurrency()
+
   local balancer = get_balancer()
   if not balancer then
     return
@@ -257,6 +491,12 @@ end
 if _TEST then
   _M.get_implementation = get_implementation
   _M.sync_backend = sync_backend
+  _M.track_try = track_try
+  _M.release_try = release_try
+  _M.release_stale_requests = release_stale_requests
+  _M.get_retry_budget = get_retry_budget
+  _M.limit_concurrency = limit_concurrency
+  _M.release_concurrency = release_concurrency
 end
 
 return _M
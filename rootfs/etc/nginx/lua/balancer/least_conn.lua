@@ -0,0 +1,85 @@
+-- least connections load balancing, used by the stream services.
+-- The active connections are counted by worker, so the balancing is
+-- approximate when there are several workers.
+
+local util = require("util")
+
+local _M = { name = "least_conn" }
+
+local function peer_key(endpoint)
+  return endpoint.address .. ":" .. endpoint.port
+end
+
+function _M.balance(self)
+  local peers = self.peers
+
+  -- start at a random peer to spread the connections among the peers with
+  -- the same number of active connections
+  local offset = math.random(#peers)
+  local selected, least
+  for i = 0, #peers - 1 do
+    local key = peer_key(peers[(offset + i) % #peers + 1])
+    local count = self.connections[key] or 0
+    if not least or count < least then
+      selected, least = key, count
+    end
+  end
+
+  self.connections[selected] = least + 1
+
+  -- the peers of every try of the connection are released in the log phase
+  local tried = ngx.ctx.least_conn_peers or {}
+  table.insert(tried, selected)
+  ngx.ctx.least_conn_peers = tried
+
+  return selected
+end
+
+function _M.after_balance(self)
+  local tried = ngx.ctx.least_conn_peers
+  if not tried then
+    return
+  end
+
+  for _, key in ipairs(tried) do
+    local count = self.connections[key]
+    if count and count > 0 then
+      self.connections[key] = count - 1
+    end
+  end
+  ngx.ctx.least_conn_peers = nil
+end
+
+function _M.sync(self, backend)
+  self.traffic_shaping_policy = backend.trafficShapingPolicy
+  self.alternative_backends = backend.alternativeBackends
+
+  local changed = not util.deep_compare(self.peers, backend.endpoints)
+  if not changed then
+    return
+  end
+
+  -- keep the active connections of the peers still present
+  local connections = {}
+  for _, endpoint in ipairs(backend.endpoints) do
+    local key = peer_key(endpoint)
+    connections[key] = self.connections[key]
+  end
+
+  self.peers = backend.endpoints
+  self.connections = connections
+end
+
+function _M.new(self, backend)
+  local o = {
+    peers = backend.endpoints,
+    connections = {},
+    traffic_shaping_policy = backend.trafficShapingPolicy,
+    alternative_backends = backend.alternativeBackends,
+  }
+  setmetatable(o, self)
+  self.__index = self
+  return o
+end
+
+return _M
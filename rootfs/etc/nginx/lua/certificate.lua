@@ -26,22 +26,34 @@ local function set_pem_cert_key(pem_cert_key)
   end
 end
 
+-- get_pem_cert_key returns the certificate of the server with the exact
+-- hostname, or of the most specific wildcard server matching it. Like
+-- server_name in NGINX, "*.example.com" matches "a.b.example.com".
 local function get_pem_cert_key(hostname)
   local pem_cert_key = configuration.get_pem_cert_key(hostname)
   if pem_cert_key then
     return pem_cert_key
   end
 
-  local wildcard_hosatname, _, err = re_sub(hostname, "^[^\\.]+\\.", "*.", "jo")
-  if err then
-    ngx.log(ngx.ERR, "error: ", err)
-    return pem_cert_key
-  end
+  local domain = hostname
+  while true do
+    local parent, _, err = re_sub(domain, "^[^\\.]+\\.", "", "jo")
+    if err then
+      ngx.log(ngx.ERR, "error: ", err)
+      return nil
+    end
+
+    if not parent or parent == "" or parent == domain then
+      return nil
+    end
+
+    pem_cert_key = configuration.get_pem_cert_key("*." .. parent)
+    if pem_cert_key then
+      return pem_cert_key
+    end
 
-  if wildcard_hosatname then
-    pem_cert_key = configuration.get_pem_cert_key(wildcard_hosatname)
+    domain = parent
   end
-  return pem_cert_key
 end
 
 function _M.call()
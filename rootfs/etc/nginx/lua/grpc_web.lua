@@ -0,0 +1,95 @@
+local bit = require("bit")
+local string_char = string.char
+local string_sub = string.sub
+
+-- the prefix of the content types of the gRPC-Web requests,
+-- application/grpc-web and application/grpc-web+<codec>
+local GRPC_WEB = "application/grpc-web"
+-- the text format, base64 encoded, is not supported
+local GRPC_WEB_TEXT = "application/grpc-web-text"
+-- the prefix of the content types of the gRPC responses of the backend
+local GRPC = "application/grpc"
+local HTTP_UNSUPPORTED_MEDIA_TYPE = 415
+-- the flag of the frame carrying the trailers at the end of the response body
+local TRAILERS_FLAG = 0x80
+
+local _M = {}
+
+local function has_prefix(value, prefix)
+  return string_sub(value, 1, #prefix) == prefix
+end
+
+-- frame returns a gRPC-Web frame: the flags, the length of the data in 4
+-- bytes in big-endian order and the data
+local function frame(flags, data)
+  local length = #data
+  return string_char(flags,
+    bit.band(bit.rshift(length, 24), 0xff),
+    bit.band(bit.rshift(length, 16), 0xff),
+    bit.band(bit.rshift(length, 8), 0xff),
+    bit.band(length, 0xff)) .. data
+end
+
+-- rewrite turns a gRPC-Web request into a gRPC request. The messages of the
+-- body are framed in the same way, so only the content type changes. Other
+-- requests, like gRPC requests or CORS preflight requests, are not modified.
+function _M.rewrite()
+  local content_type = ngx.var.content_type
+  if not content_type or not has_prefix(content_type, GRPC_WEB) then
+    return
+  end
+
+  if has_prefix(content_type, GRPC_WEB_TEXT) then
+    return ngx.exit(HTTP_UNSUPPORTED_MEDIA_TYPE)
+  end
+
+  -- the content type of the response
+  ngx.ctx.grpc_web = content_type
+  ngx.req.set_header("Content-Type", "application/grpc" .. string_sub(content_type, #GRPC_WEB + 1))
+end
+
+function _M.header_filter()
+  local content_type = ngx.ctx.grpc_web
+  if not content_type then
+    return
+  end
+
+  -- the responses generated by NGINX, like the 502 and 504 error pages, are
+  -- not gRPC responses
+  local upstream_content_type = ngx.var.upstream_http_content_type
+  if not upstream_content_type or not has_prefix(upstream_content_type, GRPC) then
+    return
+  end
+
+  ngx.header["Content-Type"] = content_type
+  -- the trailers are appended to the body
+  ngx.header["Content-Length"] = nil
+end
+
+-- body_filter sends the trailers of the gRPC response, which browsers cannot
+-- read, in a frame at the end of the body. The responses without body carry
+-- grpc-status in the headers instead.
+function _M.body_filter()
+  if not ngx.ctx.grpc_web or not ngx.arg[2] then
+    return
+  end
+
+  local status = ngx.var.upstream_trailer_grpc_status
+  if not status then
+    return
+  end
+
+  local trailers = "grpc-status:" .. status .. "\r\n"
+  local message = ngx.var.upstream_trailer_grpc_message
+  if message then
+    trailers = trailers .. "grpc-message:" .. message .. "\r\n"
+  end
+
+  ngx.arg[1] = (ngx.arg[1] or "") .. frame(TRAILERS_FLAG, trailers)
+end
+
+if _TEST then
+  _M.frame = frame
+end
+
+return _M
@@ -0,0 +1,72 @@
+local ipairs = ipairs
+local type = type
+
+local _M = {}
+
+-- append returns the values of a header after adding a new one
+local function append(current, value)
+  if current == nil then
+    return value
+  end
+
+  local values = {}
+  if type(current) == "table" then
+    for _, v in ipairs(current) do
+      values[#values + 1] = v
+    end
+  else
+    values[1] = current
+  end
+  values[#values + 1] = value
+
+  return values
+end
+
+-- apply removes, sets and appends the headers using the accessors
+-- of the request or the response headers
+local function apply(ops, get, set)
+  for _, name in ipairs(ops.remove or {}) do
+    set(name, nil)
+  end
+
+  for _, header in ipairs(ops.set or {}) do
+    set(header[1], header[2])
+  end
+
+  for _, header in ipairs(ops.add or {}) do
+    set(header[1], append(get(header[1]), header[2]))
+  end
+end
+
+local function get_request_header(name)
+  return ngx.req.get_headers()[name]
+end
+
+local function set_request_header(name, value)
+  if value == nil then
+    ngx.req.clear_header(name)
+    return
+  end
+
+  ngx.req.set_header(name, value)
+end
+
+local function get_response_header(name)
+  return ngx.header[name]
+end
+
+local function set_response_header(name, value)
+  ngx.header[name] = value
+end
+
+-- rewrite changes the headers of the request sent to the upstream
+function _M.rewrite(ops)
+  apply(ops, get_request_header, set_request_header)
+end
+
+-- header_filter changes the headers of the response sent to the client
+function _M.header_filter(ops)
+  apply(ops, get_response_header, set_response_header)
+end
+
+return _M
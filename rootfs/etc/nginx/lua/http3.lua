@@ -0,0 +1,19 @@
+local string = string
+
+local _M = {}
+
+-- header_filter advertises the HTTP/3 listener in the responses sent over
+-- TLS, unless the backend already sent its own Alt-Svc header
+function _M.header_filter(config)
+  if ngx.var.https ~= "on" then
+    return
+  end
+
+  if ngx.header["Alt-Svc"] then
+    return
+  end
+
+  ngx.header["Alt-Svc"] = string.format('h3=":%d"; ma=%d', config.port, config.max_age)
+end
+
+return _M
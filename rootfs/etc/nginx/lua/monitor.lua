@@ -1,26 +1,26 @@
-local socket = ngx.socket.tcp
 local cjson = require("cjson.safe")
-local assert = assert
-local new_tab = require "table.new"
-local clear_tab = require "table.clear"
-local clone_tab = require "table.clone"
+local grpc = require("util.grpc")
+local metrics_batch = require("util.metrics_batch")
+local websocket = require("websocket")
 
--- if an Nginx worker processes more than (MAX_BATCH_SIZE/FLUSH_INTERVAL) RPS then it will start dropping metrics
-local MAX_BATCH_SIZE = 10000
-local FLUSH_INTERVAL = 1 -- second
+local batch = metrics_batch.new("request")
 
-local metrics_batch = new_tab(MAX_BATCH_SIZE, 0)
+-- the workers that are exiting cannot send their metrics: the worker exits as
+-- soon as only timers are left, before they expire. The metrics are queued in
+-- a shared dictionary instead, and sent by the workers that replaced them.
+local PENDING_KEY = "metrics"
 
 local _M = {}
 
-local function send(payload)
-  local s = assert(socket())
-  assert(s:connect("unix:/tmp/prometheus-nginx.socket"))
-  assert(s:send(payload))
-  assert(s:close())
-end
+local function metrics(location)
+  -- the gRPC requests of the gRPC locations are also reported by gRPC
+  -- service, method and status
+  local grpc_service, grpc_method, grpc_status
+  if location.grpc and (ngx.ctx.grpc_web or grpc.is_grpc(ngx.var.content_type)) then
+    grpc_service, grpc_method = grpc.method_labels(ngx.var.uri)
+    grpc_status = grpc.status(ngx.var.status, ngx.var.upstream_trailer_grpc_status or ngx.var.upstream_http_grpc_status)
+  end
 
-local function metrics()
   return {
     host = ngx.var.host or "-",
     namespace = ngx.var.namespace or "-",
@@ -38,50 +38,82 @@ local function metrics()
     upstreamResponseTime = tonumber(ngx.var.upstream_response_time) or -1,
     upstreamResponseLength = tonumber(ngx.var.upstream_response_length) or -1,
     --upstreamStatus = ngx.var.upstream_status or "-",
+
+    cacheStatus = ngx.var.upstream_cache_status,
+    validationError = ngx.ctx.request_validation_error,
+    upstreamRetries = ngx.ctx.upstream_retries,
+    retryBudgetExhausted = ngx.ctx.retry_budget_exhausted,
+    concurrencyLimit = ngx.ctx.concurrency_limit,
+
+    grpcService = grpc_service,
+    grpcMethod = grpc_method,
+    grpcStatus = grpc_status,
+
+    upgradeOutcome = websocket.outcome(),
   }
 end
 
-local function flush(premature)
-  if premature then
+-- queue adds the metrics of a request handled by an exiting worker to the
+-- ones sent by the other workers
+local function queue(request_metrics)
+  local payload, err = cjson.encode(request_metrics)
+  if not payload then
+    ngx.log(ngx.ERR, "error while encoding metrics: ", err)
     return
   end
 
-  if #metrics_batch == 0 then
-    return
+  local length, push_err = ngx.shared.monitor_pending:rpush(PENDING_KEY, payload)
+  if not length then
+    ngx.log(ngx.WARN, "omitting metrics for the request, pending metrics could not be queued: ", push_err)
   end
+end
 
-  local current_metrics_batch = clone_tab(metrics_batch)
-  clear_tab(metrics_batch)
+-- flush_pending sends the metrics queued by the exiting workers
+local function flush_pending()
+  local pending = ngx.shared.monitor_pending
+  local payloads = {}
+  for i = 1, metrics_batch.MAX_BATCH_SIZE do
+    local payload = pending:lpop(PENDING_KEY)
+    if not payload then
+      break
+    end
+    payloads[i] = payload
+  end
 
-  local payload, err = cjson.encode(current_metrics_batch)
-  if not payload then
-    ngx.log(ngx.ERR, "error while encoding metrics: ", err)
+  if #payloads == 0 then
     return
   end
 
-  send(payload)
+  metrics_batch.send("[" .. table.concat(payloads, ",") .. "]")
+end
+
+-- flush also runs when the timer expires prematurely so that the metrics of
+-- the worker that is shutting down are not lost
+local function flush()
+  flush_pending()
+  batch:flush()
 end
 
 function _M.init_worker()
-  local _, err = ngx.timer.every(FLUSH_INTERVAL, flush)
-  if err then
-    ngx.log(ngx.ERR, string.format("error when setting up timer.every: %s", tostring(err)))
-  end
+  metrics_batch.every(flush)
 end
 
-function _M.call()
-  local metrics_size = #metrics_batch
-  if metrics_size >= MAX_BATCH_SIZE then
-    ngx.log(ngx.WARN, "omitting metrics for the request, current batch is full")
+-- location contains grpc = true in the locations using the GRPC or GRPCS
+-- backend protocol
+function _M.call(location)
+  -- the flush interval timer is gone once the worker starts exiting, the
+  -- connections it keeps draining are reported by the other workers
+  if ngx.worker.exiting() then
+    queue(metrics(location or {}))
     return
   end
 
-  metrics_batch[metrics_size + 1] = metrics()
+  batch:add(metrics(location or {}))
 end
 
 if _TEST then
   _M.flush = flush
-  _M.get_metrics_batch = function() return metrics_batch end
+  _M.get_metrics_batch = function() return batch.metrics end
 end
 
 return _M
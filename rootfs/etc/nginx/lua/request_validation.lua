@@ -0,0 +1,302 @@
+local cjson = require("cjson.safe")
+
+local io = io
+local ipairs = ipairs
+local pairs = pairs
+local type = type
+local tonumber = tonumber
+local tostring = tostring
+local getmetatable = getmetatable
+local math_floor = math.floor
+local string_lower = string.lower
+local string_match = string.match
+
+-- a dedicated instance to decode the empty arrays with the array metatable
+-- without changing the behaviour of the other modules
+local json = cjson.new()
+if json.decode_array_with_array_mt then
+  json.decode_array_with_array_mt(true)
+end
+
+local _M = {}
+
+-- decoded schemas, indexed by their JSON document
+local schemas = {}
+
+local function is_array(value)
+  if json.array_mt and getmetatable(value) == json.array_mt then
+    return true
+  end
+
+  local count = 0
+  for _ in pairs(value) do
+    count = count + 1
+  end
+
+  return count > 0 and count == #value
+end
+
+local function json_type(value)
+  if value == json.null then
+    return "null"
+  end
+
+  local t = type(value)
+  if t == "table" then
+    return is_array(value) and "array" or "object"
+  end
+
+  if t == "number" and math_floor(value) == value then
+    return "integer"
+  end
+
+  return t
+end
+
+local function type_matches(expected, actual)
+  if type(expected) == "table" then
+    for _, t in ipairs(expected) do
+      if type_matches(t, actual) then
+        return true
+      end
+    end
+    return false
+  end
+
+  return expected == actual or (expected == "number" and actual == "integer")
+end
+
+local function is_equal(a, b)
+  if type(a) ~= "table" or type(b) ~= "table" then
+    return a == b
+  end
+
+  for k, v in pairs(a) do
+    if not is_equal(v, b[k]) then
+      return false
+    end
+  end
+  for k in pairs(b) do
+    if a[k] == nil then
+      return false
+    end
+  end
+
+  return true
+end
+
+-- validate checks a decoded JSON value against a subset of JSON schema:
+-- type, enum, properties, required, additionalProperties, items,
+-- minItems, maxItems, minLength, maxLength, pattern, minimum and maximum.
+-- It returns nil or a description of the first error found.
+local function validate(schema, value, path)
+  if type(schema) ~= "table" then
+    return nil
+  end
+
+  local actual = json_type(value)
+
+  if schema.type and not type_matches(schema.type, actual) then
+    return path .. ": expected type " .. cjson.encode(schema.type) .. " but got " .. actual
+  end
+
+  if schema.enum then
+    local found = false
+    for _, allowed in ipairs(schema.enum) do
+      if is_equal(allowed, value) then
+        found = true
+        break
+      end
+    end
+    if not found then
+      return path .. ": value is not one of the allowed values"
+    end
+  end
+
+  if actual == "object" then
+    for _, name in ipairs(schema.required or {}) do
+      if value[name] == nil then
+        return path .. ": missing required property " .. name
+      end
+    end
+
+    local properties = schema.properties or {}
+    for name, property in pairs(value) do
+      if properties[name] then
+        local err = validate(properties[name], property, path .. "." .. tostring(name))
+        if err then
+          return err
+        end
+      elseif schema.additionalProperties == false then
+        return path .. ": property " .. tostring(name) .. " is not allowed"
+      end
+    end
+  elseif actual == "array" then
+    if schema.minItems and #value < schema.minItems then
+      return path .. ": expected at least " .. schema.minItems .. " items"
+    end
+    if schema.maxItems and #value > schema.maxItems then
+      return path .. ": expected at most " .. schema.maxItems .. " items"
+    end
+
+    if schema.items then
+      for i, item in ipairs(value) do
+        local err = validate(schema.items, item, path .. "[" .. (i - 1) .. "]")
+        if err then
+          return err
+        end
+      end
+    end
+  elseif actual == "string" then
+    if schema.minLength and #value < schema.minLength then
+      return path .. ": expected at least " .. schema.minLength .. " characters"
+    end
+    if schema.maxLength and #value > schema.maxLength then
+      return path .. ": expected at most " .. schema.maxLength .. " characters"
+    end
+    if schema.pattern and not ngx.re.find(value, schema.pattern, "jo") then
+      return path .. ": value does not match pattern " .. schema.pattern
+    end
+  elseif actual == "number" or actual == "integer" then
+    if schema.minimum and value < schema.minimum then
+      return path .. ": value is lower than " .. schema.minimum
+    end
+    if schema.maximum and value > schema.maximum then
+      return path .. ": value is greater than " .. schema.maximum
+    end
+  end
+
+  return nil
+end
+
+local function get_schema(document)
+  local schema = schemas[document]
+  if schema then
+    return schema
+  end
+
+  local err
+  schema, err = json.decode(document)
+  if not schema then
+    return nil, err
+  end
+
+  schemas[document] = schema
+  return schema
+end
+
+local function content_type_allowed(content_types, header)
+  if #content_types == 0 then
+    return true
+  end
+
+  if type(header) == "table" then
+    header = header[1]
+  end
+  if not header then
+    return false
+  end
+
+  local media_type = string_lower(string_match(header, "^%s*([^;%s]+)") or "")
+  for _, allowed in ipairs(content_types) do
+    if media_type == allowed then
+      return true
+    end
+  end
+
+  return false
+end
+
+local function read_body()
+  ngx.req.read_body()
+
+  local body = ngx.req.get_body_data()
+  if body then
+    return body
+  end
+
+  -- the body is buffered to a file when it is larger than client_body_buffer_size
+  local file_name = ngx.req.get_body_file()
+  if not file_name then
+    return ""
+  end
+
+  local file, err = io.open(file_name, "rb")
+  if not file then
+    return nil, err
+  end
+
+  body = file:read("*a")
+  file:close()
+
+  return body
+end
+
+local function reject(config, reason, message)
+  ngx.ctx.request_validation_error = reason
+  ngx.log(ngx.INFO, "request rejected by the request validation: ", message)
+  return ngx.exit(config.status)
+end
+
+-- access rejects the requests with a body not matching the content types,
+-- the maximum size or the JSON schema of the location. Requests without a
+-- body, including the ones with an empty body, are not validated.
+function _M.access(config)
+  local headers = ngx.req.get_headers()
+  local content_length = tonumber(headers["Content-Length"])
+
+  if (not content_length or content_length == 0) and not headers["Transfer-Encoding"] then
+    return
+  end
+
+  if not content_type_allowed(config.content_types or {}, headers["Content-Type"]) then
+    return reject(config, "content_type", "content type " .. tostring(headers["Content-Type"]) .. " is not allowed")
+  end
+
+  local max_body_size = config.max_body_size or 0
+  if max_body_size > 0 and content_length and content_length > max_body_size then
+    return reject(config, "body_size", "body of " .. content_length .. " bytes is too large")
+  end
+
+  if max_body_size == 0 and not config.schema then
+    return
+  end
+
+  local body, err = read_body()
+  if not body then
+    ngx.log(ngx.ERR, "error reading the request body: ", err)
+    return ngx.exit(ngx.HTTP_INTERNAL_SERVER_ERROR)
+  end
+
+  if max_body_size > 0 and #body > max_body_size then
+    return reject(config, "body_size", "body of " .. #body .. " bytes is too large")
+  end
+
+  if not config.schema then
+    return
+  end
+
+  local schema
+  schema, err = get_schema(config.schema)
+  if not schema then
+    ngx.log(ngx.ERR, "error decoding the JSON schema: ", err)
+    return ngx.exit(ngx.HTTP_INTERNAL_SERVER_ERROR)
+  end
+
+  local value
+  value, err = json.decode(body)
+  if value == nil then
+    return reject(config, "invalid_json", "invalid JSON body: " .. tostring(err))
+  end
+
+  err = validate(schema, value, "$")
+  if err then
+    return reject(config, "schema", err)
+  end
+end
+
+if _TEST then
+  _M.validate = validate
+end
+
+return _M
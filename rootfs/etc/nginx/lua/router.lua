@@ -0,0 +1,95 @@
+local ipairs = ipairs
+local type = type
+local string_sub = string.sub
+local table_concat = table.concat
+local grpc = require("util.grpc")
+
+local _M = {}
+
+local function header_matches(values, match)
+  if values == nil then
+    return false
+  end
+
+  if type(values) ~= "table" then
+    values = { values }
+  end
+
+  for _, value in ipairs(values) do
+    if match.type == "prefix" then
+      if string_sub(value, 1, #match.value) == match.value then
+        return true
+      end
+    elseif value == match.value then
+      return true
+    end
+  end
+
+  return false
+end
+
+local function route_matches(route, method, get_headers)
+  if route.method and route.method ~= method then
+    return false
+  end
+
+  if route.grpc_service or route.grpc_method then
+    local grpc_service, grpc_method = grpc.parse_path(ngx.var.uri)
+    if route.grpc_service and route.grpc_service ~= grpc_service then
+      return false
+    end
+    if route.grpc_method and route.grpc_method ~= grpc_method then
+      return false
+    end
+  end
+
+  for _, match in ipairs(route.headers or {}) do
+    if not header_matches(get_headers()[match.name], match) then
+      return false
+    end
+  end
+
+  return true
+end
+
+local function is_allowed(methods, method)
+  for _, allowed in ipairs(methods) do
+    if allowed == method then
+      return true
+    end
+  end
+
+  return false
+end
+
+-- rewrite rejects the methods not allowed in the location and selects the
+-- backend of the first route matching the request. It must be called before
+-- balancer.rewrite() because the balancers use $proxy_upstream_name.
+function _M.rewrite(config)
+  local method = ngx.req.get_method()
+
+  local methods = config.methods or {}
+  if #methods > 0 and not is_allowed(methods, method) then
+    ngx.header["Allow"] = table_concat(methods, ", ")
+    return ngx.exit(ngx.HTTP_NOT_ALLOWED)
+  end
+
+  -- the headers are only read if a route has header conditions
+  local headers
+  local get_headers = function()
+    if not headers then
+      headers = ngx.req.get_headers()
+    end
+    return headers
+  end
+
+  for _, route in ipairs(config.routes or {}) do
+    if route_matches(route, method, get_headers) then
+      ngx.var.proxy_upstream_name = route.backend
+      ngx.var.service_name = route.service
+      return
+    end
+  end
+end
+
+return _M
@@ -4,6 +4,9 @@ local util = require("util")
 local dns_util = require("util.dns")
 local configuration = require("tcp_udp_configuration")
 local round_robin = require("balancer.round_robin")
+local chash = require("balancer.chash")
+local ewma = require("balancer.ewma")
+local least_conn = require("balancer.least_conn")
 
 -- measured in seconds
 -- for an Nginx worker to pick up the new list of upstream peers
@@ -12,7 +15,10 @@ local BACKENDS_SYNC_INTERVAL = 1
 
 local DEFAULT_LB_ALG = "round_robin"
 local IMPLEMENTATIONS = {
-  round_robin = round_robin
+  round_robin = round_robin,
+  chash = chash,
+  ewma = ewma,
+  least_conn = least_conn,
 }
 
 local _M = {}
@@ -21,6 +27,10 @@ local balancers = {}
 local function get_implementation(backend)
   local name = backend["load-balance"] or DEFAULT_LB_ALG
 
+  if backend["upstreamHashByConfig"] and backend["upstreamHashByConfig"]["upstream-hash-by"] then
+    name = "chash"
+  end
+
   local implementation = IMPLEMENTATIONS[name]
   if not implementation then
     ngx.log(ngx.WARN, string.format("%s is not supported, falling back to %s", backend["load-balance"], DEFAULT_LB_ALG))
@@ -136,6 +146,38 @@ function _M.init_worker()
   end
 end
 
+-- routes the connection to the upstream of the TLS server name (SNI) sent by
+-- the client, read by ssl_preread. routes is a table with the upstream name,
+-- namespace and name of the services indexed by hostname
+function _M.route_by_sni(routes)
+  local server_name = ngx.var.ssl_preread_server_name
+  local route = server_name and routes[string.lower(server_name)]
+  if not route then
+    ngx.log(ngx.WARN, string.format("there is no TCP service for server name \"%s\" in port %s",
+      tostring(server_name), ngx.var.server_port))
+    return ngx.exit(ngx.ERROR)
+  end
+
+  ngx.var.proxy_upstream_name = route.upstream
+  -- used to report the metrics of the service
+  ngx.ctx.stream_service = route
+end
+
+-- routes the connection to the upstream of the service listening in the port
+-- of the dynamic port range, configured without a reload. protocol is "tcp"
+-- or "udp"
+function _M.route_by_port(protocol)
+  local route = configuration.get_route(protocol, ngx.var.server_port)
+  if not route then
+    ngx.log(ngx.WARN, string.format("there is no %s service in port %s", protocol, ngx.var.server_port))
+    return ngx.exit(ngx.ERROR)
+  end
+
+  ngx.var.proxy_upstream_name = route.upstream
+  -- used to report the metrics of the service
+  ngx.ctx.stream_service = route
+end
+
 function _M.balance()
   local balancer = get_balancer()
   if not balancer then
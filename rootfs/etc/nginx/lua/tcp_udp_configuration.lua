@@ -1,12 +1,41 @@
+local cjson = require("cjson.safe")
+
 -- this is the Lua representation of TCP/UDP Configuration
 local tcp_udp_configuration_data = ngx.shared.tcp_udp_configuration_data
 
 local _M = {}
 
+-- routes decoded by this worker, and the JSON document they come from
+local routes_data
+local routes = {}
+
 function _M.get_backends_data()
   return tcp_udp_configuration_data:get("backends")
 end
 
+-- get_route returns the upstream, namespace and name of the service of the
+-- protocol without hostname listening in the port
+function _M.get_route(protocol, port)
+  local data = tcp_udp_configuration_data:get("routes")
+  if data ~= routes_data then
+    local decoded, err = cjson.decode(data or "{}")
+    if not decoded then
+      ngx.log(ngx.ERR, "could not parse routes data: ", err)
+      return nil
+    end
+
+    routes = decoded
+    routes_data = data
+  end
+
+  local protocol_routes = routes[protocol]
+  if not protocol_routes then
+    return nil
+  end
+
+  return protocol_routes[tostring(port)]
+end
+
 function _M.call()
   local sock, err = ngx.req.socket(true)
   if not sock then
@@ -33,6 +62,19 @@ function _M.call()
     ngx.say("error: ", err_conf)
     return
   end
+
+  -- the routes by port of the TCP and UDP services follow the backends
+  local routes_line = reader()
+  if not routes_line or routes_line == "" then
+    return
+  end
+
+  success, err_conf = tcp_udp_configuration_data:set("routes", routes_line)
+  if not success then
+    ngx.log(ngx.ERR, "dynamic-configuration: error updating routes: " .. tostring(err_conf))
+    ngx.say("error: ", err_conf)
+    return
+  end
 end
 
 return _M
@@ -0,0 +1,69 @@
+local metrics_batch = require("util.metrics_batch")
+local split = require("util.split")
+
+-- the status of the sessions closed by the access rules and the connection limit
+local BLOCKED_REASONS = {
+  ["403"] = "denied",
+  ["503"] = "limited",
+}
+
+local batch = metrics_batch.new("session")
+
+local _M = {}
+
+-- returns the value of the last upstream server of a variable with the
+-- values of every try, like $upstream_connect_time
+local function last_value(var)
+  local values = split.split_upstream_var(var)
+  if not values or #values == 0 then
+    return nil
+  end
+  return values[#values]
+end
+
+-- service contains the protocol, namespace, name and external port of the
+-- stream service. The namespace and name of the services of a port routed by
+-- hostname are known after the hostname is read
+local function metrics(service)
+  local routed = ngx.ctx.stream_service or {}
+
+  return {
+    stream = {
+      protocol = service.protocol,
+      namespace = service.namespace or routed.namespace or "-",
+      service = service.service or routed.service or "-",
+      port = tostring(service.port),
+
+      status = ngx.var.status or "-",
+      bytesSent = tonumber(ngx.var.bytes_sent) or -1,
+      bytesReceived = tonumber(ngx.var.bytes_received) or -1,
+      sessionTime = tonumber(ngx.var.session_time) or -1,
+      upstreamConnectTime = tonumber(last_value(ngx.var.upstream_connect_time)) or -1,
+
+      blocked = BLOCKED_REASONS[ngx.var.status],
+    }
+  }
+end
+
+local function flush(premature)
+  if premature then
+    return
+  end
+
+  batch:flush()
+end
+
+function _M.init_worker()
+  metrics_batch.every(flush)
+end
+
+function _M.call(service)
+  batch:add(metrics(service))
+end
+
+if _TEST then
+  _M.flush = flush
+  _M.get_metrics_batch = function() return batch.metrics end
+end
+
+return _M
@@ -0,0 +1,63 @@
+local util = require("util")
+
+describe("Balancer least_conn", function()
+  local balancer_least_conn = require("balancer.least_conn")
+  local backend, instance
+
+  before_each(function()
+    _G.ngx.ctx = {}
+    backend = {
+      name = "my-dummy-backend", ["load-balance"] = "least_conn",
+      endpoints = {
+        { address = "10.184.7.40", port = "8080", maxFails = 0, failTimeout = 0 },
+        { address = "10.184.97.100", port = "8080", maxFails = 0, failTimeout = 0 },
+      }
+    }
+    instance = balancer_least_conn:new(backend)
+  end)
+
+  describe("balance()", function()
+    it("picks the endpoint with the least active connections", function()
+      instance.connections = { ["10.184.7.40:8080"] = 3, ["10.184.97.100:8080"] = 1 }
+
+      local peer = instance:balance()
+      assert.equal("10.184.97.100:8080", peer)
+      assert.equal(2, instance.connections["10.184.97.100:8080"])
+    end)
+
+    it("spreads the connections among the endpoints", function()
+      local first = instance:balance()
+      _G.ngx.ctx = {}
+      local second = instance:balance()
+
+      assert.are_not.equal(first, second)
+    end)
+  end)
+
+  describe("after_balance()", function()
+    it("releases the endpoints of every try", function()
+      instance:balance()
+      instance:balance()
+      assert.equal(1, instance.connections["10.184.7.40:8080"])
+      assert.equal(1, instance.connections["10.184.97.100:8080"])
+
+      instance:after_balance()
+      assert.equal(0, instance.connections["10.184.7.40:8080"])
+      assert.equal(0, instance.connections["10.184.97.100:8080"])
+      assert.is_nil(ngx.ctx.least_conn_peers)
+    end)
+  end)
+
+  describe("sync()", function()
+    it("keeps the active connections of the remaining endpoints", function()
+      instance.connections = { ["10.184.7.40:8080"] = 3, ["10.184.97.100:8080"] = 1 }
+
+      local new_backend = util.deepcopy(backend)
+      table.remove(new_backend.endpoints, 2)
+      instance:sync(new_backend)
+
+      assert.are.same(new_backend.endpoints, instance.peers)
+      assert.are.same({ ["10.184.7.40:8080"] = 3 }, instance.connections)
+    end)
+  end)
+end)
@@ -174,4 +174,181 @@ describe("Balancer", function()
       assert.stub(mock_instance.sync).was_called_with(mock_instance, backend)
     end)
   end)
+
+  describe("track_try()", function()
+    local original_ngx = ngx
+
+    local function mock_request(request_id, retry_policy)
+      local _ngx = {
+        ctx = { retry_policy = retry_policy },
+        var = { request_id = request_id, proxy_upstream_name = "my-dummy-app-1" },
+      }
+      setmetatable(_ngx, { __index = original_ngx })
+      _G.ngx = _ngx
+      return _ngx
+    end
+
+    after_each(function()
+      _G.ngx = original_ngx
+    end)
+
+    it("counts the retries of the request", function()
+      local request = mock_request("1", nil)
+
+      assert.is_true(balancer.track_try())
+      assert.is_nil(request.ctx.upstream_retries)
+      assert.is_true(balancer.track_try())
+      assert.is_true(balancer.track_try())
+      assert.equal(2, request.ctx.upstream_retries)
+
+      local budget = balancer.get_retry_budget("my-dummy-app-1")
+      assert.equal(1, budget.requests)
+      assert.equal(1, budget.retries)
+
+      balancer.release_try()
+      assert.equal(0, budget.requests)
+      assert.equal(0, budget.retries)
+    end)
+
+    it("rejects the retries exceeding the retry budget", function()
+      local requests = {}
+      for i = 1, 4 do
+        requests[i] = mock_request(tostring(i), { budget = 20 })
+        assert.is_true(balancer.track_try())
+      end
+
+      -- the minimum of concurrent retries is allowed regardless of the budget
+      for i = 1, 3 do
+        _G.ngx = requests[i]
+        assert.is_true(balancer.track_try())
+      end
+
+      _G.ngx = requests[4]
+      assert.is_false(balancer.track_try())
+      assert.is_true(requests[4].ctx.retry_budget_exhausted)
+
+      _G.ngx = requests[1]
+      balancer.release_try()
+
+      _G.ngx = requests[4]
+      requests[4].ctx.balancer_tries = 1
+      assert.is_true(balancer.track_try())
+    end)
+
+    it("releases the requests that were not released in the log phase", function()
+      local request = mock_request("1", nil)
+      request.now = function() return 1000 end
+      assert.is_true(balancer.track_try())
+      assert.is_true(balancer.track_try())
+
+      local budget = balancer.get_retry_budget("my-dummy-app-1")
+
+      request.now = function() return 1000 + 60 end
+      balancer.release_stale_requests(false)
+      assert.equal(1, budget.requests)
+
+      request.now = function() return 1000 + 3601 end
+      balancer.release_stale_requests(false)
+      assert.equal(0, budget.requests)
+      assert.equal(0, budget.retries)
+
+      -- releasing it again in the log phase has no effect
+      balancer.release_try()
+      assert.equal(0, budget.requests)
+    end)
+  end)
+
+  describe("limit_concurrency()", function()
+    local original_ngx = ngx
+
+    local function mock_request(request_id)
+      local _ngx = {
+        ctx = {},
+        var = { request_id = request_id, proxy_upstream_name = "my-dummy-app-1" },
+        sleep = function() end,
+      }
+      setmetatable(_ngx, { __index = original_ngx })
+      _G.ngx = _ngx
+      return _ngx
+    end
+
+    before_each(function()
+      ngx.shared.balancer_concurrency:flush_all()
+    end)
+
+    after_each(function()
+      _G.ngx = original_ngx
+    end)
+
+    it("does not limit backends without a limit", function()
+      mock_request("1")
+
+      assert.is_true(balancer.limit_concurrency("my-dummy-app-1"))
+      assert.is_nil(ngx.shared.balancer_concurrency:get("my-dummy-app-1"))
+    end)
+
+    it("rejects the requests exceeding the limit of the backend", function()
+      balancer.sync_backend({ name = "my-dummy-app-1", concurrencyLimit = { maxRequests = 2 } })
+
+      local first = mock_request("1")
+      assert.is_true(balancer.limit_concurrency("my-dummy-app-1"))
+      -- an internal redirect keeps the slot of the request
+      assert.is_true(balancer.limit_concurrency("my-dummy-app-1"))
+      mock_request("2")
+      assert.is_true(balancer.limit_concurrency("my-dummy-app-1"))
+
+      local third = mock_request("3")
+      assert.is_false(balancer.limit_concurrency("my-dummy-app-1"))
+      assert.equal("rejected", third.ctx.concurrency_limit)
+      assert.equal(2, ngx.shared.balancer_concurrency:get("my-dummy-app-1"))
+
+      _G.ngx = first
+      balancer.release_concurrency()
+      assert.equal(1, ngx.shared.balancer_concurrency:get("my-dummy-app-1"))
+
+      _G.ngx = third
+      assert.is_true(balancer.limit_concurrency("my-dummy-app-1"))
+    end)
+
+    it("queues the requests until the queue timeout", function()
+      balancer.sync_backend({ name = "my-dummy-app-1", concurrencyLimit = { maxRequests = 1, queueTimeout = 100 } })
+
+      local first = mock_request("1")
+      assert.is_true(balancer.limit_concurrency("my-dummy-app-1"))
+
+      local second = mock_request("2")
+      second.sleep = function()
+        _G.ngx = first
+        balancer.release_concurrency()
+        _G.ngx = second
+      end
+
+      assert.is_true(balancer.limit_concurrency("my-dummy-app-1"))
+      assert.equal("queued", second.ctx.concurrency_limit)
+      assert.equal(1, ngx.shared.balancer_concurrency:get("my-dummy-app-1"))
+    end)
+
+    it("releases the slots that were not released in the log phase", function()
+      balancer.sync_backend({ name = "my-dummy-app-1", concurrencyLimit = { maxRequests = 1 } })
+
+      local request = mock_request("1")
+      request.now = function() return 1000 end
+      assert.is_true(balancer.limit_concurrency("my-dummy-app-1"))
+
+      request.now = function() return 1000 + 60 end
+      balancer.release_stale_requests(false)
+      assert.equal(1, ngx.shared.balancer_concurrency:get("my-dummy-app-1"))
+
+      request.now = function() return 1000 + 3601 end
+      balancer.release_stale_requests(false)
+      assert.equal(0, ngx.shared.balancer_concurrency:get("my-dummy-app-1"))
+
+      -- releasing it again in the log phase has no effect
+      balancer.release_concurrency()
+      assert.equal(0, ngx.shared.balancer_concurrency:get("my-dummy-app-1"))
+
+      mock_request("2")
+      assert.is_true(balancer.limit_concurrency("my-dummy-app-1"))
+    end)
+  end)
 end)
@@ -92,6 +92,31 @@ describe("Certificate", function()
       assert.spy(ssl.set_der_priv_key).was_called_with(ssl.priv_key_pem_to_der(PEM_CERT_KEY))
     end)
 
+    it("falls back to the certificate of a wildcard server matching more than one label", function()
+      ssl.server_name = function() return "sub.nested.hostname.com", nil end
+      ngx.shared.certificate_data:set("*.hostname.com", PEM_CERT_KEY)
+
+      spy.on(ngx, "log")
+      spy.on(ssl, "set_der_cert")
+
+      assert.has_no.errors(certificate.call)
+      assert.spy(ngx.log).was_not_called_with(ngx.ERR, _)
+      assert.spy(ssl.set_der_cert).was_called_with(ssl.cert_pem_to_der(PEM_CERT_KEY))
+    end)
+
+    it("prefers the certificate of the most specific wildcard server", function()
+      ssl.server_name = function() return "sub.nested.hostname.com", nil end
+      ngx.shared.certificate_data:set("*.hostname.com", "something invalid")
+      ngx.shared.certificate_data:set("*.nested.hostname.com", PEM_CERT_KEY)
+
+      spy.on(ngx, "log")
+      spy.on(ssl, "set_der_cert")
+
+      assert.has_no.errors(certificate.call)
+      assert.spy(ngx.log).was_not_called_with(ngx.ERR, _)
+      assert.spy(ssl.set_der_cert).was_called_with(ssl.cert_pem_to_der(PEM_CERT_KEY))
+    end)
+
     it("logs error message when certificate in dictionary is invalid", function()
       ngx.shared.certificate_data:set("hostname", "something invalid")
 
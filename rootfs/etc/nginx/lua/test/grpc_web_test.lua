@@ -0,0 +1,117 @@
+_G._TEST = true
+
+local original_ngx = ngx
+local function reset_ngx()
+  _G.ngx = original_ngx
+end
+
+local function mock_ngx(mock)
+  local _ngx = mock
+  setmetatable(_ngx, { __index = ngx })
+  _G.ngx = _ngx
+end
+
+describe("gRPC-Web", function()
+  local grpc_web = require("grpc_web")
+
+  after_each(function()
+    reset_ngx()
+  end)
+
+  describe("rewrite()", function()
+    it("sends the gRPC-Web requests as gRPC requests", function()
+      local request_headers = {}
+      mock_ngx({
+        ctx = {},
+        var = { content_type = "application/grpc-web+proto" },
+        req = { set_header = function(name, value) request_headers[name] = value end },
+      })
+
+      grpc_web.rewrite()
+
+      assert.equal("application/grpc+proto", request_headers["Content-Type"])
+      assert.equal("application/grpc-web+proto", ngx.ctx.grpc_web)
+    end)
+
+    it("does not modify other requests", function()
+      local req = { set_header = function() end }
+      local s = spy.on(req, "set_header")
+      mock_ngx({ ctx = {}, var = { content_type = "application/grpc" }, req = req })
+
+      grpc_web.rewrite()
+
+      assert.spy(s).was_not_called()
+      assert.is_nil(ngx.ctx.grpc_web)
+    end)
+
+    it("rejects the text format", function()
+      mock_ngx({ ctx = {}, var = { content_type = "application/grpc-web-text" }, exit = function(status) return status end })
+
+      assert.equal(415, grpc_web.rewrite())
+    end)
+  end)
+
+  describe("header_filter()", function()
+    it("sets the gRPC-Web content type", function()
+      mock_ngx({
+        ctx = { grpc_web = "application/grpc-web" },
+        var = { upstream_http_content_type = "application/grpc" },
+        header = { ["Content-Type"] = "application/grpc", ["Content-Length"] = "10" },
+      })
+
+      grpc_web.header_filter()
+
+      assert.equal("application/grpc-web", ngx.header["Content-Type"])
+      assert.is_nil(ngx.header["Content-Length"])
+    end)
+
+    it("does not modify the responses generated by NGINX", function()
+      for _, upstream_content_type in ipairs({ false, "text/html" }) do
+        mock_ngx({
+          ctx = { grpc_web = "application/grpc-web" },
+          var = { upstream_http_content_type = upstream_content_type or nil },
+          header = { ["Content-Type"] = "text/html", ["Content-Length"] = "150" },
+        })
+
+        grpc_web.header_filter()
+
+        assert.equal("text/html", ngx.header["Content-Type"])
+        assert.equal("150", ngx.header["Content-Length"])
+      end
+    end)
+  end)
+
+  describe("body_filter()", function()
+    it("appends the trailers at the end of the body", function()
+      mock_ngx({
+        ctx = { grpc_web = "application/grpc-web" },
+        arg = { "message", true },
+        var = { upstream_trailer_grpc_status = "5", upstream_trailer_grpc_message = "not found" },
+      })
+
+      grpc_web.body_filter()
+
+      local trailers = "grpc-status:5\r\ngrpc-message:not found\r\n"
+      assert.equal("message" .. string.char(0x80, 0, 0, 0, #trailers) .. trailers, ngx.arg[1])
+    end)
+
+    it("does not modify the body before the end", function()
+      mock_ngx({
+        ctx = { grpc_web = "application/grpc-web" },
+        arg = { "message", false },
+        var = { upstream_trailer_grpc_status = "0" },
+      })
+
+      grpc_web.body_filter()
+
+      assert.equal("message", ngx.arg[1])
+    end)
+  end)
+
+  describe("frame()", function()
+    it("encodes the length in big-endian order", function()
+      local data = string.rep("a", 258)
+      assert.equal(string.char(0x80, 0, 0, 1, 2) .. data, grpc_web.frame(0x80, data))
+    end)
+  end)
+end)
@@ -0,0 +1,65 @@
+local original_ngx = ngx
+local function reset_ngx()
+  _G.ngx = original_ngx
+end
+
+local function mock_ngx(mock)
+  local _ngx = mock
+  setmetatable(_ngx, { __index = ngx })
+  _G.ngx = _ngx
+end
+
+local function mock_request_headers(request_headers)
+  local req = {
+    get_headers = function() return request_headers end,
+    set_header = function(name, value) request_headers[name] = value end,
+    clear_header = function(name) request_headers[name] = nil end,
+  }
+  mock_ngx({ req = req })
+end
+
+describe("Headers", function()
+  local headers = require("headers")
+
+  after_each(function()
+    reset_ngx()
+  end)
+
+  describe("rewrite()", function()
+    it("removes, sets and appends request headers", function()
+      local request_headers = { ["Cookie"] = "a=b", ["X-Env"] = "staging", ["X-A"] = "0" }
+      mock_request_headers(request_headers)
+
+      headers.rewrite({
+        remove = { "Cookie" },
+        set = { { "X-Env", "production" } },
+        add = { { "X-A", "1" }, { "X-B", "2" } },
+      })
+
+      assert.same({ ["X-Env"] = "production", ["X-A"] = { "0", "1" }, ["X-B"] = "2" }, request_headers)
+    end)
+
+    it("appends to headers with several values", function()
+      local request_headers = { ["X-A"] = { "0", "1" } }
+      mock_request_headers(request_headers)
+
+      headers.rewrite({ add = { { "X-A", "2" } } })
+
+      assert.same({ ["X-A"] = { "0", "1", "2" } }, request_headers)
+    end)
+  end)
+
+  describe("header_filter()", function()
+    it("removes, sets and appends response headers", function()
+      mock_ngx({ header = { ["Server"] = "nginx", ["Cache-Control"] = "public" } })
+
+      headers.header_filter({
+        remove = { "Server" },
+        set = { { "Cache-Control", "no-store" } },
+        add = { { "Set-Cookie", "a=b" } },
+      })
+
+      assert.same({ ["Cache-Control"] = "no-store", ["Set-Cookie"] = "a=b" }, ngx.header)
+    end)
+  end)
+end)
@@ -0,0 +1,51 @@
+local original_ngx = ngx
+local function reset_ngx()
+  _G.ngx = original_ngx
+end
+
+local function mock_ngx(https, response_headers)
+  local _ngx = {
+    var = { https = https },
+    header = response_headers,
+  }
+  setmetatable(_ngx, { __index = ngx })
+  _G.ngx = _ngx
+end
+
+describe("HTTP/3", function()
+  local http3 = require("http3")
+  local config = { port = 443, max_age = 86400 }
+
+  after_each(function()
+    reset_ngx()
+  end)
+
+  describe("header_filter()", function()
+    it("advertises the HTTP/3 listener in the HTTPS responses", function()
+      local response_headers = {}
+      mock_ngx("on", response_headers)
+
+      http3.header_filter(config)
+
+      assert.equal('h3=":443"; ma=86400', response_headers["Alt-Svc"])
+    end)
+
+    it("does not advertise the HTTP/3 listener in the HTTP responses", function()
+      local response_headers = {}
+      mock_ngx("", response_headers)
+
+      http3.header_filter(config)
+
+      assert.is_nil(response_headers["Alt-Svc"])
+    end)
+
+    it("keeps the Alt-Svc header of the backend", function()
+      local response_headers = { ["Alt-Svc"] = 'h3=":8443"' }
+      mock_ngx("on", response_headers)
+
+      http3.header_filter(config)
+
+      assert.equal('h3=":8443"', response_headers["Alt-Svc"])
+    end)
+  end)
+end)
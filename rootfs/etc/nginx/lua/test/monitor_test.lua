@@ -1,4 +1,5 @@
 _G._TEST = true
+local cjson = require("cjson")
 
 local original_ngx = ngx
 local function reset_ngx()
@@ -27,6 +28,7 @@ end
 describe("Monitor", function()
   after_each(function()
     reset_ngx()
+    ngx.shared.monitor_pending:flush_all()
     package.loaded["monitor"] = nil
   end)
 
@@ -41,8 +43,97 @@ describe("Monitor", function()
     assert.equal(10, #monitor.get_metrics_batch())
   end)
 
+  it("reports the gRPC service, method and status of the gRPC requests", function()
+    local monitor = require("monitor")
+
+    mock_ngx({
+      ctx = {},
+      var = {
+        content_type = "application/grpc", uri = "/helloworld.Greeter/SayHello", status = "200",
+        upstream_trailer_grpc_status = "5",
+      },
+    })
+    monitor.call({ grpc = true })
+    mock_ngx({ ctx = {}, var = { content_type = "application/grpc", uri = "/helloworld.Greeter/SayHello", status = "502" } })
+    monitor.call({ grpc = true })
+    mock_ngx({ ctx = {}, var = { content_type = "application/json", uri = "/api/users", status = "200" } })
+    monitor.call({ grpc = true })
+
+    local batch = monitor.get_metrics_batch()
+    assert.equal("helloworld.Greeter", batch[1].grpcService)
+    assert.equal("SayHello", batch[1].grpcMethod)
+    assert.equal("5", batch[1].grpcStatus)
+    assert.equal("14", batch[2].grpcStatus)
+    assert.is_nil(batch[3].grpcService)
+    assert.is_nil(batch[3].grpcStatus)
+  end)
+
+  it("does not report by gRPC service and method outside the gRPC locations", function()
+    local monitor = require("monitor")
+
+    mock_ngx({ ctx = {}, var = { content_type = "application/grpc", uri = "/helloworld.Greeter/SayHello", status = "200" } })
+    monitor.call()
+
+    local batch = monitor.get_metrics_batch()
+    assert.is_nil(batch[1].grpcService)
+    assert.is_nil(batch[1].grpcMethod)
+    assert.is_nil(batch[1].grpcStatus)
+  end)
+
+  it("reports the paths that are not gRPC methods with a fixed service and method", function()
+    local monitor = require("monitor")
+
+    mock_ngx({ ctx = {}, var = { content_type = "application/grpc", uri = "/users/a-1b2c", status = "200" } })
+    monitor.call({ grpc = true })
+    mock_ngx({ ctx = {}, var = { content_type = "application/grpc", uri = "/api/v1/users", status = "200" } })
+    monitor.call({ grpc = true })
+
+    local batch = monitor.get_metrics_batch()
+    assert.equal("-", batch[1].grpcService)
+    assert.equal("-", batch[1].grpcMethod)
+    assert.equal("-", batch[2].grpcService)
+    assert.equal("-", batch[2].grpcMethod)
+    assert.equal("2", batch[2].grpcStatus)
+  end)
+
+  it("reports how the upgraded connections ended", function()
+    local monitor = require("monitor")
+
+    mock_ngx({ ctx = {}, var = { status = "101" }, worker = { exiting = function() return false end } })
+    monitor.call()
+    mock_ngx({ ctx = {}, var = { status = "200" }, worker = { exiting = function() return false end } })
+    monitor.call()
+
+    local batch = monitor.get_metrics_batch()
+    assert.equal("closed", batch[1].upgradeOutcome)
+    assert.is_nil(batch[2].upgradeOutcome)
+  end)
+
+  it("queues the metrics while the worker is exiting for the other workers to send them", function()
+    local tcp_mock = mock_ngx_socket_tcp()
+    local monitor = require("monitor")
+
+    mock_ngx({
+      ctx = {},
+      var = { status = "101", request_completion = "" },
+      worker = { exiting = function() return true end },
+    })
+    monitor.call()
+
+    assert.equal(0, #monitor.get_metrics_batch())
+    assert.equal(1, ngx.shared.monitor_pending:llen("metrics"))
+
+    mock_ngx({ ctx = {}, var = {}, worker = { exiting = function() return false end } })
+    monitor.flush()
+
+    assert.equal(0, ngx.shared.monitor_pending:llen("metrics"))
+    assert.stub(tcp_mock.send).was_called(1)
+    local payload = cjson.decode(tcp_mock.send.calls[1].refs[2])
+    assert.equal("killed", payload[1].upgradeOutcome)
+  end)
+
   describe("flush", function()
-    it("short circuits when premmature is true (when worker is shutting down)", function()
+    it("sends the batched metrics when premature is true (when worker is shutting down)", function()
       local tcp_mock = mock_ngx_socket_tcp()
       local monitor = require("monitor")
       mock_ngx({ var = {} })
@@ -51,7 +142,8 @@ describe("Monitor", function()
         monitor.call()
       end
       monitor.flush(true)
-      assert.stub(tcp_mock.connect).was_not_called()
+      assert.stub(tcp_mock.connect).was_called_with(tcp_mock, "unix:/tmp/prometheus-nginx.socket")
+      assert.equal(0, #monitor.get_metrics_batch())
     end)
 
     it("short circuits when there's no metrics batched", function()
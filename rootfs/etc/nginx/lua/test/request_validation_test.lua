@@ -0,0 +1,114 @@
+_G._TEST = true
+
+local original_ngx = ngx
+local function reset_ngx()
+  _G.ngx = original_ngx
+end
+
+local function mock_ngx(request_headers, body)
+  local _ngx = {
+    ctx = {},
+    req = {
+      get_headers = function() return request_headers end,
+      read_body = function() end,
+      get_body_data = function() return body end,
+      get_body_file = function() return nil end,
+    },
+    exit = function(status) return status end,
+    log = function() end,
+  }
+  setmetatable(_ngx, { __index = ngx })
+  _G.ngx = _ngx
+end
+
+describe("Request validation", function()
+  local request_validation = require("request_validation")
+
+  local config = {
+    schema = [[{"type":"object","required":["id"],"additionalProperties":false,
+      "properties":{"id":{"type":"integer","minimum":1},"name":{"type":"string","maxLength":5},
+      "tags":{"type":"array","items":{"enum":["a","b"]}}}}]],
+    content_types = { "application/json" },
+    max_body_size = 64,
+    status = 422,
+  }
+
+  local function json_request(body)
+    mock_ngx({ ["Content-Type"] = "application/json; charset=utf-8", ["Content-Length"] = tostring(#body) }, body)
+  end
+
+  after_each(function()
+    reset_ngx()
+  end)
+
+  it("does not validate requests without a body", function()
+    mock_ngx({}, nil)
+
+    assert.is_nil(request_validation.access(config))
+    assert.is_nil(ngx.ctx.request_validation_error)
+  end)
+
+  it("does not validate requests with an empty body", function()
+    mock_ngx({ ["Content-Type"] = "text/plain", ["Content-Length"] = "0" }, nil)
+
+    assert.is_nil(request_validation.access(config))
+    assert.is_nil(ngx.ctx.request_validation_error)
+  end)
+
+  it("accepts valid requests", function()
+    json_request([[{"id":1,"name":"foo","tags":["a"]}]])
+
+    assert.is_nil(request_validation.access(config))
+    assert.is_nil(ngx.ctx.request_validation_error)
+  end)
+
+  it("rejects content types that are not allowed", function()
+    mock_ngx({ ["Content-Type"] = "text/plain", ["Content-Length"] = "2" }, "{}")
+
+    assert.equal(422, request_validation.access(config))
+    assert.equal("content_type", ngx.ctx.request_validation_error)
+  end)
+
+  it("rejects bodies larger than the maximum size", function()
+    json_request([[{"id":1,"name":"]] .. string.rep("a", 64) .. [["}]])
+
+    assert.equal(422, request_validation.access(config))
+    assert.equal("body_size", ngx.ctx.request_validation_error)
+  end)
+
+  it("rejects invalid JSON bodies", function()
+    json_request([[{"id":]])
+
+    assert.equal(422, request_validation.access(config))
+    assert.equal("invalid_json", ngx.ctx.request_validation_error)
+  end)
+
+  it("rejects bodies not matching the schema", function()
+    local bodies = {
+      [[{"name":"foo"}]],
+      [[{"id":0}]],
+      [[{"id":1.5}]],
+      [[{"id":1,"name":"foobar"}]],
+      [[{"id":1,"tags":["c"]}]],
+      [[{"id":1,"other":true}]],
+      [[["id"] ]],
+    }
+
+    for _, body in ipairs(bodies) do
+      json_request(body)
+
+      assert.equal(422, request_validation.access(config))
+      assert.equal("schema", ngx.ctx.request_validation_error)
+    end
+  end)
+
+  describe("validate()", function()
+    it("reports the path of the error", function()
+      local schema = { type = "object", properties = { items = { type = "array", items = { type = "string" } } } }
+
+      local err = request_validation.validate(schema, { items = { "a", 1 } }, "$")
+
+      assert.equal("$.items[1]: expected type \"string\" but got integer", err)
+    end)
+  end)
+end)
@@ -0,0 +1,99 @@
+local original_ngx = ngx
+local function reset_ngx()
+  _G.ngx = original_ngx
+end
+
+local function mock_ngx(method, request_headers, uri)
+  local _ngx = {
+    var = { proxy_upstream_name = "default-api-80", service_name = "api", uri = uri or "/" },
+    header = {},
+    req = {
+      get_method = function() return method end,
+      get_headers = function() return request_headers end,
+    },
+    exit = function(status) return status end,
+  }
+  setmetatable(_ngx, { __index = ngx })
+  _G.ngx = _ngx
+end
+
+describe("Router", function()
+  local router = require("router")
+
+  local config = {
+    methods = { "GET", "POST" },
+    routes = {
+      {
+        grpc_service = "helloworld.Greeter", grpc_method = "SayHello", headers = {},
+        backend = "default-greeter-v2-80", service = "greeter-v2",
+      },
+      { headers = { { name = "X-Api-Version", value = "2", type = "exact" } }, backend = "default-api-v2-80", service = "api-v2" },
+      { headers = { { name = "Content-Type", value = "application/grpc", type = "prefix" } }, backend = "default-grpc-80", service = "grpc" },
+      { method = "POST", headers = {}, backend = "default-writer-80", service = "writer" },
+    },
+  }
+
+  after_each(function()
+    reset_ngx()
+  end)
+
+  it("rejects methods that are not allowed", function()
+    mock_ngx("DELETE", {})
+
+    local status = router.rewrite(config)
+
+    assert.equal(ngx.HTTP_NOT_ALLOWED, status)
+    assert.equal("GET, POST", ngx.header["Allow"])
+  end)
+
+  it("keeps the backend of the location when no route matches", function()
+    mock_ngx("GET", { ["X-Api-Version"] = "1" })
+
+    router.rewrite(config)
+
+    assert.equal("default-api-80", ngx.var.proxy_upstream_name)
+    assert.equal("api", ngx.var.service_name)
+  end)
+
+  it("routes by exact header value", function()
+    mock_ngx("GET", { ["X-Api-Version"] = "2" })
+
+    router.rewrite(config)
+
+    assert.equal("default-api-v2-80", ngx.var.proxy_upstream_name)
+    assert.equal("api-v2", ngx.var.service_name)
+  end)
+
+  it("routes by header prefix", function()
+    mock_ngx("POST", { ["Content-Type"] = { "application/grpc+proto" } })
+
+    router.rewrite(config)
+
+    assert.equal("default-grpc-80", ngx.var.proxy_upstream_name)
+  end)
+
+  it("routes by method", function()
+    mock_ngx("POST", {})
+
+    router.rewrite(config)
+
+    assert.equal("default-writer-80", ngx.var.proxy_upstream_name)
+  end)
+
+  it("routes by gRPC service and method", function()
+    mock_ngx("POST", { ["Content-Type"] = "application/grpc" }, "/helloworld.Greeter/SayHello")
+
+    router.rewrite(config)
+
+    assert.equal("default-greeter-v2-80", ngx.var.proxy_upstream_name)
+    assert.equal("greeter-v2", ngx.var.service_name)
+  end)
+
+  it("does not route other gRPC methods", function()
+    mock_ngx("POST", { ["Content-Type"] = "application/grpc" }, "/helloworld.Greeter/SayGoodbye")
+
+    router.rewrite(config)
+
+    assert.equal("default-grpc-80", ngx.var.proxy_upstream_name)
+  end)
+end)
@@ -0,0 +1,73 @@
+_G._TEST = true
+
+local cjson = require("cjson")
+
+local original_ngx = ngx
+local function reset_ngx()
+  _G.ngx = original_ngx
+end
+
+local function mock_ngx(mock)
+  local _ngx = mock
+  setmetatable(_ngx, { __index = ngx })
+  _G.ngx = _ngx
+end
+
+describe("TCP/UDP balancer", function()
+  local tcp_udp_balancer = require("tcp_udp_balancer")
+
+  describe("get_implementation()", function()
+    it("returns round_robin by default", function()
+      local backend = { name = "tcp-default-postgres-5432" }
+      assert.equal(package.loaded["balancer.round_robin"], tcp_udp_balancer.get_implementation(backend))
+    end)
+
+    it("returns the implementation of the load balancing algorithm", function()
+      local backend = { name = "tcp-default-postgres-5432", ["load-balance"] = "least_conn" }
+      assert.equal(package.loaded["balancer.least_conn"], tcp_udp_balancer.get_implementation(backend))
+
+      backend["load-balance"] = "ewma"
+      assert.equal(package.loaded["balancer.ewma"], tcp_udp_balancer.get_implementation(backend))
+    end)
+
+    it("returns chash when there is a hash key", function()
+      local backend = {
+        name = "udp-kube-system-kube-dns-53",
+        upstreamHashByConfig = { ["upstream-hash-by"] = "$remote_addr" },
+      }
+      assert.equal(package.loaded["balancer.chash"], tcp_udp_balancer.get_implementation(backend))
+    end)
+  end)
+
+  describe("route_by_port()", function()
+    local route = { upstream = "tcp-default-redis-6379", namespace = "default", service = "redis" }
+
+    before_each(function()
+      ngx.shared.tcp_udp_configuration_data:set("routes", cjson.encode({ tcp = { ["10000"] = route }, udp = {} }))
+    end)
+
+    after_each(function()
+      reset_ngx()
+      ngx.shared.tcp_udp_configuration_data:delete("routes")
+    end)
+
+    it("routes the connection to the service of the port", function()
+      mock_ngx({ var = { server_port = "10000" }, ctx = {} })
+
+      tcp_udp_balancer.route_by_port("tcp")
+
+      assert.equal("tcp-default-redis-6379", ngx.var.proxy_upstream_name)
+      assert.are.same(route, ngx.ctx.stream_service)
+    end)
+
+    it("closes the connection when there is no service in the port", function()
+      mock_ngx({ var = { server_port = "10000" }, ctx = {} })
+      local s = stub(ngx, "exit")
+
+      tcp_udp_balancer.route_by_port("udp")
+
+      assert.stub(s).was_called_with(ngx.ERROR)
+      assert.is_nil(ngx.var.proxy_upstream_name)
+    end)
+  end)
+end)
@@ -0,0 +1,110 @@
+_G._TEST = true
+
+local cjson = require("cjson")
+
+local original_ngx = ngx
+local function reset_ngx()
+  _G.ngx = original_ngx
+end
+
+local function mock_ngx(mock)
+  local _ngx = mock
+  setmetatable(_ngx, { __index = ngx })
+  _G.ngx = _ngx
+end
+
+local function mock_ngx_socket_tcp()
+  local tcp_mock = {}
+  stub(tcp_mock, "connect", true)
+  stub(tcp_mock, "send", true)
+  stub(tcp_mock, "close", true)
+
+  local socket_mock = {}
+  stub(socket_mock, "tcp", tcp_mock)
+  mock_ngx({ socket = socket_mock })
+
+  return tcp_mock
+end
+
+describe("TCP/UDP monitor", function()
+  local service = { protocol = "tcp", namespace = "default", service = "postgres", port = 5432 }
+
+  after_each(function()
+    reset_ngx()
+    package.loaded["tcp_udp_monitor"] = nil
+  end)
+
+  it("batches the sessions", function()
+    local tcp_udp_monitor = require("tcp_udp_monitor")
+
+    mock_ngx({
+      ctx = {},
+      var = {
+        status = "200", bytes_sent = "512", bytes_received = "256", session_time = "1.250",
+        upstream_connect_time = "0.010, 0.002",
+      },
+    })
+    tcp_udp_monitor.call(service)
+    mock_ngx({ ctx = {}, var = { status = "403", bytes_sent = "0", bytes_received = "0", session_time = "0.000" } })
+    tcp_udp_monitor.call(service)
+
+    local batch = tcp_udp_monitor.get_metrics_batch()
+    assert.equal(2, #batch)
+    assert.are.same({
+      protocol = "tcp", namespace = "default", service = "postgres", port = "5432",
+      status = "200", bytesSent = 512, bytesReceived = 256, sessionTime = 1.25, upstreamConnectTime = 0.002,
+    }, batch[1].stream)
+    assert.are.same({
+      protocol = "tcp", namespace = "default", service = "postgres", port = "5432",
+      status = "403", bytesSent = 0, bytesReceived = 0, sessionTime = 0, upstreamConnectTime = -1,
+      blocked = "denied",
+    }, batch[2].stream)
+  end)
+
+  it("uses the service of the hostname in the ports routed by hostname", function()
+    local tcp_udp_monitor = require("tcp_udp_monitor")
+
+    mock_ngx({ ctx = {}, var = { status = "503" } })
+    tcp_udp_monitor.call({ protocol = "tcp", port = 443 })
+    mock_ngx({
+      ctx = { stream_service = { upstream = "tcp-tenant-a-postgres-5432", namespace = "tenant-a", service = "postgres" } },
+      var = { status = "200" },
+    })
+    tcp_udp_monitor.call({ protocol = "tcp", port = 443 })
+
+    local batch = tcp_udp_monitor.get_metrics_batch()
+    assert.equal("-", batch[1].stream.namespace)
+    assert.equal("-", batch[1].stream.service)
+    assert.equal("limited", batch[1].stream.blocked)
+    assert.equal("tenant-a", batch[2].stream.namespace)
+    assert.equal("postgres", batch[2].stream.service)
+    assert.is_nil(batch[2].stream.blocked)
+  end)
+
+  it("sends the batched metrics", function()
+    local tcp_mock = mock_ngx_socket_tcp()
+    local payload
+    tcp_mock.send = function(_, data)
+      payload = data
+      return true
+    end
+    local tcp_udp_monitor = require("tcp_udp_monitor")
+    mock_ngx({ ctx = {}, var = { status = "403" } })
+
+    tcp_udp_monitor.call(service)
+    tcp_udp_monitor.flush()
+
+    assert.stub(tcp_mock.connect).was_called_with(tcp_mock, "unix:/tmp/prometheus-nginx.socket")
+    assert.are.same({
+      {
+        stream = {
+          protocol = "tcp", namespace = "default", service = "postgres", port = "5432",
+          status = "403", bytesSent = -1, bytesReceived = -1, sessionTime = -1, upstreamConnectTime = -1,
+          blocked = "denied",
+        },
+      },
+    }, cjson.decode(payload))
+    assert.stub(tcp_mock.close).was_called_with(tcp_mock)
+    assert.equal(0, #tcp_udp_monitor.get_metrics_batch())
+  end)
+end)
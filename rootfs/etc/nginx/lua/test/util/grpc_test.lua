@@ -0,0 +1,57 @@
+describe("gRPC utilities", function()
+  local grpc = require("util.grpc")
+
+  describe("parse_path()", function()
+    it("returns the service and the method", function()
+      local service, method = grpc.parse_path("/helloworld.Greeter/SayHello")
+      assert.equal("helloworld.Greeter", service)
+      assert.equal("SayHello", method)
+    end)
+
+    it("returns nil for other paths", function()
+      assert.is_nil(grpc.parse_path(nil))
+      assert.is_nil(grpc.parse_path("/"))
+      assert.is_nil(grpc.parse_path("/helloworld.Greeter"))
+      assert.is_nil(grpc.parse_path("/api/v1/users"))
+    end)
+  end)
+
+  describe("method_labels()", function()
+    it("returns the service and the method of the gRPC methods", function()
+      local service, method = grpc.method_labels("/helloworld.Greeter/SayHello")
+      assert.equal("helloworld.Greeter", service)
+      assert.equal("SayHello", method)
+    end)
+
+    it("returns a fixed value for the other paths", function()
+      for _, path in ipairs({ "/", "/api/v1/users", "/users/a-1b2c", "/helloworld..Greeter/SayHello", "/1Greeter/SayHello" }) do
+        local service, method = grpc.method_labels(path)
+        assert.equal("-", service)
+        assert.equal("-", method)
+      end
+    end)
+  end)
+
+  describe("is_grpc()", function()
+    it("matches the gRPC content types", function()
+      assert.is_true(grpc.is_grpc("application/grpc"))
+      assert.is_true(grpc.is_grpc("application/grpc+proto"))
+      assert.is_false(grpc.is_grpc("application/grpc-web"))
+      assert.is_false(grpc.is_grpc("application/json"))
+      assert.is_false(grpc.is_grpc(nil))
+    end)
+  end)
+
+  describe("status()", function()
+    it("returns the status sent by the upstream server", function()
+      assert.equal("5", grpc.status("200", "5"))
+      assert.equal("0", grpc.status("200", "0"))
+    end)
+
+    it("derives the status from the HTTP status", function()
+      assert.equal("14", grpc.status("502", nil))
+      assert.equal("12", grpc.status("404", ""))
+      assert.equal("2", grpc.status("200", nil))
+    end)
+  end)
+end)
@@ -0,0 +1,69 @@
+local cjson = require("cjson")
+
+local original_ngx = ngx
+local function reset_ngx()
+  _G.ngx = original_ngx
+end
+
+local function mock_ngx(mock)
+  local _ngx = mock
+  setmetatable(_ngx, { __index = ngx })
+  _G.ngx = _ngx
+end
+
+local function mock_ngx_socket_tcp()
+  local tcp_mock = {}
+  stub(tcp_mock, "connect", true)
+  stub(tcp_mock, "send", true)
+  stub(tcp_mock, "close", true)
+
+  local socket_mock = {}
+  stub(socket_mock, "tcp", tcp_mock)
+  mock_ngx({ socket = socket_mock })
+
+  return tcp_mock
+end
+
+describe("metrics batch", function()
+  local metrics_batch = require("util.metrics_batch")
+
+  after_each(function()
+    reset_ngx()
+  end)
+
+  it("sends the batched metrics and empties the batch", function()
+    local tcp_mock = mock_ngx_socket_tcp()
+    local batch = metrics_batch.new("request")
+
+    batch:add({ status = "200" })
+    batch:add({ status = "503" })
+    batch:flush()
+
+    assert.stub(tcp_mock.connect).was_called_with(tcp_mock, "unix:/tmp/prometheus-nginx.socket")
+    assert.stub(tcp_mock.send).was_called(1)
+    assert.are.same({ { status = "200" }, { status = "503" } }, cjson.decode(tcp_mock.send.calls[1].refs[2]))
+    assert.stub(tcp_mock.close).was_called_with(tcp_mock)
+    assert.equal(0, #batch.metrics)
+  end)
+
+  it("does not send empty batches", function()
+    local tcp_mock = mock_ngx_socket_tcp()
+
+    metrics_batch.new("session"):flush()
+
+    assert.stub(tcp_mock.connect).was_not_called()
+  end)
+
+  it("drops the metrics when the batch is full", function()
+    local batch = metrics_batch.new("session")
+    for i = 1, metrics_batch.MAX_BATCH_SIZE do
+      batch:add({ id = i })
+    end
+
+    local s_ngx_log = spy.on(ngx, "log")
+    batch:add({ id = 0 })
+
+    assert.equal(metrics_batch.MAX_BATCH_SIZE, #batch.metrics)
+    assert.spy(s_ngx_log).was_called_with(ngx.WARN, "omitting metrics for the ", "session", ", current batch is full")
+  end)
+end)
@@ -0,0 +1,47 @@
+_G._TEST = true
+
+local original_ngx = ngx
+local function reset_ngx()
+  _G.ngx = original_ngx
+end
+
+local function mock_ngx(status, exiting, request_completion)
+  local _ngx = {
+    var = { status = status, request_completion = request_completion },
+    worker = { exiting = function() return exiting end },
+  }
+  setmetatable(_ngx, { __index = ngx })
+  _G.ngx = _ngx
+end
+
+describe("WebSocket", function()
+  local websocket = require("websocket")
+
+  after_each(function()
+    reset_ngx()
+  end)
+
+  describe("outcome()", function()
+    it("ignores the requests that did not switch protocols", function()
+      mock_ngx("200", false, "OK")
+      assert.is_nil(websocket.outcome())
+    end)
+
+    it("returns closed while the worker is running", function()
+      mock_ngx("101", false, "OK")
+      assert.equal("closed", websocket.outcome())
+      mock_ngx("101", false, "")
+      assert.equal("closed", websocket.outcome())
+    end)
+
+    it("returns drained when a peer closes the connection while the worker is exiting", function()
+      mock_ngx("101", true, "OK")
+      assert.equal("drained", websocket.outcome())
+    end)
+
+    it("returns killed when NGINX closes the connection while the worker is exiting", function()
+      mock_ngx("101", true, "")
+      assert.equal("killed", websocket.outcome())
+    end)
+  end)
+end)
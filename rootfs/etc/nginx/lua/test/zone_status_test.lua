@@ -0,0 +1,140 @@
+_G._TEST = true
+
+local cjson = require("cjson.safe")
+
+local original_ngx = ngx
+local function reset_ngx()
+  _G.ngx = original_ngx
+end
+
+local function mock_ngx(mock)
+  local _ngx = mock
+  setmetatable(_ngx, { __index = ngx })
+  _G.ngx = _ngx
+end
+
+local function new_dict()
+  local data = {}
+  return {
+    incr = function(_, key, value, init)
+      data[key] = (data[key] or init) + value
+      return data[key], nil, false
+    end,
+    get = function(_, key)
+      return data[key]
+    end,
+    delete = function(_, key)
+      data[key] = nil
+    end,
+    get_keys = function(_, _)
+      local keys = {}
+      for key, _ in pairs(data) do
+        table.insert(keys, key)
+      end
+      return keys
+    end,
+  }
+end
+
+describe("Zone status", function()
+  local zone_status
+  local dict
+
+  before_each(function()
+    zone_status = require("zone_status")
+    dict = new_dict()
+  end)
+
+  after_each(function()
+    reset_ngx()
+    package.loaded["zone_status"] = nil
+  end)
+
+  it("does nothing when the shared dictionary is not defined", function()
+    mock_ngx({ shared = {}, var = { server_name = "example.com", status = "200" } })
+
+    assert.has_no.errors(function() zone_status.log() end)
+    assert.same({ serverZones = {}, upstreamZones = {} }, zone_status.status())
+  end)
+
+  it("accounts requests per server and upstream zone", function()
+    mock_ngx({ shared = { [zone_status.DICT_NAME] = dict }, var = {
+      server_name = "example.com", proxy_upstream_name = "default-echo-80",
+      status = "200", request_length = "100", bytes_sent = "1000",
+    } })
+    zone_status.log()
+
+    ngx.var.status = "503"
+    zone_status.log()
+
+    local zones = zone_status.status()
+    assert.same({
+      requests = 2, bytesIn = 200, bytesOut = 2000,
+      responses = { ["2xx"] = 1, ["5xx"] = 1 },
+    }, zones.serverZones["example.com"])
+    assert.same(zones.serverZones["example.com"], zones.upstreamZones["default-echo-80"])
+  end)
+
+  it("skips requests without upstream", function()
+    mock_ngx({ shared = { [zone_status.DICT_NAME] = dict }, var = {
+      server_name = "_", proxy_upstream_name = "-", status = "404",
+    } })
+    zone_status.log()
+
+    local zones = zone_status.status()
+    assert.same({ requests = 1, bytesIn = 0, bytesOut = 0, responses = { ["4xx"] = 1 } }, zones.serverZones["_"])
+    assert.same({}, zones.upstreamZones)
+  end)
+
+  it("returns the zones as JSON", function()
+    local payload
+    mock_ngx({ shared = { [zone_status.DICT_NAME] = dict }, var = {
+      server_name = "example.com", status = "200", request_method = "GET",
+    }, header = {}, print = function(p) payload = p end })
+    zone_status.log()
+
+    zone_status.call()
+
+    local zones = cjson.decode(payload)
+    assert.equal(1, zones.serverZones["example.com"].requests)
+    assert.equal("application/json", ngx.header.content_type)
+  end)
+
+  it("rejects methods other than GET and POST", function()
+    mock_ngx({ shared = { [zone_status.DICT_NAME] = dict }, var = { request_method = "DELETE" } })
+    stub(ngx, "print")
+
+    zone_status.call()
+
+    assert.equal(ngx.HTTP_BAD_REQUEST, ngx.status)
+  end)
+
+  it("prunes the zones removed from the configuration", function()
+    for _, var in ipairs({
+      { server_name = "example.com", proxy_upstream_name = "default-echo-80", status = "200" },
+      { server_name = "removed.com", proxy_upstream_name = "default-removed-80", status = "200" },
+    }) do
+      mock_ngx({ shared = { [zone_status.DICT_NAME] = dict }, var = var })
+      zone_status.log()
+    end
+
+    mock_ngx({
+      shared = { [zone_status.DICT_NAME] = dict },
+      var = { request_method = "POST" },
+      req = {
+        read_body = function() end,
+        get_body_data = function()
+          return cjson.encode({ servers = { "example.com" }, upstreams = { "default-echo-80" } })
+        end,
+      },
+    })
+    zone_status.call()
+    assert.equal(ngx.HTTP_CREATED, ngx.status)
+
+    local zones = zone_status.status()
+    assert.is_not_nil(zones.serverZones["example.com"])
+    assert.is_nil(zones.serverZones["removed.com"])
+    assert.is_not_nil(zones.upstreamZones["default-echo-80"])
+    assert.is_nil(zones.upstreamZones["default-removed-80"])
+  end)
+end)
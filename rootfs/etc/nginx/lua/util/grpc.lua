@@ -0,0 +1,69 @@
+local string_match = string.match
+local re_find = ngx.re.find
+
+-- the status seen by the gRPC clients when the response has no grpc-status,
+-- https://github.com/grpc/grpc/blob/master/doc/http-grpc-status-mapping.md
+local HTTP_TO_GRPC_STATUS = {
+  ["400"] = "13", -- INTERNAL
+  ["401"] = "16", -- UNAUTHENTICATED
+  ["403"] = "7",  -- PERMISSION_DENIED
+  ["404"] = "12", -- UNIMPLEMENTED
+  ["429"] = "14", -- UNAVAILABLE
+  ["502"] = "14",
+  ["503"] = "14",
+  ["504"] = "14",
+}
+local UNKNOWN_STATUS = "2"
+
+-- the service and method names accepted by the routing annotation, the
+-- other paths are reported with INVALID_NAME to keep the metrics bounded
+local SERVICE_REGEX = [[^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)*$]]
+local METHOD_REGEX = [[^[A-Za-z_][A-Za-z0-9_]*$]]
+local INVALID_NAME = "-"
+
+local _M = {}
+
+-- parse_path returns the service, including the package, and the method of
+-- the path of a gRPC request, /<package>.<Service>/<Method>
+function _M.parse_path(path)
+  if not path then
+    return nil, nil
+  end
+
+  return string_match(path, "^/([^/]+)/([^/]+)$")
+end
+
+-- method_labels returns the service and the method of the path of a gRPC
+-- request to be used in the metrics, or "-" for both when the path is not
+-- the one of a valid gRPC method
+function _M.method_labels(path)
+  local service, method = _M.parse_path(path)
+  if not service or not re_find(service, SERVICE_REGEX, "jo") or not re_find(method, METHOD_REGEX, "jo") then
+    return INVALID_NAME, INVALID_NAME
+  end
+
+  return service, method
+end
+
+-- is_grpc returns true when the content type is the one of a gRPC request,
+-- application/grpc or application/grpc+<codec>
+function _M.is_grpc(content_type)
+  if not content_type then
+    return false
+  end
+
+  return content_type == "application/grpc" or string_match(content_type, "^application/grpc%+") ~= nil
+end
+
+-- status returns the grpc-status sent by the upstream server, in the trailers
+-- or in the headers of a response without body, or the one derived from the
+-- HTTP status when there is none
+function _M.status(http_status, grpc_status)
+  if grpc_status and grpc_status ~= "" then
+    return grpc_status
+  end
+
+  return HTTP_TO_GRPC_STATUS[http_status] or UNKNOWN_STATUS
+end
+
+return _M
@@ -0,0 +1,69 @@
+local cjson = require("cjson.safe")
+local assert = assert
+local setmetatable = setmetatable
+local new_tab = require "table.new"
+local clear_tab = require "table.clear"
+local clone_tab = require "table.clone"
+
+-- if an Nginx worker processes more than (MAX_BATCH_SIZE/FLUSH_INTERVAL) requests or sessions per second then it will start dropping metrics
+local MAX_BATCH_SIZE = 10000
+local FLUSH_INTERVAL = 1 -- second
+
+local _M = {
+  MAX_BATCH_SIZE = MAX_BATCH_SIZE,
+}
+
+local batch = {}
+batch.__index = batch
+
+-- send writes a JSON payload of metrics to the socket of the controller
+function _M.send(payload)
+  local s = assert(ngx.socket.tcp())
+  assert(s:connect("unix:/tmp/prometheus-nginx.socket"))
+  assert(s:send(payload))
+  assert(s:close())
+end
+
+-- every calls the flush function of a monitor every flush interval
+function _M.every(flush)
+  local _, err = ngx.timer.every(FLUSH_INTERVAL, flush)
+  if err then
+    ngx.log(ngx.ERR, string.format("error when setting up timer.every: %s", tostring(err)))
+  end
+end
+
+-- new returns an empty batch of metrics, name is what the metrics measure,
+-- like "request", and is only used in the logs
+function _M.new(name)
+  return setmetatable({ name = name, metrics = new_tab(MAX_BATCH_SIZE, 0) }, batch)
+end
+
+function batch:add(metrics)
+  local metrics_size = #self.metrics
+  if metrics_size >= MAX_BATCH_SIZE then
+    ngx.log(ngx.WARN, "omitting metrics for the ", self.name, ", current batch is full")
+    return
+  end
+
+  self.metrics[metrics_size + 1] = metrics
+end
+
+-- flush sends the batched metrics and empties the batch
+function batch:flush()
+  if #self.metrics == 0 then
+    return
+  end
+
+  local current_metrics_batch = clone_tab(self.metrics)
+  clear_tab(self.metrics)
+
+  local payload, err = cjson.encode(current_metrics_batch)
+  if not payload then
+    ngx.log(ngx.ERR, "error while encoding metrics: ", err)
+    return
+  end
+
+  _M.send(payload)
+end
+
+return _M
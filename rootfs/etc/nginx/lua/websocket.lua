@@ -0,0 +1,25 @@
+local _M = {}
+
+-- outcome returns how an upgraded (i.e. WebSocket) connection ended:
+-- "closed" when it was closed while the worker was running, "drained" when it
+-- was closed by one of the peers while the worker was exiting and "killed"
+-- when NGINX closed it, usually at the end of the shutdown timeout. NGINX
+-- terminates these requests instead of finalizing them, so they never
+-- complete. Requests that did not switch protocols return nil.
+function _M.outcome()
+  if ngx.var.status ~= "101" then
+    return nil
+  end
+
+  if not ngx.worker.exiting() then
+    return "closed"
+  end
+
+  if ngx.var.request_completion ~= "OK" then
+    return "killed"
+  end
+
+  return "drained"
+end
+
+return _M
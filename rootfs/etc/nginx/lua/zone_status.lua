@@ -0,0 +1,184 @@
+local cjson = require("cjson.safe")
+local math_floor = math.floor
+local ipairs = ipairs
+local tonumber = tonumber
+local tostring = tostring
+local string_format = string.format
+
+-- counters of the server and upstream zones, keyed by
+-- "<zone type>\t<zone name>\t<counter name>". The dictionary is only
+-- defined when the zone metrics are enabled.
+local DICT_NAME = "zone_status_data"
+local SEPARATOR = "\t"
+local KEY_PATTERN = "^([^\t]+)\t(.+)\t([^\t]+)$"
+
+local SERVER_ZONE = "server"
+local UPSTREAM_ZONE = "upstream"
+
+local _M = {}
+
+local function incr(dict, zone_type, zone, counter, value)
+  local key = zone_type .. SEPARATOR .. zone .. SEPARATOR .. counter
+  local _, err, forcible = dict:incr(key, value, 0)
+  if err then
+    ngx.log(ngx.WARN, string_format("error incrementing zone counter %s: %s", key, tostring(err)))
+  elseif forcible then
+    ngx.log(ngx.WARN, "shared dictionary ", DICT_NAME, " is full, zone counters are being evicted")
+  end
+end
+
+local function account(dict, zone_type, zone, status_class, request_length, bytes_sent)
+  incr(dict, zone_type, zone, "requests", 1)
+  incr(dict, zone_type, zone, "bytesIn", request_length)
+  incr(dict, zone_type, zone, "bytesOut", bytes_sent)
+  if status_class then
+    incr(dict, zone_type, zone, status_class, 1)
+  end
+end
+
+-- log accounts the current request in the zone of the server block
+-- and the upstream that handled it
+function _M.log()
+  local dict = ngx.shared[DICT_NAME]
+  if not dict then
+    return
+  end
+
+  local status = tonumber(ngx.var.status)
+  local status_class
+  if status then
+    status_class = string_format("%dxx", math_floor(status / 100))
+  end
+
+  local request_length = tonumber(ngx.var.request_length) or 0
+  local bytes_sent = tonumber(ngx.var.bytes_sent) or 0
+
+  -- server_name is the name of the server block and not the
+  -- Host header sent by the client
+  local server_name = ngx.var.server_name
+  if server_name and server_name ~= "" then
+    account(dict, SERVER_ZONE, server_name, status_class, request_length, bytes_sent)
+  end
+
+  local upstream_name = ngx.var.proxy_upstream_name
+  if upstream_name and upstream_name ~= "" and upstream_name ~= "-" then
+    account(dict, UPSTREAM_ZONE, upstream_name, status_class, request_length, bytes_sent)
+  end
+end
+
+local function new_zone()
+  return { requests = 0, bytesIn = 0, bytesOut = 0, responses = {} }
+end
+
+-- status returns the counters of all the zones
+function _M.status()
+  local zones = {
+    serverZones = {},
+    upstreamZones = {},
+  }
+
+  local dict = ngx.shared[DICT_NAME]
+  if not dict then
+    return zones
+  end
+
+  local zone_types = {
+    [SERVER_ZONE] = zones.serverZones,
+    [UPSTREAM_ZONE] = zones.upstreamZones,
+  }
+
+  for _, key in ipairs(dict:get_keys(0)) do
+    local zone_type, zone, counter = key:match(KEY_PATTERN)
+    local value = dict:get(key)
+
+    local zones_of_type = zone_type and zone_types[zone_type]
+    if zones_of_type and value then
+      local z = zones_of_type[zone]
+      if not z then
+        z = new_zone()
+        zones_of_type[zone] = z
+      end
+
+      if z[counter] then
+        z[counter] = value
+      else
+        z.responses[counter] = value
+      end
+    end
+  end
+
+  return zones
+end
+
+local function to_set(names)
+  local set = {}
+  for _, name in ipairs(names or {}) do
+    set[name] = true
+  end
+  return set
+end
+
+-- prune removes the counters of the zones that are not part of the
+-- configuration anymore. zones contains the names of the servers and the
+-- upstreams of the configuration.
+function _M.prune(zones)
+  local dict = ngx.shared[DICT_NAME]
+  if not dict then
+    return
+  end
+
+  local active = {
+    [SERVER_ZONE] = to_set(zones.servers),
+    [UPSTREAM_ZONE] = to_set(zones.upstreams),
+  }
+
+  for _, key in ipairs(dict:get_keys(0)) do
+    local zone_type, zone = key:match(KEY_PATTERN)
+    local active_zones = zone_type and active[zone_type]
+    if active_zones and not active_zones[zone] then
+      dict:delete(key)
+    end
+  end
+end
+
+local function handle_prune()
+  ngx.req.read_body()
+  local zones, err = cjson.decode(ngx.req.get_body_data() or "")
+  if not zones then
+    ngx.log(ngx.ERR, "could not parse zones: ", tostring(err))
+    ngx.status = ngx.HTTP_BAD_REQUEST
+    return
+  end
+
+  _M.prune(zones)
+  ngx.status = ngx.HTTP_CREATED
+end
+
+function _M.call()
+  if ngx.var.request_method == "POST" then
+    handle_prune()
+    return
+  end
+
+  if ngx.var.request_method ~= "GET" then
+    ngx.status = ngx.HTTP_BAD_REQUEST
+    ngx.print("Only GET and POST requests are allowed!")
+    return
+  end
+
+  local payload, err = cjson.encode(_M.status())
+  if not payload then
+    ngx.log(ngx.ERR, "error encoding zone status: ", tostring(err))
+    ngx.status = ngx.HTTP_INTERNAL_SERVER_ERROR
+    return
+  end
+
+  ngx.header.content_type = "application/json"
+  ngx.print(payload)
+end
+
+if _TEST then
+  _M.DICT_NAME = DICT_NAME
+end
+
+return _M
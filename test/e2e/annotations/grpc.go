@@ -17,7 +17,11 @@ limitations under the License.
 package annotations
 
 import (
+	"bytes"
+	"encoding/binary"
 	"fmt"
+	"io/ioutil"
+	"net/http"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -55,5 +59,87 @@ var _ = framework.IngressNginxDescribe("Annotations - grpc", func() {
 						Expect(server).ShouldNot(ContainSubstring("proxy_pass"))
 				})
 		})
+
+		It("should bridge gRPC-Web requests when grpc-web is enabled", func() {
+			host := "grpc-web"
+
+			annotations := map[string]string{
+				"nginx.ingress.kubernetes.io/backend-protocol": "GRPC",
+				"nginx.ingress.kubernetes.io/grpc-web":         "true",
+			}
+
+			ing := framework.NewSingleIngress(host, "/", host, f.Namespace, "fortune-teller", 50051, &annotations)
+			f.EnsureIngress(ing)
+
+			f.WaitForNginxServer(host,
+				func(server string) bool {
+					return Expect(server).Should(ContainSubstring("grpc_pass")) &&
+						Expect(server).Should(ContainSubstring("grpc_web.header_filter()")) &&
+						Expect(server).Should(ContainSubstring("grpc_web.body_filter()"))
+				})
+
+			// an empty PredictRequest in a frame without flags
+			body := []byte{0, 0, 0, 0, 0}
+			req, err := http.NewRequest("POST", f.GetURL(framework.HTTP)+"/build.stack.fortune.FortuneTeller/Predict", bytes.NewReader(body))
+			Expect(err).NotTo(HaveOccurred())
+			req.Host = host
+			req.Header.Set("Content-Type", "application/grpc-web+proto")
+			req.Header.Set("X-Grpc-Web", "1")
+
+			resp, err := http.DefaultClient.Do(req)
+			Expect(err).NotTo(HaveOccurred())
+			defer resp.Body.Close()
+
+			Expect(resp.StatusCode).Should(Equal(http.StatusOK))
+			Expect(resp.Header.Get("Content-Type")).Should(Equal("application/grpc-web+proto"))
+
+			data, err := ioutil.ReadAll(resp.Body)
+			Expect(err).NotTo(HaveOccurred())
+
+			frames := grpcWebFrames(data)
+			Expect(frames).Should(HaveLen(2), "expected a message and a trailer frame")
+			Expect(frames[0].flags).Should(Equal(byte(0)))
+			Expect(frames[0].data).ShouldNot(BeEmpty(), "expected the fortune in the message frame")
+			Expect(frames[1].flags).Should(Equal(byte(0x80)))
+			Expect(string(frames[1].data)).Should(ContainSubstring("grpc-status:0\r\n"))
+		})
+
+		It("should ignore grpc-web when the backend protocol is not gRPC", func() {
+			host := "grpc-web-http"
+
+			annotations := map[string]string{
+				"nginx.ingress.kubernetes.io/grpc-web": "true",
+			}
+
+			ing := framework.NewSingleIngress(host, "/", host, f.Namespace, "fortune-teller", 50051, &annotations)
+			f.EnsureIngress(ing)
+
+			f.WaitForNginxServer(host,
+				func(server string) bool {
+					return Expect(server).Should(ContainSubstring("proxy_pass")) &&
+						Expect(server).ShouldNot(ContainSubstring("grpc_web.body_filter()"))
+				})
+		})
 	})
 })
+
+type grpcWebFrame struct {
+	flags byte
+	data  []byte
+}
+
+// grpcWebFrames splits the body of a gRPC-Web response in frames: the flags,
+// the length of the data in 4 bytes in big-endian order and the data
+func grpcWebFrames(body []byte) []grpcWebFrame {
+	frames := []grpcWebFrame{}
+	for len(body) > 0 {
+		Expect(len(body)).Should(BeNumerically(">=", 5), "expected the header of a frame")
+		length := int(binary.BigEndian.Uint32(body[1:5]))
+		Expect(len(body)).Should(BeNumerically(">=", 5+length), "expected the data of a frame")
+
+		frames = append(frames, grpcWebFrame{flags: body[0], data: body[5 : 5+length]})
+		body = body[5+length:]
+	}
+
+	return frames
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package settings
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"k8s.io/ingress-nginx/test/e2e/framework"
+)
+
+var _ = framework.IngressNginxDescribe("WebSocket drain", func() {
+	f := framework.NewDefaultFramework("websocket-drain")
+
+	It("should report the WebSocket connections killed at the end of the drain budget", func() {
+		host := "websocket-drain"
+
+		f.NewDeployment("websocket", "jmalloc/echo-server", 8080, 1)
+		f.UpdateNginxConfigMapData("worker-shutdown-timeout", "5s")
+		f.EnsureIngress(framework.NewSingleIngress(host, "/", host, f.Namespace, "websocket", 8080, nil))
+
+		f.WaitForNginxServer(host,
+			func(server string) bool {
+				return strings.Contains(server, fmt.Sprintf("server_name %v", host))
+			})
+
+		conn, err := net.Dial("tcp", fmt.Sprintf("%v:80", f.GetNginxIP()))
+		Expect(err).NotTo(HaveOccurred())
+		defer conn.Close()
+
+		fmt.Fprintf(conn, "GET / HTTP/1.1\r\nHost: %v\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n"+
+			"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n", host)
+
+		reader := bufio.NewReader(conn)
+		resp, err := http.ReadResponse(reader, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).Should(Equal(http.StatusSwitchingProtocols))
+
+		// reload NGINX while the connection is open
+		f.UpdateNginxConfigMapData("keep-alive-requests", "200")
+		f.WaitForNginxConfiguration(
+			func(cfg string) bool {
+				return strings.Contains(cfg, "keepalive_requests 200;")
+			})
+
+		// the connection is closed by NGINX at the end of the drain budget
+		err = conn.SetReadDeadline(time.Now().Add(30 * time.Second))
+		Expect(err).NotTo(HaveOccurred())
+		_, err = ioutil.ReadAll(reader)
+		Expect(err).NotTo(HaveOccurred(), "expected the connection to be closed by NGINX")
+
+		killed := regexp.MustCompile(`nginx_ingress_controller_upgraded_connections_total{[^}]*outcome="killed"[^}]*} [1-9]`)
+		err = wait.Poll(framework.Poll, framework.DefaultTimeout, func() (bool, error) {
+			metrics, err := f.ExecIngressPod("curl -s http://localhost:10254/metrics")
+			if err != nil {
+				return false, nil
+			}
+			return killed.MatchString(metrics), nil
+		})
+		Expect(err).NotTo(HaveOccurred(), "expected the connection to be reported as killed")
+	})
+})
@@ -101,6 +101,74 @@ var _ = framework.IngressNginxDescribe("TCP Feature", func() {
 		Expect(resp.StatusCode).Should(Equal(200))
 	})
 
+	It("should terminate TLS in a TCP service", func() {
+		host := "tcp.tls.com"
+
+		f.NewEchoDeploymentWithReplicas(1)
+
+		tlsConfig, err := framework.CreateIngressTLSSecret(f.KubeClientSet,
+			[]string{host},
+			"tcp-tls",
+			f.Namespace)
+		Expect(err).NotTo(HaveOccurred())
+
+		config, err := f.KubeClientSet.
+			CoreV1().
+			ConfigMaps(f.Namespace).
+			Get("tcp-services", metav1.GetOptions{})
+		Expect(err).To(BeNil(), "unexpected error obtaining tcp-services configmap")
+		Expect(config).NotTo(BeNil(), "expected a configmap but none returned")
+
+		if config.Data == nil {
+			config.Data = map[string]string{}
+		}
+
+		config.Data["8443"] = fmt.Sprintf("%v/http-svc:80:tls=tcp-tls", f.Namespace)
+
+		_, err = f.KubeClientSet.
+			CoreV1().
+			ConfigMaps(f.Namespace).
+			Update(config)
+		Expect(err).NotTo(HaveOccurred(), "unexpected error updating configmap")
+
+		svc, err := f.KubeClientSet.
+			CoreV1().
+			Services(f.Namespace).
+			Get("ingress-nginx", metav1.GetOptions{})
+		Expect(err).To(BeNil(), "unexpected error obtaining ingress-nginx service")
+		Expect(svc).NotTo(BeNil(), "expected a service but none returned")
+
+		svc.Spec.Ports = append(svc.Spec.Ports, corev1.ServicePort{
+			Name:       "http-svc-tls",
+			Port:       8443,
+			TargetPort: intstr.FromInt(8443),
+		})
+		_, err = f.KubeClientSet.
+			CoreV1().
+			Services(f.Namespace).
+			Update(svc)
+		Expect(err).NotTo(HaveOccurred(), "unexpected error updating service")
+
+		f.WaitForNginxConfiguration(
+			func(cfg string) bool {
+				return strings.Contains(cfg, "listen                  8443 ssl;") &&
+					strings.Contains(cfg, fmt.Sprintf("ssl_certificate         /etc/ingress-controller/ssl/%v-tcp-tls.pem;", f.Namespace))
+			})
+
+		// the echo server receives the plain HTTP request after the TLS
+		// termination in NGINX
+		ip := f.GetNginxIP()
+		resp, _, errs := gorequest.New().
+			Get(fmt.Sprintf("https://%v:8443", ip)).
+			TLSClientConfig(tlsConfig).
+			End()
+		Expect(errs).Should(BeEmpty())
+		Expect(resp.StatusCode).Should(Equal(200))
+		Expect(resp.TLS).NotTo(BeNil())
+		Expect(resp.TLS.PeerCertificates).NotTo(BeEmpty())
+		Expect(resp.TLS.PeerCertificates[0].DNSNames[0]).Should(Equal(host))
+	})
+
 	It("should expose an ExternalName TCP service", func() {
 		// Setup:
 		// - Create an external name service for DNS lookups on port 5353. Point it to google's DNS server